@@ -0,0 +1,73 @@
+package Benchmarks
+
+import (
+	"DSM-project/dsm-api/treadmarks"
+	"fmt"
+	"testing"
+	"time"
+)
+
+//Batch sizes compared by the batching benchmarks. A size of 0 disables batching.
+var batchSizes = []int{0, 64 * 1024}
+
+func BenchmarkLockTM(b *testing.B) {
+	tm1, tms := setupTMHosts(8, 4096, 4096)
+	all := append(tms, tm1)
+	for _, size := range batchSizes {
+		setBatchingTM(all, size)
+		b.Run(fmt.Sprint("batch=", size), func(b *testing.B) {
+			frames, bytes := framesSentTM(all)
+			for i := 0; i < b.N; i++ {
+				tms[i%7].AcquireLock(0)
+				tms[i%7].ReleaseLock(0)
+			}
+			reportFramesTM(b, all, frames, bytes)
+		})
+	}
+	for _, tm := range all {
+		tm.Shutdown()
+	}
+}
+
+func BenchmarkBarrierTimeTM(b *testing.B) {
+	tm1, tms := setupTMHosts(8, 4096, 4096)
+	all := append(tms, tm1)
+	for _, size := range batchSizes {
+		setBatchingTM(all, size)
+		b.Run(fmt.Sprint("batch=", size), func(b *testing.B) {
+			frames, bytes := framesSentTM(all)
+			for i := 0; i < b.N; i++ {
+				for _, tm := range tms {
+					go tm.Barrier(0)
+				}
+				tm1.Barrier(0)
+			}
+			reportFramesTM(b, all, frames, bytes)
+		})
+	}
+	for _, tm := range all {
+		tm.Shutdown()
+	}
+}
+
+//framesSentTM returns the frames and bytes written by the connections of all hosts.
+func framesSentTM(tms []*treadmarks.TreadmarksApi) (frames, bytes int) {
+	for _, tm := range tms {
+		f, b := tm.FramesSent()
+		frames, bytes = frames+f, bytes+b
+	}
+	return
+}
+
+//reportFramesTM reports the frames and bytes written since the counts given, per second.
+func reportFramesTM(b *testing.B, tms []*treadmarks.TreadmarksApi, frames, bytes int) {
+	f, n := framesSentTM(tms)
+	b.ReportMetric(float64(f-frames)/b.Elapsed().Seconds(), "frames/s")
+	b.ReportMetric(float64(n-bytes)/b.Elapsed().Seconds(), "bytes/s")
+}
+
+func setBatchingTM(tms []*treadmarks.TreadmarksApi, maxBatchBytes int) {
+	for _, tm := range tms {
+		tm.SetBatching(maxBatchBytes, time.Duration(0))
+	}
+}
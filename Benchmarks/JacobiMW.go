@@ -4,7 +4,6 @@ import (
 	"DSM-project/multiview"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"runtime"
 	"runtime/pprof"
@@ -17,7 +16,6 @@ var _ = log.Print
 var _ = log.Print
 
 func TestJacobiProgramMultiView(t *testing.T) {
-	log.SetOutput(ioutil.Discard)
 	group := sync.WaitGroup{}
 	pageSize := 4096
 	//decent results with N,M = 64, iterations = 10-24
@@ -68,15 +66,11 @@ func JacobiProgramMultiView(matrixSize int, nrIterations int, nrProcs int, isMan
 		mw.Initialize(N*M*float32_BYTE_LENGTH, pageByteSize, nrProcs)
 		mw.CSVLoggingIsEnabled(false)
 
-		allocs := make([]int, M*N)
-		for i := range allocs {
-			allocs[i] = float32_BYTE_LENGTH
-		}
-		addrs, _ := mw.MultiMalloc(allocs)
+		grid, _ := mw.MallocNamedArray("grid", M*N, float32_BYTE_LENGTH, float32_BYTE_LENGTH)
 		for i := range gridEntryAddresses {
 			row := gridEntryAddresses[i]
 			for j := range row {
-				gridEntryAddresses[i][j] = addrs[i*M+j]
+				gridEntryAddresses[i][j] = grid.Addr(i*N + j)
 				if gridEntryAddresses[i][j] < 0 {
 					panic(fmt.Sprintln("Address was negative: ", i, ", ", j, ", ", gridEntryAddresses[i][j]))
 				}
@@ -92,17 +86,15 @@ func JacobiProgramMultiView(matrixSize int, nrIterations int, nrProcs int, isMan
 		mw.Barrier(0)
 	} else {
 		mw.Join(M*N*float32_BYTE_LENGTH, pageByteSize)
-		//calculate the addresses of the pointers allocated by the manager host
 		mw.Barrier(0)
+		grid, err := mw.LookupNamedArray("grid")
+		if err != nil {
+			panic(err.Error())
+		}
 		for i := range gridEntryAddresses {
 			row := gridEntryAddresses[i]
 			for j := range row {
-				k := i * N * float32_BYTE_LENGTH
-				l := k + j*float32_BYTE_LENGTH
-				q := (i + j) % (mw.GetPageSize() / float32_BYTE_LENGTH)
-				memsize := M * N * float32_BYTE_LENGTH
-				vAddr := (q+1)*memsize + l
-				gridEntryAddresses[i][j] = vAddr
+				gridEntryAddresses[i][j] = grid.Addr(i*N + j)
 			}
 		}
 	}
@@ -1,7 +1,6 @@
 package Benchmarks
 
 import (
-	"DSM-project/dsm-api/treadmarks"
 	"fmt"
 	"io"
 	"log"
@@ -40,7 +39,7 @@ func JacobiProgramTreadMarks(matrixsize int, nrIterations int, nrProcs int, isMa
 		return (m * N * float64_BYTE_LENGTH) + (n * float64_BYTE_LENGTH)
 	}
 
-	tm, _ := treadmarks.NewTreadmarksApi(M*N*float64_BYTE_LENGTH, 4096, uint8(nrProcs), uint8(nrProcs), uint8(nrProcs))
+	tm, _ := newTreadmarksApi(M*N*float64_BYTE_LENGTH, 4096, uint8(nrProcs), uint8(nrProcs), uint8(nrProcs))
 	tm.Initialize(port)
 	if !isManager {
 		tm.Join("localhost", 2000)
@@ -4,7 +4,6 @@ import (
 	"DSM-project/multiview"
 	"fmt"
 	"github.com/stretchr/testify/assert"
-	"io/ioutil"
 	"log"
 	"math/rand"
 	"runtime"
@@ -16,7 +15,6 @@ import (
 
 func TestMergeSortMW(t *testing.T) {
 	runtime.GOMAXPROCS(4) // or 2 or 4
-	log.SetOutput(ioutil.Discard)
 	group := sync.WaitGroup{}
 	pageSize := 4096
 	arraySize := 4096 * 1000
@@ -1,8 +1,8 @@
 package Benchmarks
 
 import (
+	"DSM-project/dsm-api/treadmarks"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math/rand"
 	"sort"
@@ -12,7 +12,6 @@ import (
 )
 
 func TestMergeSortTM(t *testing.T) {
-	log.SetOutput(ioutil.Discard)
 	group := sync.WaitGroup{}
 	start := time.Now()
 	arraySize := 10
@@ -40,9 +39,9 @@ func MergeSortTM(arraySize int, nrProcs int, isManager bool, pageByteSize int, g
 	cellByteSize := ARRAY_SIZE * INT_BYTE_LENGTH / nrProcs
 	fmt.Println("cell byte size:", cellByteSize)
 
-	tm := setupTreadMarksStruct(nrProcs, ARRAY_SIZE*INT_BYTE_LENGTH, pageByteSize, nrProcs, nrProcs+1)
+	tm, _ := treadmarks.NewTreadmarksApi(ARRAY_SIZE*INT_BYTE_LENGTH, pageByteSize, uint8(nrProcs), uint8(nrProcs+1), uint8(nrProcs+1))
 	if isManager {
-		tm.Startup()
+		tm.Initialize(2000)
 		rand.Seed(time.Now().UnixNano())
 		for i := 0; i < ARRAY_SIZE*INT_BYTE_LENGTH/cellByteSize; i++ {
 			addr, _ := tm.Malloc(cellByteSize)
@@ -50,27 +49,29 @@ func MergeSortTM(arraySize int, nrProcs int, isManager bool, pageByteSize int, g
 			//fill with random values
 			for j := 0; j < cellByteSize/INT_BYTE_LENGTH; j++ {
 				rn := rand.Intn(1000000)
-				tm.WriteInt(addr+j*INT_BYTE_LENGTH, rn)
+				writeInt(tm, addr+j*INT_BYTE_LENGTH, rn)
 			}
 		}
 		tm.Barrier(0)
 
 	} else {
-		tm.Join("localhost:2000")
+		tm.Initialize(0)
+		tm.Join("localhost", 2000)
 		tm.Barrier(0)
-		start := cellByteSize * (int(tm.ProcId) - 1)
+		start := cellByteSize * tm.GetId()
 		//calculate the addresses of the pointers allocated by the manager host
 		for i := 0; i < cellByteSize; i++ {
 			fmt.Println("reading at address", start+(i*INT_BYTE_LENGTH))
-			tm.ReadInt(start + (i * INT_BYTE_LENGTH))
+			readInt(tm, start+(i*INT_BYTE_LENGTH))
 		}
 	}
-	start := cellByteSize * (int(tm.ProcId) - 1)
-	tm.AcquireLock(int(tm.ProcId))
+	procId := tm.GetId() + 1
+	start := cellByteSize * (procId - 1)
+	tm.AcquireLock(procId)
 	tm.Barrier(1) //ensures everyone gets their first lock
 	//sort own array here
 	for i := range privateArray {
-		privateArray[i] = tm.ReadInt(start + i*INT_BYTE_LENGTH)
+		privateArray[i] = readInt(tm, start+i*INT_BYTE_LENGTH)
 	}
 	var sortableArray sort.IntSlice = privateArray
 	sort.Sort(sortableArray)
@@ -78,13 +79,13 @@ func MergeSortTM(arraySize int, nrProcs int, isManager bool, pageByteSize int, g
 	level := 1
 	for mergeArrSize <= ARRAY_SIZE {
 		if start%mergeArrSize == 0 { //if I am the leftmost process in the subtree, I win and do the sorting
-			otherProcId := int(tm.ProcId) + level
+			otherProcId := procId + level
 			tm.AcquireLock(otherProcId)
 			//read losing processor's result into private array and merge them
 			otherProcRes := make([]int, mergeArrSize/2)
 			otherProcAddr := cellByteSize * (int(otherProcId) - 1)
 			for i := 0; i < mergeArrSize/2; i++ {
-				otherProcRes[i] = tm.ReadInt(otherProcAddr + i*INT_BYTE_LENGTH)
+				otherProcRes[i] = readInt(tm, otherProcAddr+i*INT_BYTE_LENGTH)
 			}
 			privateArray = mergeArrays(privateArray, otherProcRes)
 			level = level * 2
@@ -92,22 +93,15 @@ func MergeSortTM(arraySize int, nrProcs int, isManager bool, pageByteSize int, g
 		} else {
 			//only write results to DSM once the lock is about to be released
 			for i, val := range privateArray {
-				tm.WriteInt(start+i*INT_BYTE_LENGTH, val)
+				writeInt(tm, start+i*INT_BYTE_LENGTH, val)
 			}
 			break
 		}
 	}
-	tm.ReleaseLock(int(tm.ProcId))
+	tm.ReleaseLock(procId)
 	tm.Barrier(2)
 	defer func() {
-		if isManager {
-			//fmt.Println("result array:",privateArray)
-			//var res sort.IntSlice = privateArray
-			//fmt.Println("isSorted:", sort.IsSorted(res))
-			tm.Shutdown()
-		} else {
-			tm.Close()
-		}
+		tm.Shutdown()
 		group.Done()
 	}()
 }
@@ -82,6 +82,7 @@ func TestSynchronizedWritesMW(nrhosts, nrRounds int, pprofFile io.Writer) {
 	end := time.Now()
 	diff := end.Sub(startTime)
 	fmt.Println("execution time:", diff.String())
+	printBytesSentMW(append(mws, mw1))
 	for _, mw := range mws {
 		mw.Leave()
 	}
@@ -117,12 +118,23 @@ func readOnAllHosts(addr int, mws []*multiview.Multiview) {
 	}
 }
 
+func printBytesSentMW(mws []*multiview.Multiview) {
+	raw, wire := 0, 0
+	for _, mw := range mws {
+		r, w := mw.BytesSent()
+		raw, wire = raw+r, wire+w
+	}
+	fmt.Println("message bytes:", raw, "sent as:", wire, "bytes")
+}
+
 func setupHosts(nrHosts int, memSize, pageByteSize int) (manager *multiview.Multiview, mws []*multiview.Multiview) {
 	manager = multiview.NewMultiView()
+	manager.SetCompression(UseCompression)
 	manager.Initialize(memSize, pageByteSize, nrHosts)
 	mws = make([]*multiview.Multiview, nrHosts-1)
 	for i := range mws {
 		mws[i] = multiview.NewMultiView()
+		mws[i].SetCompression(UseCompression)
 		mws[i].Join(memSize, pageByteSize)
 	}
 	return
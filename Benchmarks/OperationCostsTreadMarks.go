@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"runtime/pprof"
+	"sync"
 	"time"
 )
 
@@ -30,12 +31,49 @@ func TestBarrierTimeTM(nrTimes, nrhosts int, pprofFile io.Writer) {
 	end := time.Now()
 	diff := end.Sub(startTime)
 	fmt.Println("execution time:", diff.String())
+	printMessageRateTM(append(tms, tm1), diff)
 	for _, tm := range tms {
 		tm.Shutdown()
 	}
 	tm1.Shutdown()
 }
 
+var barrierAlgorithmsTM = []treadmarks.BarrierAlgorithm{treadmarks.CentralBarrier, treadmarks.TreeBarrier, treadmarks.DisseminationBarrier}
+var barrierAlgorithmNamesTM = []string{"central", "tree", "dissemination"}
+
+//TestBarrierScalingTM times nrTimes barriers with each barrier algorithm, for each number of hosts in hostCounts.
+//Every host writes before each barrier, so intervals are merged and passed on, and all hosts leave a barrier before the next.
+func TestBarrierScalingTM(nrTimes int, hostCounts []int) {
+	for _, nrhosts := range hostCounts {
+		for i, algorithm := range barrierAlgorithmsTM {
+			tm1, tms := setupTMHosts(nrhosts, 4096, 4096)
+			all := append(tms, tm1)
+			for _, tm := range all {
+				tm.SetBarrierAlgorithm(algorithm)
+			}
+			group := new(sync.WaitGroup)
+			startTime := time.Now()
+			for j := 0; j < nrTimes; j++ {
+				group.Add(len(all))
+				for _, tm := range all {
+					go func(tm *treadmarks.TreadmarksApi) {
+						tm.Write(tm.GetId(), byte(j))
+						tm.Barrier(0)
+						group.Done()
+					}(tm)
+				}
+				group.Wait()
+			}
+			diff := time.Since(startTime)
+			fmt.Println(barrierAlgorithmNamesTM[i], "barrier,", nrhosts, "hosts:", diff/time.Duration(nrTimes), "per barrier")
+			printMessageRateTM(all, diff)
+			for _, tm := range all {
+				tm.Shutdown()
+			}
+		}
+	}
+}
+
 func TestLockTM(nrTimes int, pprofFile io.Writer) {
 	tm1, tms := setupTMHosts(8, 4096, 4096)
 
@@ -59,6 +97,7 @@ func TestLockTM(nrTimes int, pprofFile io.Writer) {
 	end := time.Now()
 	diff := end.Sub(startTime)
 	fmt.Println("execution time:", diff.String())
+	printMessageRateTM(append(tms, tm1), diff)
 	for _, tm := range tms {
 		tm.Shutdown()
 	}
@@ -90,6 +129,7 @@ func TestSynchronizedReadsWritesTM(nrRounds int, pprofFile io.Writer) {
 	end := time.Now()
 	diff := end.Sub(startTime)
 	fmt.Println("execution time:", diff.String())
+	printBytesSentTM(append(tms, tm1))
 	for _, tm := range tms {
 		tm.Shutdown()
 	}
@@ -119,6 +159,29 @@ func TestNonSynchronizedReadWritesTM(nrRounds int, pprofFile io.Writer) {
 	tm1.Shutdown()
 }
 
+func printMessageRateTM(tms []*treadmarks.TreadmarksApi, d time.Duration) {
+	messages := messagesSentTM(tms)
+	fmt.Println("messages sent:", messages, "message rate:", float64(messages)/d.Seconds(), "msg/s")
+	printBytesSentTM(tms)
+}
+
+func printBytesSentTM(tms []*treadmarks.TreadmarksApi) {
+	raw, wire := 0, 0
+	for _, tm := range tms {
+		r, w := tm.BytesSent()
+		raw, wire = raw+r, wire+w
+	}
+	fmt.Println("message bytes:", raw, "sent as:", wire, "bytes")
+}
+
+func messagesSentTM(tms []*treadmarks.TreadmarksApi) int {
+	messages := 0
+	for _, tm := range tms {
+		messages += tm.MessagesSent()
+	}
+	return messages
+}
+
 func readOnAllTMHosts(addr int, tms []*treadmarks.TreadmarksApi) {
 	for _, tm := range tms {
 		tm.Read(addr)
@@ -126,12 +189,14 @@ func readOnAllTMHosts(addr int, tms []*treadmarks.TreadmarksApi) {
 }
 
 func setupTMHosts(nrHosts int, memSize, pageByteSize int) (manager *treadmarks.TreadmarksApi, mws []*treadmarks.TreadmarksApi) {
-	manager, _ = treadmarks.NewTreadmarksApi(memSize, pageByteSize, uint8(nrHosts), uint8(nrHosts), uint8(nrHosts))
+	manager, _ = newTreadmarksApi(memSize, pageByteSize, uint8(nrHosts), uint8(nrHosts), uint8(nrHosts))
 	manager.Initialize(2000)
+	manager.SetCompression(UseCompression)
 	mws = make([]*treadmarks.TreadmarksApi, nrHosts-1)
 	for i := range mws {
-		mws[i], _ = treadmarks.NewTreadmarksApi(memSize, pageByteSize, uint8(nrHosts), uint8(nrHosts), uint8(nrHosts))
+		mws[i], _ = newTreadmarksApi(memSize, pageByteSize, uint8(nrHosts), uint8(nrHosts), uint8(nrHosts))
 		mws[i].Initialize(2000 + i + 1)
+		mws[i].SetCompression(UseCompression)
 		mws[i].Join("localhost", 2000)
 	}
 	return
@@ -5,7 +5,6 @@ import (
 	"DSM-project/multiview"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"math/rand"
 	"runtime"
@@ -17,7 +16,6 @@ import (
 
 func TestParallelSumTM(t *testing.T) {
 	runtime.GOMAXPROCS(4) // or 2 or 4
-	log.SetOutput(ioutil.Discard)
 	group := sync.WaitGroup{}
 	pageSize := 4096
 	var nrOfInts int64 = 4096 * 1000000
@@ -37,7 +35,6 @@ func TestParallelSumTM(t *testing.T) {
 
 func TestParallelSumMW(t *testing.T) {
 	runtime.GOMAXPROCS(4) // or 2 or 4
-	log.SetOutput(ioutil.Discard)
 	group := sync.WaitGroup{}
 	pageSize := 4096
 	nrOfInts := 4096 * 1000000
@@ -139,7 +136,7 @@ func ParallelSumTM(batchSize int64, nrOfInts int64, nrProcs int, isManager bool,
 	var sharedSumAddr int
 	var currBatchNrAddr int
 	var tm *treadmarks.TreadmarksApi
-	tm, _ = treadmarks.NewTreadmarksApi(INT_BYTE_LENGTH*2, pageByteSize, uint8(nrProcs), uint8(4), uint8(4))
+	tm, _ = newTreadmarksApi(INT_BYTE_LENGTH*2, pageByteSize, uint8(nrProcs), uint8(4), uint8(4))
 	tm.Initialize(port)
 	defer tm.Shutdown()
 	if isManager {
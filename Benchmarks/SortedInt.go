@@ -2,12 +2,10 @@ package Benchmarks
 
 import (
 	"DSM-project/dsm-api"
-	"DSM-project/dsm-api/treadmarks"
 	"DSM-project/multiview"
 	"encoding/binary"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"runtime/pprof"
 	"sync"
@@ -15,45 +13,39 @@ import (
 )
 
 func SortedIntMVBenchmark(nrProcs int, batchSize int, isManager bool, N int, Bmax int32, Imax int, pprofFile io.Writer) {
-	log.SetOutput(ioutil.Discard)
 	mv := multiview.NewMultiView()
 
 	rand := NewRandom()
 	address := make([]int, N)
 	pagebytesize := 128
-	memsize := (((N+1)*4)/pagebytesize + 1) * pagebytesize
-	prog := N*4 + ((N%(pagebytesize/4))+1)*memsize
+	//the keys, and a page for the progress counter, as arrays start on a new page
+	memsize := ((N*4+pagebytesize-1)/pagebytesize + 1) * pagebytesize
+	var prog int
 	if isManager {
 		mv.Initialize(memsize, pagebytesize, nrProcs)
 		fmt.Println("I am the manager.")
-		allocs := make([]int, len(address))
+		arr, _ := mv.MallocNamedArray("keys", N, 4, 4)
 		for i := range address {
-			allocs[i] = 4
-			//fmt.Println("manager address", i, ":",address[i])
+			address[i] = arr.Addr(i)
 		}
-		address, _ = mv.MultiMalloc(allocs)
-		prog, _ = mv.Malloc(4)
+		progArr, _ := mv.MallocNamedArray("progress", 1, 4, 4)
+		prog = progArr.Addr(0)
 		mv.Barrier(3)
 	} else {
 		mv.Join(memsize, pagebytesize)
 		mv.Barrier(3)
-		for i := range address { /*
-				//address[i] = i*4 + ((i%(mv.GetPageSize()/4))+1)*memsize
-				k := i * N * 4
-				l := k + j*4
-				q := (i + j) % (mw.GetPageSize() / float32_BYTE_LENGTH)
-				memsize := M * N * float32_BYTE_LENGTH
-				vAddr := (q+1)*memsize + l*/
-
-			k := 4 * i
-			q := 0
-			if 4%pagebytesize != 0 {
-				q = i % max((pagebytesize/4), 2)
-			}
-			vAddr := (q+1)*mv.GetMemoryByteSize() + k
-			//fmt.Println("host", mv.Id, "address", i, ":", vAddr)
-			address[i] = vAddr
-			mv.ReadInt(vAddr)
+		arr, err := mv.LookupNamedArray("keys")
+		if err != nil {
+			panic(err.Error())
+		}
+		progArr, err := mv.LookupNamedArray("progress")
+		if err != nil {
+			panic(err.Error())
+		}
+		prog = progArr.Addr(0)
+		for i := range address {
+			address[i] = arr.Addr(i)
+			mv.ReadInt(address[i])
 		}
 
 	}
@@ -172,7 +164,7 @@ func SortedIntTMBenchmark(group *sync.WaitGroup, port, nrProcs, batchSize int, i
 	}
 	rand := NewRandom()
 	pagebytesize := 4096
-	tm, err := treadmarks.NewTreadmarksApi((((N+1)*4)/pagebytesize+1)*pagebytesize, pagebytesize, uint8(nrProcs), uint8(2), uint8(4))
+	tm, err := newTreadmarksApi((((N+1)*4)/pagebytesize+1)*pagebytesize, pagebytesize, uint8(nrProcs), uint8(2), uint8(4))
 	tm.Initialize(port)
 	if err != nil {
 		panic(err.Error())
@@ -1,18 +1,24 @@
 package Benchmarks
 
 import (
-	"DSM-project/memory"
-	"DSM-project/treadmarks"
 	"encoding/binary"
 	"math"
 	"DSM-project/dsm-api"
+	"DSM-project/dsm-api/treadmarks"
 	"bytes"
 )
 
-func setupTreadMarksStruct(nrProcs, memsize, pagebytesize, nrlocks, nrbarriers int) *treadmarks.TreadMarks {
-	vm1 := memory.NewVmem(memsize, pagebytesize)
-	tm1 := treadmarks.NewTreadMarks(vm1, nrProcs, nrlocks, nrbarriers)
-	return tm1
+//UseCompression makes the benchmark hosts compress page copies and diffs on the wire.
+var UseCompression = false
+
+//EagerRelease makes the TreadMarks benchmark hosts use eager instead of lazy release consistency.
+var EagerRelease = false
+
+func newTreadmarksApi(memSize, pageByteSize int, nrProcs, nrLocks, nrBarriers uint8) (*treadmarks.TreadmarksApi, error) {
+	if EagerRelease {
+		return treadmarks.NewEagerTreadmarksApi(memSize, pageByteSize, nrProcs, nrLocks, nrBarriers)
+	}
+	return treadmarks.NewTreadmarksApi(memSize, pageByteSize, nrProcs, nrLocks, nrBarriers)
 }
 
 func bytesToFloat32(bytes []byte) float32 {
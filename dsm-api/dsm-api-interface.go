@@ -1,5 +1,10 @@
 package dsm_api
 
+import (
+	"DSM-project/memory"
+	"time"
+)
+
 type DSMApiInterface interface{
 	Initialize(port int) error
 	Join(ip string, port int) error
@@ -8,10 +13,33 @@ type DSMApiInterface interface{
 	Write(addr int, val byte) error
 	ReadBytes(addr int, length int) ([]byte, error)
 	WriteBytes(addr int, val []byte) error
-	Malloc(size int) (int, error)
+	Malloc(size int, flags ...memory.AllocFlag) (int, error)
 	Free(addr, size int) error
-	Barrier(id uint8)
-	AcquireLock(id uint8)
-	ReleaseLock(id uint8)
+	Barrier(id int)
+	AcquireLock(id int)
+	ReleaseLock(id int)
+	TryAcquireLock(id int) bool
+	AcquireLockTimeout(id int, d time.Duration) bool
+	AcquireReadLock(id int)
+	ReleaseReadLock(id int)
+	NewLock() int
+	NewBarrier(participants []int) int
+	Wait(lockId, condId int)
+	Signal(lockId, condId int)
+	Broadcast(lockId, condId int)
+	NewSemaphore(value int) int
+	AcquireSemaphore(id int)
+	ReleaseSemaphore(id int)
+	FetchAndAdd(addr int, delta int64) (int64, error)
+	CompareAndSwap(addr int, old, val int64) (bool, error)
+	Swap(addr int, val int64) (int64, error)
+	Prefetcher
 	GetId() int
+}
+
+//Prefetcher is implemented by the DSM systems that can fetch a range ahead of its use.
+type Prefetcher interface {
+	//Prefetch fetches the range, so the first accesses to it don't fault. The returned channel receives once:
+	//nil when the range is readable, or the error that stopped the prefetch.
+	Prefetch(addr int, length int) <-chan error
 }
\ No newline at end of file
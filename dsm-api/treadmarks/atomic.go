@@ -0,0 +1,114 @@
+package treadmarks
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+//The operations of an AtomicRequest.
+const (
+	atomicAdd = iota
+	atomicCAS
+	atomicSwap
+)
+
+//FetchAndAdd adds delta to the int64 at addr, and returns the value it had.
+//Atomic operations on an address are executed one at a time by its owner, and need no lock. Like a lock handoff,
+//the answer carries the intervals the caller is missing, so later acquirers of the caller's locks see the result,
+//and the owner gets the intervals of the caller, so the hosts that operate on the address after it see its writes.
+//The address must be 8 byte aligned, and mustn't be written other than with atomic operations.
+//The int64 is stored little-endian.
+func (t *TreadmarksApi) FetchAndAdd(addr int, delta int64) (int64, error) {
+	return t.requestAtomic(AtomicRequest{
+		Op:    atomicAdd,
+		Addr:  int32(addr),
+		Value: delta,
+	})
+}
+
+//CompareAndSwap sets the int64 at addr to val if it is old, and tells if it did.
+func (t *TreadmarksApi) CompareAndSwap(addr int, old, val int64) (bool, error) {
+	res, err := t.requestAtomic(AtomicRequest{
+		Op:    atomicCAS,
+		Addr:  int32(addr),
+		Old:   old,
+		Value: val,
+	})
+	return err == nil && res == old, err
+}
+
+//Swap sets the int64 at addr to val, and returns the value it had.
+func (t *TreadmarksApi) Swap(addr int, val int64) (int64, error) {
+	return t.requestAtomic(AtomicRequest{
+		Op:    atomicSwap,
+		Addr:  int32(addr),
+		Value: val,
+	})
+}
+
+//getAtomicOwner returns the host that executes the atomic operations on an address. The pages are spread over the hosts.
+func (t *TreadmarksApi) getAtomicOwner(addr int) uint8 {
+	return uint8(addr / t.pageByteSize % int(t.nrProcs))
+}
+
+func (t *TreadmarksApi) requestAtomic(req AtomicRequest) (int64, error) {
+	addr := int(req.Addr)
+	if addr < 0 || addr+8 > t.memory.Size() {
+		return 0, errors.New("atomic operation out of bounds")
+	}
+	if addr%8 != 0 {
+		return 0, errors.New("atomic operations need an 8 byte aligned address")
+	}
+	owner := t.getAtomicOwner(addr)
+	t.newInterval()
+	req.From = t.myId
+	req.Timestamp = t.knownTimestamp()
+	if owner == t.myId {
+		return t.executeAtomic(req), nil
+	}
+	reqId, reply := t.newRequest()
+	req.ReqId = reqId
+	req.Intervals = t.getMissingIntervals(t.getHighestTimestamp(owner))
+	t.sendMessage(owner, 20, req)
+	resp := (<-reply).(AtomicResponse)
+	t.addMissingIntervals(resp.Intervals)
+	return resp.Value, nil
+}
+
+func (t *TreadmarksApi) handleAtomicRequest(req AtomicRequest) {
+	old := t.executeAtomic(req)
+	resp := AtomicResponse{
+		ReqId:     req.ReqId,
+		Value:     old,
+		Intervals: t.getMissingIntervals(req.Timestamp),
+	}
+	t.sendMessage(req.From, 21, resp)
+}
+
+//executeAtomic executes an atomic operation at the owner of its address, and returns the value before it.
+//The write is closed in an interval at once, so it is passed on with the answer.
+func (t *TreadmarksApi) executeAtomic(req AtomicRequest) int64 {
+	t.atomicLock.Lock()
+	defer t.atomicLock.Unlock()
+	t.addMissingIntervals(req.Intervals)
+	addr := int(req.Addr)
+	b, _ := t.memory.ReadBytes(addr, 8)
+	old := int64(binary.LittleEndian.Uint64(b))
+	val := old
+	switch req.Op {
+	case atomicAdd:
+		val = old + req.Value
+	case atomicCAS:
+		if old == req.Old {
+			val = req.Value
+		}
+	case atomicSwap:
+		val = req.Value
+	}
+	if val != old {
+		binary.LittleEndian.PutUint64(b, uint64(val))
+		t.memory.WriteBytes(addr, b)
+		t.newInterval()
+	}
+	return old
+}
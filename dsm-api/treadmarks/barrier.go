@@ -0,0 +1,202 @@
+package treadmarks
+
+import "strconv"
+
+//BarrierAlgorithm is the way hosts meet at a barrier.
+type BarrierAlgorithm int
+
+const (
+	//CentralBarrier sends every arrival to the manager of the barrier, which answers all hosts when the last has arrived.
+	CentralBarrier BarrierAlgorithm = iota
+	//TreeBarrier combines the arrivals up a tree of hosts rooted at the first host of the barrier, and releases the
+	//hosts down the same tree.
+	//No host handles more than barrierTreeArity+1 messages per barrier.
+	TreeBarrier
+	//DisseminationBarrier has every host signal another in each of log2(nrProcs) rounds, so there is no root to wait for.
+	DisseminationBarrier
+)
+
+//barrierTreeArity is the number of children of a host in the tree barrier.
+const barrierTreeArity = 4
+
+//The rounds of the tree barrier. Dissemination rounds count up from 0.
+const (
+	treeArrival = -1
+	treeRelease = -2
+)
+
+//treeBarrier waits for the children of this host to arrive and merges their intervals, passes the arrival on to
+//its parent, and then releases the children with the intervals each of them is missing.
+//The tree is laid out over the hosts of the barrier, with the first of them at the root.
+func (t *TreadmarksApi) treeBarrier(id int) {
+	hosts := t.barrierHosts(id)
+	base := t.getBarrierBase(id)
+	t.newInterval()
+	arrived := t.Timestamp()
+	me := hostPosition(hosts, t.myId)
+	children := make(map[uint8]Timestamp)
+	for c := me*barrierTreeArity + 1; c <= (me+1)*barrierTreeArity && c < len(hosts); c++ {
+		msg := t.awaitBarrierMessage(id, treeArrival)
+		t.addMissingIntervals(msg.Intervals)
+		arrived = arrived.merge(msg.Arrived)
+		children[msg.From] = msg.Timestamp
+	}
+	if me != 0 {
+		//the parent has every interval covered by the last barrier, and its subtree sends it the rest
+		t.sendBarrierMessage(hosts[(me-1)/barrierTreeArity], id, treeArrival, arrived, t.getMissingIntervals(base))
+		msg := t.awaitBarrierMessage(id, treeRelease)
+		t.addMissingIntervals(msg.Intervals)
+		arrived = msg.Arrived
+	}
+	for c, ts := range children {
+		t.sendBarrierMessage(c, id, treeRelease, arrived, t.getMissingIntervals(ts))
+	}
+	//the hosts that arrived may know of intervals this host has only in part, so they are fetched after the barrier
+	t.mergeTimestamp(arrived)
+	t.setBarrierBase(id, arrived)
+}
+
+//disseminationBarrier signals the host 2^r places after this one among the hosts of the barrier, and waits for the
+//host 2^r places before it, in round r. After the last round every host has heard from all others, through the
+//intervals and arrival timestamps passed on in each round.
+func (t *TreadmarksApi) disseminationBarrier(id int) {
+	hosts := t.barrierHosts(id)
+	base := t.getBarrierBase(id)
+	t.newInterval()
+	arrived := t.Timestamp()
+	me := hostPosition(hosts, t.myId)
+	n := len(hosts)
+	for round, dist := 0, 1; dist < n; round, dist = round+1, dist*2 {
+		to := hosts[(me+dist)%n]
+		t.sendBarrierMessage(to, id, round, arrived, t.getMissingIntervals(base))
+		msg := t.awaitBarrierMessage(id, round)
+		t.addMissingIntervals(msg.Intervals)
+		arrived = arrived.merge(msg.Arrived)
+	}
+	//the hosts that arrived may know of intervals this host has only in part, so they are fetched after the barrier
+	t.mergeTimestamp(arrived)
+	t.setBarrierBase(id, arrived)
+}
+
+/*
+	barrierHosts returns the hosts that meet at the barrier. For a barrier made by NewBarrier, only the manager
+	knows them, so the other hosts ask it the first time they meet at the barrier.
+*/
+func (t *TreadmarksApi) barrierHosts(id int) []uint8 {
+	var hosts []uint8
+	if id >= dynamicIdBase {
+		t.barriersLock.Lock()
+		known, ok := t.barrierParticipants[id]
+		t.barriersLock.Unlock()
+		if ok {
+			return known
+		}
+		manager := t.getManagerId(id)
+		if manager == t.myId {
+			t.barriersLock.Lock()
+			hosts = t.getBarrier(id).participants
+			t.barriersLock.Unlock()
+		} else {
+			reqId, reply := t.newRequest()
+			req := ParticipantsRequest{
+				From:      t.myId,
+				ReqId:     reqId,
+				BarrierId: int32(id),
+			}
+			t.sendMessage(manager, 27, req)
+			hosts = (<-reply).(ParticipantsResponse).Participants
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = make([]uint8, t.nrProcs)
+		for i := range hosts {
+			hosts[i] = uint8(i)
+		}
+	}
+	if id >= dynamicIdBase {
+		t.barriersLock.Lock()
+		t.barrierParticipants[id] = hosts
+		t.barriersLock.Unlock()
+	}
+	return hosts
+}
+
+func (t *TreadmarksApi) handleParticipantsRequest(req ParticipantsRequest) {
+	t.barriersLock.Lock()
+	resp := ParticipantsResponse{
+		ReqId:        req.ReqId,
+		Participants: t.getBarrier(int(req.BarrierId)).participants,
+	}
+	t.barriersLock.Unlock()
+	t.sendMessage(req.From, 28, resp)
+}
+
+//hostPosition returns the position of the host among the hosts of a barrier.
+func hostPosition(hosts []uint8, id uint8) int {
+	for i, h := range hosts {
+		if h == id {
+			return i
+		}
+	}
+	panic("host " + strconv.Itoa(int(id)) + " doesn't take part in the barrier")
+}
+
+/*
+	getBarrierBase returns a timestamp whose intervals every host of the barrier has. Those are the intervals
+	covered by the last tree or dissemination barrier all hosts met at, and by the last time the hosts of a barrier
+	made by NewBarrier met at it. As every host has all intervals of each host up to both, it has them up to the merge.
+*/
+func (t *TreadmarksApi) getBarrierBase(id int) Timestamp {
+	t.barriersLock.Lock()
+	defer t.barriersLock.Unlock()
+	if base, ok := t.barrierBases[id]; ok {
+		return t.barrierBase.merge(base)
+	}
+	return t.barrierBase
+}
+
+func (t *TreadmarksApi) setBarrierBase(id int, arrived Timestamp) {
+	t.barriersLock.Lock()
+	defer t.barriersLock.Unlock()
+	if id >= dynamicIdBase {
+		t.barrierBases[id] = arrived
+	} else {
+		t.barrierBase = arrived
+	}
+}
+
+func (t *TreadmarksApi) sendBarrierMessage(to uint8, id, round int, arrived Timestamp, intervals []IntervalRecord) {
+	msg := BarrierMessage{
+		From:      t.myId,
+		BarrierId: int32(id),
+		Round:     int32(round),
+		Timestamp: t.knownTimestamp(),
+		Arrived:   arrived,
+		Intervals: intervals,
+	}
+	t.sendMessage(to, 22, msg)
+}
+
+//awaitBarrierMessage waits for a message in the round of the barrier, and takes it.
+func (t *TreadmarksApi) awaitBarrierMessage(id, round int) BarrierMessage {
+	key := barrierRound{id, round}
+	t.barriersLock.Lock()
+	defer t.barriersLock.Unlock()
+	for len(t.barrierMessages[key]) == 0 {
+		t.barrierArrived.Wait()
+	}
+	msg := t.barrierMessages[key][0]
+	t.barrierMessages[key] = t.barrierMessages[key][1:]
+	return msg
+}
+
+//handleBarrierMessage keeps the message until the host reaches its round. A host may be some rounds behind the sender,
+//or still be in an earlier use of the barrier.
+func (t *TreadmarksApi) handleBarrierMessage(msg BarrierMessage) {
+	t.logger.Debugf("barrier message from host %v for barrier %v round %v", msg.From, msg.BarrierId, msg.Round)
+	key := barrierRound{int(msg.BarrierId), int(msg.Round)}
+	t.barriersLock.Lock()
+	t.barrierMessages[key] = append(t.barrierMessages[key], msg)
+	t.barriersLock.Unlock()
+	t.barrierArrived.Broadcast()
+}
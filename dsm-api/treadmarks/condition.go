@@ -0,0 +1,182 @@
+package treadmarks
+
+import "strconv"
+
+//The operations of a SyncRequest.
+const (
+	opCondWait = iota
+	opCondSignal
+	opCondBroadcast
+	opSemAcquire
+	opSemRelease
+)
+
+//Wait releases the lock, waits until the condition variable is signalled and then takes the lock again.
+//The caller must hold the lock. Condition variables are managed by the manager of their lock, and need not be made first.
+//As another host may take the lock before the woken host gets it back, the condition should be checked again after Wait.
+func (t *TreadmarksApi) Wait(lockId, condId int) {
+	wakeId, wake := t.newRequest()
+	t.requestSync(t.getManagerId(lockId), SyncRequest{
+		Op:     opCondWait,
+		WakeId: wakeId,
+		Id:     int32(lockId),
+		CondId: int32(condId),
+	})
+	t.ReleaseLock(lockId)
+	t.addMissingIntervals((<-wake).(SyncResponse).Intervals)
+	t.AcquireLock(lockId)
+}
+
+//Signal wakes up one of the hosts waiting on the condition variable, if any.
+//The woken host sees all writes this host has seen before the signal.
+func (t *TreadmarksApi) Signal(lockId, condId int) {
+	t.requestSync(t.getManagerId(lockId), SyncRequest{
+		Op:     opCondSignal,
+		Id:     int32(lockId),
+		CondId: int32(condId),
+	})
+}
+
+//Broadcast wakes up all hosts waiting on the condition variable.
+func (t *TreadmarksApi) Broadcast(lockId, condId int) {
+	t.requestSync(t.getManagerId(lockId), SyncRequest{
+		Op:     opCondBroadcast,
+		Id:     int32(lockId),
+		CondId: int32(condId),
+	})
+}
+
+//NewSemaphore makes a counting semaphore with the given value and returns its id, which is valid at every host.
+//The semaphore is managed by this host. Call it after Join.
+func (t *TreadmarksApi) NewSemaphore(value int) int {
+	id := t.newSyncId()
+	t.syncLock.Lock()
+	t.semaphores[id] = &semaphore{value: value}
+	t.syncLock.Unlock()
+	return id
+}
+
+//AcquireSemaphore waits until the value of the semaphore is above zero, and decrements it.
+//The host sees all writes that the hosts releasing the semaphore had seen before they released it.
+func (t *TreadmarksApi) AcquireSemaphore(id int) {
+	t.requestSync(t.getManagerId(id), SyncRequest{
+		Op: opSemAcquire,
+		Id: int32(id),
+	})
+}
+
+//ReleaseSemaphore increments the value of the semaphore, or hands it to a host waiting for it.
+func (t *TreadmarksApi) ReleaseSemaphore(id int) {
+	t.requestSync(t.getManagerId(id), SyncRequest{
+		Op: opSemRelease,
+		Id: int32(id),
+	})
+}
+
+//requestSync sends a request to the manager of a condition variable or semaphore, and waits for the answer.
+//As with a barrier, the request carries the intervals the manager may be missing, so it can pass them on.
+func (t *TreadmarksApi) requestSync(managerId uint8, req SyncRequest) {
+	reqId, reply := t.newRequest()
+	t.newInterval()
+	req.From = t.myId
+	req.ReqId = reqId
+	req.Timestamp = t.knownTimestamp()
+	if managerId == t.myId {
+		t.handleSyncRequest(req)
+	} else {
+		req.Intervals = t.getMissingIntervals(t.getHighestTimestamp(managerId))
+		t.sendMessage(managerId, 18, req)
+	}
+	t.addMissingIntervals((<-reply).(SyncResponse).Intervals)
+}
+
+func (t *TreadmarksApi) handleSyncRequest(req SyncRequest) {
+	t.addMissingIntervals(req.Intervals)
+	t.syncLock.Lock()
+	defer t.syncLock.Unlock()
+	switch req.Op {
+	case opCondWait:
+		key := condKey{int(req.Id), int(req.CondId)}
+		t.conds[key] = append(t.conds[key], syncWaiter{req.From, req.WakeId, req.Timestamp})
+	case opCondSignal, opCondBroadcast:
+		key := condKey{int(req.Id), int(req.CondId)}
+		waiters := t.conds[key]
+		n := len(waiters)
+		if req.Op == opCondSignal && n > 1 {
+			n = 1
+		}
+		for _, w := range waiters[:n] {
+			t.wake(w)
+		}
+		if n == len(waiters) {
+			delete(t.conds, key)
+		} else {
+			t.conds[key] = waiters[n:]
+		}
+	case opSemAcquire:
+		s := t.getSemaphore(int(req.Id))
+		w := syncWaiter{req.From, req.ReqId, req.Timestamp}
+		if s.value > 0 {
+			s.value--
+			t.wake(w)
+		} else {
+			s.waiters = append(s.waiters, w)
+		}
+		//the request is answered when the semaphore is granted
+		return
+	case opSemRelease:
+		s := t.getSemaphore(int(req.Id))
+		if len(s.waiters) > 0 {
+			t.wake(s.waiters[0])
+			s.waiters = s.waiters[1:]
+		} else {
+			s.value++
+		}
+	}
+	t.sendSyncResponse(req.From, req.ReqId, nil)
+}
+
+//wake answers a waiting host with the intervals it is missing, like the holder of a lock does when it passes it on.
+//The caller holds syncLock.
+func (t *TreadmarksApi) wake(w syncWaiter) {
+	t.newInterval()
+	t.sendSyncResponse(w.host, w.reqId, t.getMissingIntervals(w.timestamp))
+}
+
+func (t *TreadmarksApi) sendSyncResponse(to uint8, reqId uint32, intervals []IntervalRecord) {
+	resp := SyncResponse{
+		ReqId:     reqId,
+		Intervals: intervals,
+	}
+	if to == t.myId {
+		//the manager has all intervals already
+		resp.Intervals = nil
+		t.reply(reqId, resp)
+		return
+	}
+	t.sendMessage(to, 19, resp)
+}
+
+//getSemaphore returns the semaphore with the id at its manager. The caller holds syncLock.
+func (t *TreadmarksApi) getSemaphore(id int) *semaphore {
+	s, ok := t.semaphores[id]
+	if !ok {
+		panic("no semaphore with id " + strconv.Itoa(id))
+	}
+	return s
+}
+
+//addMissingIntervals adds the intervals this host hasn't seen. Unlike a lock handoff, the sender of a
+//sync message doesn't know the timestamp of this host, so it may send intervals that are known already.
+//mergeInterval skips those.
+func (t *TreadmarksApi) addMissingIntervals(intervals []IntervalRecord) {
+	if len(intervals) == 0 {
+		return
+	}
+	t.intervalLock.Lock()
+	defer t.intervalLock.Unlock()
+	t.closeInterval()
+	for i := len(intervals); i > 0; i-- {
+		t.mergeInterval(intervals[i-1], false)
+	}
+}
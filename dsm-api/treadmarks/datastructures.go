@@ -24,7 +24,12 @@ type accessControl struct {
 	write     bitarray.BitArray
 }
 
+//pageArrayEntry is the state of one page. Its Locker guards the fields below fetchLock. It is taken after
+//dirtyPagesLock and diffLock, and is never held while waiting for a reply.
 type pageArrayEntry struct {
+	sync.Locker
+	fetchLock *sync.Mutex //held while a copy or diffs for the page are being fetched
+	twin      []byte      //the page as it was before this host started writing to it
 	index []int
 	hasMissingDiffs bool
 	hasCopy      bool
@@ -46,6 +51,8 @@ func NewPageArrayEntry(nrProcs uint8) *pageArrayEntry {
 		wnl[j] = make([]WritenoticeRecord, 0)
 	}
 	entry := &pageArrayEntry{
+		Locker:    new(sync.Mutex),
+		fetchLock : new(sync.Mutex),
 		index : make([]int, nrProcs),
 		hasCopy:      false,
 		copySet:      []uint8{0},
@@ -64,9 +71,54 @@ func NewProcArray(nrProcs uint8) [][]IntervalRecord {
 
 type lock struct {
 	sync.Locker
+	granted       chan bool //receives when this host has taken the lock it waited for
 	locked        bool
 	haveToken     bool
 	last          uint8
 	nextId        uint8
 	nextTimestamp Timestamp
+	nextScope     []int16              //the scope sent with the request of nextId
+	readers       int                  //hosts holding the lock in shared mode, granted by this host
+	readQueue     []LockAcquireRequest //shared requests to grant when this host releases the lock
+	waiting       bool                 //this host waits for the readers to leave, to take the lock itself
+	reading       bool                 //this host holds the lock in shared mode
+	grantedBy     uint8                //the host that granted this host the lock in shared mode
+	withdrawn     bool                 //the request for the token timed out, so the token is passed on when it arrives
+	scope         map[int16]bool       //the pages bound to the lock with BindLock, or nil if it has none
+}
+
+//barrier is the state of a barrier at the host that manages it.
+type barrier struct {
+	participants []uint8 //the hosts that meet at the barrier, or nil for all of them
+	requests     []BarrierRequest
+}
+
+//barrierRound identifies the messages a host waits for in a round of a tree or dissemination barrier.
+type barrierRound struct {
+	id, round int
+}
+
+//size returns the number of hosts that meet at the barrier.
+func (b *barrier) size(nrProcs uint8) int {
+	if b.participants == nil {
+		return int(nrProcs)
+	}
+	return len(b.participants)
+}
+
+//syncWaiter is a host waiting on a condition variable or semaphore, at its manager.
+type syncWaiter struct {
+	host      uint8
+	reqId     uint32 //the request to answer when the host is woken up
+	timestamp Timestamp
+}
+
+//condKey identifies a condition variable by its lock and its id under the lock.
+type condKey struct {
+	lockId, condId int
+}
+
+type semaphore struct {
+	value   int
+	waiters []syncWaiter
 }
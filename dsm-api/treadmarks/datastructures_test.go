@@ -0,0 +1,230 @@
+package treadmarks
+
+import (
+	"DSM-project/memory"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//newTestHost makes a host of 2 processes with 8 pages of 8 bytes, that is not connected to anything.
+func newTestHost() *TreadmarksApi {
+	tm, _ := NewTreadmarksApi(64, 8, 2, 1, 1)
+	return tm
+}
+
+func TestNewPageArray(t *testing.T) {
+	pa := NewPageArray(4, 3)
+	assert.Len(t, pa, 4)
+	for _, page := range pa {
+		assert.False(t, page.hasCopy)
+		assert.False(t, page.hasMissingDiffs)
+		assert.Equal(t, []uint8{0}, page.copySet)
+		assert.Len(t, page.writenotices, 3)
+		for _, wnl := range page.writenotices {
+			assert.Len(t, wnl, 0)
+		}
+		assert.Equal(t, []int{0, 0, 0}, page.index)
+	}
+	assert.False(t, pa[0].Locker == pa[1].Locker)
+}
+
+func TestNewProcArray(t *testing.T) {
+	pa := NewProcArray(3)
+	assert.Len(t, pa, 3)
+	for _, intervals := range pa {
+		assert.Len(t, intervals, 0)
+	}
+}
+
+func TestTreadmarksApi_newInterval(t *testing.T) {
+	tm := newTestHost()
+	tm.newInterval()
+	assert.Len(t, tm.Intervals(0), 0)
+	assert.Equal(t, Timestamp{0, 0}, tm.Timestamp())
+
+	tm.dirtyPages[1] = true
+	tm.dirtyPages[3] = true
+	tm.newInterval()
+	assert.Equal(t, Timestamp{1, 0}, tm.Timestamp())
+	assert.Len(t, tm.Intervals(0), 1)
+	assert.Equal(t, Timestamp{1, 0}, tm.Intervals(0)[0].Timestamp)
+	assert.ElementsMatch(t, []int16{1, 3}, tm.Intervals(0)[0].Pages)
+	assert.Len(t, tm.Writenotices(0, 1), 1)
+	assert.Len(t, tm.Writenotices(0, 3), 1)
+	assert.Len(t, tm.Writenotices(0, 2), 0)
+	assert.Len(t, tm.dirtyPages, 0)
+
+	//no pages were written since the last interval, so there is no new one
+	tm.newInterval()
+	assert.Len(t, tm.Intervals(0), 1)
+	assert.Equal(t, Timestamp{1, 0}, tm.Timestamp())
+}
+
+func TestTreadmarksApi_addInterval(t *testing.T) {
+	tm := newTestHost()
+	interval := IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 1}, Pages: []int16{2, 3}}
+	tm.addInterval(interval)
+	assert.Equal(t, Timestamp{0, 1}, tm.Timestamp())
+	assert.Len(t, tm.Intervals(1), 1)
+	assert.Len(t, tm.Intervals(0), 0)
+	assert.Len(t, tm.Writenotices(1, 2), 1)
+	assert.Len(t, tm.Writenotices(1, 3), 1)
+	assert.Equal(t, Timestamp{0, 1}, tm.Writenotices(1, 2)[0].Timestamp)
+	assert.Nil(t, tm.Writenotices(1, 2)[0].Diff)
+	assert.True(t, tm.hasMissingDiffs(2))
+	assert.False(t, tm.hasMissingDiffs(1))
+	assert.Equal(t, memory.NO_ACCESS, tm.memory.GetRights(2*8))
+
+	//an interval this host has already seen is not added again
+	tm.addInterval(interval)
+	assert.Len(t, tm.Intervals(1), 1)
+	assert.Len(t, tm.Writenotices(1, 2), 1)
+}
+
+func TestTreadmarksApi_getMissingIntervals(t *testing.T) {
+	tm := newTestHost()
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 1}, Pages: []int16{0}})
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 2}, Pages: []int16{0}})
+	tm.dirtyPages[4] = true
+	tm.newInterval()
+	assert.Equal(t, Timestamp{1, 2}, tm.Timestamp())
+
+	assert.Len(t, tm.getMissingIntervals(Timestamp{1, 2}), 0)
+	missing := tm.getMissingIntervals(Timestamp{0, 1})
+	assert.Len(t, missing, 2)
+	assert.Equal(t, Timestamp{1, 2}, missing[0].Timestamp)
+	assert.Equal(t, Timestamp{0, 2}, missing[1].Timestamp)
+
+	//intervals are returned newest first
+	missing = tm.getMissingIntervalsForProc(1, Timestamp{0, 0})
+	assert.Equal(t, Timestamp{0, 2}, missing[0].Timestamp)
+	assert.Equal(t, Timestamp{0, 1}, missing[1].Timestamp)
+}
+
+//countWritenotices returns the number of write notices in the intervals.
+func countWritenotices(intervals []IntervalRecord) int {
+	n := 0
+	for _, interval := range intervals {
+		n += len(interval.Pages)
+	}
+	return n
+}
+
+func TestTreadmarksApi_lockAcquireResponse(t *testing.T) {
+	tm := newTestHost()
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 1}, Pages: []int16{1, 2}})
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 2}, Pages: []int16{2}})
+	tm.dirtyPages[3] = true
+	tm.dirtyPages[4] = true
+	tm.newInterval()
+
+	resp := tm.lockAcquireResponse(0, Timestamp{0, 0}, false, nil)
+	assert.Len(t, resp.Intervals, 3)
+	assert.Equal(t, 5, countWritenotices(resp.Intervals))
+
+	//only the write notices for the scope are sent, and intervals without any are left out
+	resp = tm.lockAcquireResponse(0, Timestamp{0, 0}, false, []int16{1, 3})
+	assert.Len(t, resp.Intervals, 2)
+	assert.Equal(t, 2, countWritenotices(resp.Intervals))
+	assert.Equal(t, Timestamp{1, 2}, resp.Timestamp)
+
+	//the intervals in the proc array are not changed
+	assert.Equal(t, 5, countWritenotices(tm.getMissingIntervals(Timestamp{0, 0})))
+}
+
+func TestTreadmarksApi_mergePartialInterval(t *testing.T) {
+	tm := newTestHost()
+	partial := IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 1}, Pages: []int16{1}}
+	tm.addGrantedIntervals(LockAcquireResponse{Intervals: []IntervalRecord{partial}, Timestamp: Timestamp{0, 2}}, true)
+	assert.Equal(t, Timestamp{0, 2}, tm.Timestamp())
+	assert.Equal(t, Timestamp{0, 0}, tm.knownTimestamp())
+	assert.Len(t, tm.Intervals(1), 0)
+	assert.Len(t, tm.Writenotices(1, 1), 1)
+	assert.Equal(t, []uint8{1}, tm.heldBack())
+
+	//the whole intervals come later, and only the notices that weren't there are added
+	tm.addMissingIntervals([]IntervalRecord{
+		{Owner: 1, Timestamp: Timestamp{0, 2}, Pages: []int16{2}},
+		{Owner: 1, Timestamp: Timestamp{0, 1}, Pages: []int16{1, 2}},
+	})
+	assert.Equal(t, Timestamp{0, 2}, tm.knownTimestamp())
+	assert.Len(t, tm.Intervals(1), 2)
+	assert.Len(t, tm.Writenotices(1, 1), 1)
+	assert.Len(t, tm.Writenotices(1, 2), 2)
+	assert.Len(t, tm.heldBack(), 0)
+}
+
+func TestTreadmarksApi_closeTwin(t *testing.T) {
+	tm := newTestHost()
+	page := tm.pagearray[1]
+	page.twin = make([]byte, 8)
+	tm.memory.PrivilegedWrite(8, []byte{0, 5, 0, 0, 0, 0, 7, 0})
+	tm.dirtyPages[1] = true
+	tm.closeTwin(1)
+	assert.Nil(t, page.twin)
+	assert.Len(t, tm.Writenotices(0, 1), 1)
+	assert.Equal(t, map[int]byte{1: 5, 6: 7}, tm.Writenotices(0, 1)[0].Diff)
+	assert.Equal(t, memory.READ_ONLY, tm.memory.GetRights(8))
+
+	//a page that was written back to what it was has no diff, so its write notice is dropped
+	page = tm.pagearray[2]
+	page.twin = make([]byte, 8)
+	tm.dirtyPages[2] = true
+	tm.closeTwin(2)
+	assert.Len(t, tm.Writenotices(0, 2), 0)
+}
+
+func TestTreadmarksApi_createDiffRequest(t *testing.T) {
+	tm := newTestHost()
+	assert.Nil(t, tm.createDiffRequest(0, 1).Last)
+
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 1}, Pages: []int16{0}})
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 2}, Pages: []int16{0}})
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 3}, Pages: []int16{0}})
+	tm.pagearray[0].writenotices[1][0].Diff = map[int]byte{0: 1}
+
+	//only the diffs after the last one this host has are requested
+	req := tm.createDiffRequest(0, 1)
+	assert.Equal(t, uint8(1), req.to)
+	assert.Equal(t, Timestamp{0, 2}, req.First)
+	assert.Equal(t, Timestamp{0, 3}, req.Last)
+	assert.Nil(t, tm.createDiffRequest(0, 0).Last)
+	assert.Len(t, tm.createDiffRequests(0), 1)
+}
+
+func TestTreadmarksApi_addDiffs(t *testing.T) {
+	tm := newTestHost()
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 1}, Pages: []int16{0}})
+	tm.addInterval(IntervalRecord{Owner: 1, Timestamp: Timestamp{0, 2}, Pages: []int16{0}})
+	tm.addDiffs(DiffResponse{
+		PageNr: 0,
+		Writenotices: []WritenoticeRecord{
+			{Owner: 1, Timestamp: Timestamp{0, 2}, Diff: map[int]byte{1: 2}},
+			{Owner: 1, Timestamp: Timestamp{0, 1}, Diff: map[int]byte{0: 1}},
+		},
+	})
+	assert.Equal(t, map[int]byte{0: 1}, tm.Writenotices(1, 0)[0].Diff)
+	assert.Equal(t, map[int]byte{1: 2}, tm.Writenotices(1, 0)[1].Diff)
+}
+
+func TestTreadmarksApi_applyAllDiffs(t *testing.T) {
+	tm := newTestHost()
+	page := tm.pagearray[0]
+	page.writenotices[1] = []WritenoticeRecord{
+		{Owner: 1, Timestamp: Timestamp{1, 1}, Diff: map[int]byte{0: 2, 1: 2}},
+	}
+	page.writenotices[0] = []WritenoticeRecord{
+		{Owner: 0, Timestamp: Timestamp{1, 0}, Diff: map[int]byte{0: 1}},
+		{Owner: 0, Timestamp: Timestamp{2, 1}, Diff: map[int]byte{1: 3}},
+	}
+
+	//the diffs are applied in the order of their timestamps, not by host
+	tm.applyAllDiffs(0)
+	assert.Equal(t, []byte{2, 3}, tm.memory.PrivilegedRead(0, 2))
+	assert.Equal(t, []int{2, 1}, page.index)
+
+	//diffs that were applied once are not applied again
+	tm.memory.PrivilegedWrite(0, []byte{9})
+	tm.applyAllDiffs(0)
+	assert.Equal(t, []byte{9, 3}, tm.memory.PrivilegedRead(0, 2))
+}
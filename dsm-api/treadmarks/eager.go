@@ -0,0 +1,36 @@
+package treadmarks
+
+//pushIntervals closes the current interval, and sends the intervals this host made since the last push to all other
+//hosts. Copy sets aren't kept, so every host is told. It returns when all of them have added the intervals.
+func (t *TreadmarksApi) pushIntervals() {
+	t.newInterval()
+	intervals := t.getMissingIntervalsForProc(t.myId, t.pushed)
+	t.pushed = t.Timestamp()
+	if len(intervals) == 0 {
+		return
+	}
+	replies := make([]chan interface{}, 0, t.nrProcs)
+	var proc uint8
+	for proc = 0; proc < t.nrProcs; proc++ {
+		if proc == t.myId {
+			continue
+		}
+		reqId, reply := t.newRequest()
+		msg := ReleaseNotice{
+			From:      t.myId,
+			ReqId:     reqId,
+			Intervals: intervals,
+		}
+		t.sendMessage(proc, 23, msg)
+		replies = append(replies, reply)
+	}
+	for _, reply := range replies {
+		<-reply
+	}
+}
+
+//handleReleaseNotice invalidates the pages written in the intervals of the notice, and acknowledges it.
+func (t *TreadmarksApi) handleReleaseNotice(msg ReleaseNotice) {
+	t.addMissingIntervals(msg.Intervals)
+	t.sendMessage(msg.From, 24, ReleaseAck{ReqId: msg.ReqId})
+}
@@ -5,60 +5,112 @@ import (
 	"DSM-project/memory"
 	"DSM-project/network"
 	"bytes"
-	"fmt"
+	"errors"
 	"github.com/davecgh/go-xdr/xdr2"
 	"math"
 	"runtime"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type TreadmarksApi struct {
 	shutdown                       chan bool
+	done                           chan bool //closed when the host shuts down
 	memory                         memory.VirtualMemory
 	myId, nrProcs                  uint8
 	memSize, pageByteSize, nrPages int
 	pagearray                      []*pageArrayEntry
-	twins                          [][]byte
-	twinsLock                      *sync.RWMutex
 	dirtyPages                     map[int16]bool
 	dirtyPagesLock                 *sync.RWMutex
 	procarray                      [][]IntervalRecord
-	locks                          []*lock
-	channel                        chan bool
-	barrier                        chan uint8
-	barrierreq                     []BarrierRequest
+	intervalLock                   *sync.RWMutex //guards procarray and timestamp. Taken before dirtyPagesLock and the page locks
+	locks                          map[int]*lock
+	locksLock                      *sync.Mutex
+	nrLocks                        int
+	channel                        chan bool //receives when this host has passed a barrier
+	barriers                       map[int]*barrier
+	barriersLock                   *sync.Mutex
+	barrierAlgorithm               BarrierAlgorithm
+	barrierMessages                map[barrierRound][]BarrierMessage //tree and dissemination messages not yet taken
+	barrierArrived                 *sync.Cond
+	barrierBase                    Timestamp //the merged arrival timestamps of the last tree or dissemination barrier of all hosts
+	barrierBases                   map[int]Timestamp //the same for the barriers made by NewBarrier, by id
+	barrierParticipants            map[int][]uint8   //the hosts of the barriers made by NewBarrier, as told by their managers
+	nextSyncId                     int32 //the sequence number of the last lock, barrier or semaphore made by this host
+	conds                          map[condKey][]syncWaiter
+	semaphores                     map[int]*semaphore
+	syncLock                       *sync.Mutex
+	atomicLock                     *sync.Mutex //held while an atomic operation is executed at this host
+	eager                          bool      //releases push write notices to all hosts, for eager release consistency
+	pushed                         Timestamp //the timestamp of the last push, when eager
 	in                             <-chan []byte
-	out                            chan<- []byte
 	conn                           network.Connection
 	group                          *sync.WaitGroup
 	timestamp                      Timestamp
 	diffLock                       *sync.Mutex
 	shouldLogMessages              bool
 	messageLog                     []int
+	messageLogLock                 *sync.Mutex
+	codec                          *network.Codec
+	pending                        map[uint32]chan interface{} //outstanding requests by ReqId
+	pendingLock                    *sync.Mutex
+	nextReqId                      uint32
+	readAhead                      int
+	lastFault                      int64 //last page of the latest fault, for detecting sequential access
+	analyseSharing                 bool
+	writes                         map[int16][]bool //offsets this host has written per page, when analysing sharing
+	writesLock                     *sync.Mutex
+	logger                         *network.Logger
 }
 
 var _ dsm_api.DSMApiInterface = new(TreadmarksApi)
 
 func NewTreadmarksApi(memSize, pageByteSize int, nrProcs, nrLocks, nrBarriers uint8) (*TreadmarksApi, error) {
+	return NewTreadmarksApiWithMemory(memory.NewVmem(memSize, pageByteSize), nrProcs, nrLocks, nrBarriers)
+}
+
+//NewEagerTreadmarksApi creates a host that uses eager release consistency. ReleaseLock and Barrier send the write
+//notices of the intervals this host made since its last release to all other hosts, which invalidate the pages at once,
+//and return when every host has them. Diffs are still fetched when a page faults. All hosts must use the same protocol.
+func NewEagerTreadmarksApi(memSize, pageByteSize int, nrProcs, nrLocks, nrBarriers uint8) (*TreadmarksApi, error) {
+	t, err := NewTreadmarksApi(memSize, pageByteSize, nrProcs, nrLocks, nrBarriers)
+	t.eager = true
+	return t, err
+}
+
+//NewTreadmarksApiWithMemory creates a host on top of the given virtual memory, so callers that already have one can share it.
+func NewTreadmarksApiWithMemory(vm memory.VirtualMemory, nrProcs, nrLocks, nrBarriers uint8) (*TreadmarksApi, error) {
 	var err error
 	t := new(TreadmarksApi)
-	t.memory = memory.NewVmem(memSize, pageByteSize)
+	memSize, pageByteSize := vm.Size(), vm.GetPageSize()
+	t.memory = vm
 	t.nrPages = int(math.Ceil(float64(memSize) / float64(pageByteSize)))
 	t.memSize, t.pageByteSize, t.nrProcs = memSize, pageByteSize, nrProcs
 	t.pagearray = NewPageArray(t.nrPages, nrProcs)
 	t.procarray = NewProcArray(nrProcs)
-	t.locks = make([]*lock, nrLocks)
+	t.nrLocks = int(nrLocks)
+	t.locksLock = new(sync.Mutex)
+	t.conds = make(map[condKey][]syncWaiter)
+	t.semaphores = make(map[int]*semaphore)
+	t.syncLock = new(sync.Mutex)
+	t.atomicLock = new(sync.Mutex)
 	t.channel = make(chan bool, 1)
 
-	t.barrierreq = make([]BarrierRequest, t.nrProcs)
 	t.timestamp = NewTimestamp(t.nrProcs)
-	t.twins = make([][]byte, t.nrPages)
+	t.intervalLock = new(sync.RWMutex)
+	t.pushed = NewTimestamp(t.nrProcs)
 	t.dirtyPages = make(map[int16]bool)
-	t.twinsLock = new(sync.RWMutex)
 	t.dirtyPagesLock = new(sync.RWMutex)
 	t.diffLock = new(sync.Mutex)
+	t.codec = new(network.Codec)
+	t.pending = make(map[uint32]chan interface{})
+	t.pendingLock = new(sync.Mutex)
+	t.lastFault = -1
+	t.writes = make(map[int16][]bool)
+	t.writesLock = new(sync.Mutex)
+	t.SetLogger(network.DefaultLogger())
 
 	return t, err
 }
@@ -68,14 +120,17 @@ func NewTreadmarksApi(memSize, pageByteSize int, nrProcs, nrLocks, nrBarriers ui
 //----------------------------------------------------------------//
 
 func (t *TreadmarksApi) Initialize(port int) error {
-	t.conn, t.in, t.out, _ = network.NewConnection(port, 10)
+	t.conn, t.in, _, _ = network.NewConnection(port, 10)
+	t.conn.SetLogger(t.logger)
 	t.memory.AddFaultListener(t.onFault)
 	t.group = new(sync.WaitGroup)
 	t.initializeBarriers()
 	t.initializeLocks()
 	t.shutdown = make(chan bool)
+	t.done = make(chan bool)
 	go t.handleIncoming()
-	t.messageLog = make([]int, 9)
+	t.messageLog = make([]int, len(messageNames))
+	t.messageLogLock = new(sync.Mutex)
 	return nil
 }
 
@@ -85,27 +140,61 @@ func (t *TreadmarksApi) Join(ip string, port int) error {
 		return err
 	}
 	t.myId = uint8(id)
+	t.logger.SetHost(id)
 	t.initializeLocks()
 	t.timestamp = NewTimestamp(t.nrProcs)
 	return nil
 }
 
+//messageNames are the names of the message types, in the order they are counted in messageLog.
+var messageNames = [...]string{
+	"Lock acquire request",
+	"Lock acquire response",
+	"Lock release",
+	"Barrier request",
+	"Barrier response",
+	"Copy request",
+	"Copy response",
+	"Diff request",
+	"Diff response",
+	"Diff batch request",
+	"Diff batch response",
+	"Malloc request",
+	"Malloc response",
+	"Free request",
+	"Free response",
+	"Write stats request",
+	"Write stats response",
+	"Read lock release",
+	"Sync request",
+	"Sync response",
+	"Atomic request",
+	"Atomic response",
+	"Barrier",
+	"Release notice",
+	"Release ack",
+	"Interval request",
+	"Interval response",
+	"Participants request",
+	"Participants response",
+}
+
 func (t *TreadmarksApi) Shutdown() error {
-	fmt.Println("Lock acquire request messages: ", t.messageLog[0])
-	fmt.Println("Lock acquire response messages: ", t.messageLog[1])
-	fmt.Println("Lock release messages: ", t.messageLog[2])
-	fmt.Println("Barrier request messages: ", t.messageLog[3])
-	fmt.Println("Barrier response messages: ", t.messageLog[4])
-	fmt.Println("Copy request messages: ", t.messageLog[5])
-	fmt.Println("Copy response messages: ", t.messageLog[6])
-	fmt.Println("Diff request messages: ", t.messageLog[7])
-	fmt.Println("Diff response messages: ", t.messageLog[8])
+	if t.logger.Enabled(network.LogInfo) {
+		t.messageLogLock.Lock()
+		for i, name := range messageNames {
+			t.logger.Infof("%v messages: %v", name, t.messageLog[i])
+		}
+		t.messageLogLock.Unlock()
+		messages, frames, bytes := t.conn.Stats()
+		t.logger.Infof("network messages: %v in %v frames of %v bytes", messages, frames, bytes)
+		raw, wire := t.codec.Stats()
+		t.logger.Infof("message bytes: %v sent as %v bytes", raw, wire)
+	}
+	close(t.done)
 	t.shutdown <- true
 	t.group.Wait()
 	t.conn.Close()
-
-
-
 	return nil
 }
 
@@ -125,50 +214,218 @@ func (t *TreadmarksApi) WriteBytes(addr int, val []byte) error {
 	return t.memory.WriteBytes(addr, val)
 }
 
-func (t *TreadmarksApi) Malloc(size int) (int, error) {
-	return t.memory.Malloc(size)
+//Malloc allocates shared memory. All allocations are made by the heap manager, so every host sees the same heap.
+func (t *TreadmarksApi) Malloc(size int, flags ...memory.AllocFlag) (int, error) {
+	managerId := t.getHeapManagerId()
+	if t.myId == managerId {
+		return t.memory.Malloc(size, flags...)
+	}
+	id, reply := t.newRequest()
+	req := MallocRequest{
+		From:  t.myId,
+		ReqId: id,
+		Size:  int32(size),
+	}
+	for _, i := range memory.FlagsToInts(flags) {
+		req.Flags = append(req.Flags, int32(i))
+	}
+	t.sendMessage(managerId, 11, req)
+	resp := (<-reply).(MallocResponse)
+	if resp.Err != "" {
+		return 0, allocError(resp.Err)
+	}
+	return int(resp.Addr), nil
 }
 
 func (t *TreadmarksApi) Free(addr, size int) error {
-	return t.memory.Free(addr)
+	managerId := t.getHeapManagerId()
+	if t.myId == managerId {
+		return t.memory.Free(addr)
+	}
+	id, reply := t.newRequest()
+	req := FreeRequest{
+		From:  t.myId,
+		ReqId: id,
+		Addr:  int32(addr),
+	}
+	t.sendMessage(managerId, 13, req)
+	resp := (<-reply).(FreeResponse)
+	return allocError(resp.Err)
+}
+
+//Barrier waits for the hosts of the barrier. Afterwards all writes made before the barrier are visible,
+//including those held back by acquires of locks with a scope.
+func (t *TreadmarksApi) Barrier(id int) {
+	if t.eager {
+		t.pushIntervals()
+	}
+	switch {
+	case t.barrierAlgorithm == TreeBarrier:
+		t.treeBarrier(id)
+	case t.barrierAlgorithm == DisseminationBarrier:
+		t.disseminationBarrier(id)
+	default:
+		t.sendBarrierRequest(id)
+	}
+	t.fetchHeldBack()
+}
+
+//NewLock makes a lock and returns its id. The id is valid at every host, so it can be handed to others through shared memory.
+//The lock is managed by this host, which holds its token at first. Call it after Join.
+func (t *TreadmarksApi) NewLock() int {
+	return t.newSyncId()
+}
+
+//NewBarrier makes a barrier for the given hosts and returns its id, which is valid at every host.
+//With no participants, all hosts meet at the barrier. Only the participants may call Barrier with the id.
+//The barrier is managed by this host, which need not be one of the participants. Call it after Join.
+func (t *TreadmarksApi) NewBarrier(participants []int) int {
+	b := new(barrier)
+	for _, p := range participants {
+		if p < 0 || p >= int(t.nrProcs) {
+			panic("no host with id " + strconv.Itoa(p))
+		}
+		b.participants = append(b.participants, uint8(p))
+	}
+	id := t.newSyncId()
+	t.barriersLock.Lock()
+	t.barriers[id] = b
+	t.barriersLock.Unlock()
+	return id
 }
 
-func (t *TreadmarksApi) Barrier(id uint8) {
-	t.sendBarrierRequest(id)
+//newSyncId returns a new lock or barrier id. Ids made by a host carry its id in the low byte,
+//so hosts never make the same id, and every host can tell which host manages it.
+func (t *TreadmarksApi) newSyncId() int {
+	seq := atomic.AddInt32(&t.nextSyncId, 1)
+	return int(seq)<<8 | int(t.myId)
 }
 
-func (t *TreadmarksApi) AcquireLock(id uint8) {
+func (t *TreadmarksApi) AcquireLock(id int) {
 	time.Sleep(0)
-	lock := t.locks[id]
+	lock := t.getLock(id)
 	lock.Lock()
-	if lock.haveToken {
-		if lock.locked {
-			panic("locking lock twice")
-		}
-		lock.locked = true
+	taken := t.requestLock(id, lock)
+	lock.Unlock()
+	if !taken {
+		<-lock.granted
+	}
+	t.completeAcquire(lock)
+}
+
+//TryAcquireLock takes the lock if this host has its token and nobody holds it. It never waits for the token,
+//and returns false at once if the token is elsewhere.
+func (t *TreadmarksApi) TryAcquireLock(id int) bool {
+	lock := t.getLock(id)
+	lock.Lock()
+	if !lock.haveToken || lock.locked || lock.reading || lock.readers > 0 {
 		lock.Unlock()
+		return false
+	}
+	lock.locked = true
+	lock.Unlock()
+	t.completeAcquire(lock)
+	return true
+}
+
+//AcquireLockTimeout is AcquireLock, but gives up after d and returns false.
+//A request that has been sent can't be taken back, as the hosts after it in the queue point to this host.
+//Instead the request is withdrawn: when the token arrives, it is passed on as if the lock was released at once.
+func (t *TreadmarksApi) AcquireLockTimeout(id int, d time.Duration) bool {
+	lock := t.getLock(id)
+	lock.Lock()
+	taken := t.requestLock(id, lock)
+	lock.Unlock()
+	if !taken && !t.awaitGrant(lock, d) {
+		return false
+	}
+	t.completeAcquire(lock)
+	return true
+}
+
+//awaitGrant waits up to d for the lock this host has requested. If it doesn't come, the request is withdrawn
+//and it returns false.
+func (t *TreadmarksApi) awaitGrant(lock *lock, d time.Duration) bool {
+	select {
+	case <-lock.granted:
+		return true
+	case <-time.After(d):
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if lock.locked {
+		//the lock came as we gave up
+		<-lock.granted
+		return true
+	}
+	if lock.waiting {
+		lock.waiting = false
 	} else {
-		t.sendLockAcquireRequest(lock.last, id)
+		lock.withdrawn = true
+	}
+	return false
+}
+
+//requestLock takes the lock if it can be taken at once, and returns true if so.
+//Otherwise it starts taking it, and lock.granted receives when it has been taken. The caller holds the lock.
+func (t *TreadmarksApi) requestLock(id int, lock *lock) bool {
+	if lock.locked || lock.reading {
+		panic("locking lock twice")
+	}
+	switch {
+	case lock.withdrawn:
+		//the request that was withdrawn is still on its way, so we wait for it again
+		lock.withdrawn = false
+	case lock.haveToken && lock.readers > 0:
+		lock.waiting = true
+	case lock.haveToken:
+		lock.locked = true
+		return true
+	default:
+		t.sendLockAcquireRequest(lock.last, id, scopePages(lock.scope))
 		lock.last = t.myId
-		lock.Unlock()
-		<-t.channel
 	}
+	return false
 }
 
-func (t *TreadmarksApi) ReleaseLock(id uint8) {
-	lock := t.locks[id]
+//Prefetch fetches copies and diffs for the pages in the range in the background, so the first read of them does not fault.
+//The returned channel receives nil when the pages are readable, or the error if they could not be fetched.
+func (t *TreadmarksApi) Prefetch(addr int, length int) <-chan error {
+	result := make(chan error, 1)
+	if addr < 0 || length < 0 || addr+length > t.memory.Size() {
+		result <- errors.New("prefetch range out of bounds")
+		return result
+	}
+	go func() {
+		result <- t.prefetch(t.pagesInRange(addr, length))
+	}()
+	return result
+}
+
+func (t *TreadmarksApi) ReleaseLock(id int) {
+	if t.eager {
+		t.pushIntervals()
+	}
+	lock := t.getLock(id)
 	lock.Lock()
 	defer lock.Unlock()
 	lock.locked = false
-	if lock.nextTimestamp != nil {
-		t.newInterval()
-		t.sendLockAcquireResponse(id, lock.nextId, lock.nextTimestamp)
-		lock.nextTimestamp = nil
-		lock.nextId = t.getManagerId(id)
-		lock.haveToken = false
+	t.grantQueuedReaders(id, lock)
+	if lock.readers == 0 && lock.nextTimestamp != nil {
+		t.passToken(id, lock)
 	}
 }
 
+//passToken sends the token of the lock to the next host in its queue. The caller holds the lock.
+func (t *TreadmarksApi) passToken(id int, lock *lock) {
+	t.newInterval()
+	t.sendLockAcquireResponse(id, lock.nextId, lock.nextTimestamp, false, lock.nextScope)
+	lock.nextTimestamp = nil
+	lock.nextScope = nil
+	lock.nextId = t.getManagerId(id)
+	lock.haveToken = false
+}
+
 //----------------------------------------------------------------//
 //                        Initialisation                          //
 //----------------------------------------------------------------//
@@ -178,36 +435,34 @@ func (t *TreadmarksApi) onFault(addr int, length int, faultType byte, accessType
 	for i := t.memory.GetPageAddr(addr); i < addr+length; i = i + t.memory.GetPageSize() {
 		addrList = append(addrList, i)
 	}
-	access := t.memory.GetRightsList(addrList)
 
-	for i := range access {
-		pageNr := int16(math.Floor(float64(t.memory.GetPageAddr(addr)) / float64(t.memory.GetPageSize())))
+	for i := range addrList {
+		pageNr := int16(math.Floor(float64(addrList[i]) / float64(t.memory.GetPageSize())))
 		if int(pageNr) >= len(t.pagearray) || pageNr < 0 {
-			fmt.Println(math.Floor(float64(addr) / float64(t.memory.GetPageSize())))
-			fmt.Println(addr, t.memory.GetPageSize())
-			fmt.Println(pageNr, len(t.pagearray), addr, t.memory.GetPageSize())
+			t.logger.Errorf("fault at %v of length %v is on page %v, but there are %v pages of %v bytes",
+				addr, length, pageNr, len(t.pagearray), t.memory.GetPageSize())
+			return errors.New("fault out of bounds")
 		}
 		page := t.pagearray[pageNr]
-		if access[i] == memory.NO_ACCESS {
-			if !page.hasCopy {
+		page.fetchLock.Lock()
+		if t.memory.GetRights(addrList[i]) == memory.NO_ACCESS {
+			if !t.hasCopy(pageNr) {
 				t.sendCopyRequest(pageNr)
-				<-t.channel
 			}
 			if t.hasMissingDiffs(pageNr) {
 				t.sendDiffRequests(pageNr)
 				t.applyAllDiffs(pageNr)
 			}
 		}
+		page.fetchLock.Unlock()
 		if accessType == "WRITE" {
-			t.twinsLock.Lock()
 			t.dirtyPagesLock.Lock()
-
-			t.twins[pageNr] = make([]byte, t.pageByteSize)
-
-			copy(t.twins[pageNr], t.memory.PrivilegedRead(t.memory.GetPageAddr(int(pageNr)), t.memory.GetPageSize()))
+			page.Lock()
+			page.twin = make([]byte, t.pageByteSize)
+			copy(page.twin, t.memory.PrivilegedRead(int(pageNr)*t.memory.GetPageSize(), t.memory.GetPageSize()))
+			page.Unlock()
 			t.dirtyPages[pageNr] = true
 			t.dirtyPagesLock.Unlock()
-			t.twinsLock.Unlock()
 		}
 	}
 
@@ -216,32 +471,193 @@ func (t *TreadmarksApi) onFault(addr int, length int, faultType byte, accessType
 	} else {
 		t.memory.SetRightsList(addrList, memory.READ_ONLY)
 	}
+	t.readAheadAfter(addrList)
+	return nil
+}
+
+//readAheadAfter prefetches the pages following a fault when the faults walk through memory page by page.
+func (t *TreadmarksApi) readAheadAfter(addrList []int) {
+	if t.readAhead <= 0 {
+		return
+	}
+	first := addrList[0] / t.pageByteSize
+	last := addrList[len(addrList)-1] / t.pageByteSize
+	prev := atomic.SwapInt64(&t.lastFault, int64(last))
+	if int64(first) != prev+1 {
+		return
+	}
+	pages := make([]int16, 0, t.readAhead)
+	for p := last + 1; p <= last+t.readAhead && p < t.nrPages; p++ {
+		pages = append(pages, int16(p))
+	}
+	if len(pages) > 0 {
+		go func() {
+			if err := t.prefetch(pages); err != nil {
+				t.logger.Warnf("read ahead of pages %v failed: %v", pages, err)
+			}
+		}()
+	}
+}
+
+//prefetch brings the given pages up to date and makes them readable. Copies are requested all at once,
+//and the diffs for all pages are requested with one message per writer.
+//If the host shuts down before the replies arrive, the pages are left inaccessible and an error is returned.
+func (t *TreadmarksApi) prefetch(pageNrs []int16) error {
+	pages := make([]int16, 0, len(pageNrs))
+	for _, pageNr := range pageNrs {
+		page := t.pagearray[pageNr]
+		page.fetchLock.Lock()
+		if t.memory.GetRights(int(pageNr)*t.pageByteSize) != memory.NO_ACCESS {
+			page.fetchLock.Unlock()
+			continue
+		}
+		pages = append(pages, pageNr)
+	}
+	defer func() {
+		for _, pageNr := range pages {
+			t.pagearray[pageNr].fetchLock.Unlock()
+		}
+	}()
+	if len(pages) == 0 {
+		return nil
+	}
+
+	replies := make([]chan interface{}, 0, len(pages))
+	for _, pageNr := range pages {
+		if !t.hasCopy(pageNr) {
+			if reply := t.requestCopy(pageNr); reply != nil {
+				replies = append(replies, reply)
+			}
+		}
+	}
+	if err := t.await(replies); err != nil {
+		return err
+	}
+
+	batches := make(map[uint8][]DiffRequest)
+	for _, pageNr := range pages {
+		if t.hasMissingDiffs(pageNr) {
+			for _, req := range t.createDiffRequests(pageNr) {
+				batches[req.to] = append(batches[req.to], req)
+			}
+		}
+	}
+	replies = replies[:0]
+	for to, reqs := range batches {
+		id, reply := t.newRequest()
+		req := DiffBatchRequest{
+			From:     t.myId,
+			ReqId:    id,
+			Requests: reqs,
+		}
+		t.sendMessage(to, 9, req)
+		replies = append(replies, reply)
+	}
+	if err := t.await(replies); err != nil {
+		return err
+	}
+
+	addrList := make([]int, len(pages))
+	for i, pageNr := range pages {
+		if t.hasMissingDiffs(pageNr) {
+			t.setMissingDiffs(pageNr, false)
+			t.applyAllDiffs(pageNr)
+		}
+		addrList[i] = int(pageNr) * t.pageByteSize
+	}
+	t.memory.SetRightsList(addrList, memory.READ_ONLY)
 	return nil
 }
 
+//await waits for the replies to requests, and fails if the host shuts down first.
+func (t *TreadmarksApi) await(replies []chan interface{}) error {
+	for _, reply := range replies {
+		select {
+		case <-reply:
+		case <-t.done:
+			return errors.New("host shut down before all replies arrived")
+		}
+	}
+	return nil
+}
+
+func (t *TreadmarksApi) pagesInRange(addr, length int) []int16 {
+	pages := make([]int16, 0)
+	for p := addr / t.pageByteSize; p*t.pageByteSize < addr+length && p < t.nrPages; p++ {
+		pages = append(pages, int16(p))
+	}
+	return pages
+}
+
+//initializeLocks makes the first nrLocks locks. The others are made when they are first used.
 func (t *TreadmarksApi) initializeLocks() {
-	var i uint8
-	for i = 0; i < uint8(len(t.locks)); i++ {
-		t.locks[i] = &lock{new(sync.Mutex), false, t.myId == t.getManagerId(i), t.getManagerId(i), 0, nil}
+	t.locksLock.Lock()
+	defer t.locksLock.Unlock()
+	t.locks = make(map[int]*lock)
+	for i := 0; i < t.nrLocks; i++ {
+		t.locks[i] = t.newLock(i)
+	}
+}
+
+func (t *TreadmarksApi) newLock(id int) *lock {
+	return &lock{
+		Locker:    new(sync.Mutex),
+		granted:   make(chan bool, 1),
+		haveToken: t.myId == t.getManagerId(id),
+		last:      t.getManagerId(id),
+	}
+}
+
+//getLock returns the lock with the id, and makes it if it hasn't been used at this host before.
+func (t *TreadmarksApi) getLock(id int) *lock {
+	t.locksLock.Lock()
+	defer t.locksLock.Unlock()
+	l, ok := t.locks[id]
+	if !ok {
+		l = t.newLock(id)
+		t.locks[id] = l
 	}
+	return l
 }
 
 func (t *TreadmarksApi) initializeBarriers() {
-	t.barrier = make(chan uint8, 1)
-	t.barrier <- 0
+	t.barriers = make(map[int]*barrier)
+	t.barriersLock = new(sync.Mutex)
+	t.barrierMessages = make(map[barrierRound][]BarrierMessage)
+	t.barrierArrived = sync.NewCond(t.barriersLock)
+	t.barrierBase = NewTimestamp(t.nrProcs)
+	t.barrierBases = make(map[int]Timestamp)
+	t.barrierParticipants = make(map[int][]uint8)
+}
+
+//getBarrier returns the barrier with the id at its manager. The caller holds barriersLock.
+func (t *TreadmarksApi) getBarrier(id int) *barrier {
+	b, ok := t.barriers[id]
+	if !ok {
+		if id >= dynamicIdBase {
+			panic("no barrier with id " + strconv.Itoa(id))
+		}
+		b = new(barrier)
+		t.barriers[id] = b
+	}
+	return b
 }
 
 //----------------------------------------------------------------//
 //                       Data Generation                          //
 //----------------------------------------------------------------//
 
+//newWritenoticeRecord must be called with intervalLock and dirtyPagesLock held.
 func (t *TreadmarksApi) newWritenoticeRecord(pageNr int16) {
 	ts := NewTimestamp(t.nrProcs).merge(t.timestamp)
 	wn := WritenoticeRecord{
 		Owner:     t.myId,
 		Timestamp: ts,
 	}
-	t.pagearray[pageNr].writenotices[t.myId] = append(t.pagearray[pageNr].writenotices[t.myId], wn)
+	page := t.pagearray[pageNr]
+	page.Lock()
+	page.writenotices[t.myId] = append(page.writenotices[t.myId], wn)
+	page.Unlock()
 	delete(t.dirtyPages, pageNr)
 }
 
@@ -251,15 +667,7 @@ func (t *TreadmarksApi) addWritenoticeRecord(pageNr int16, procId uint8, timesta
 	access := t.memory.GetRights(addr)
 
 	if access == memory.READ_WRITE {
-		t.dirtyPagesLock.Lock()
-		if t.dirtyPages[pageNr] {
-			t.newWritenoticeRecord(pageNr)
-		}
-		t.dirtyPagesLock.Unlock()
-		t.twinsLock.Lock()
-		t.generateDiff(pageNr, t.twins[pageNr])
-		t.twins[pageNr] = nil
-		t.twinsLock.Unlock()
+		t.closeTwin(pageNr)
 	}
 	t.memory.SetRights(addr, memory.NO_ACCESS)
 	wn := WritenoticeRecord{
@@ -267,13 +675,38 @@ func (t *TreadmarksApi) addWritenoticeRecord(pageNr int16, procId uint8, timesta
 		Timestamp: timestamp,
 	}
 	page := t.pagearray[pageNr]
-	wnl := page.writenotices[procId]
-	wnl = append(wnl, wn)
+	page.Lock()
+	page.writenotices[procId] = append(page.writenotices[procId], wn)
 	page.hasMissingDiffs = true
-	t.pagearray[pageNr].writenotices[procId] = wnl
+	page.Unlock()
 }
 
+//closeTwin diffs the page against its twin, if it has one, and drops the twin. The diff goes into the last write notice
+//of this host, which is made first if the page is still dirty. The caller holds intervalLock.
+func (t *TreadmarksApi) closeTwin(pageNr int16) {
+	t.dirtyPagesLock.Lock()
+	defer t.dirtyPagesLock.Unlock()
+	if t.dirtyPages[pageNr] {
+		t.newWritenoticeRecord(pageNr)
+	}
+	page := t.pagearray[pageNr]
+	page.Lock()
+	defer page.Unlock()
+	if page.twin != nil {
+		t.generateDiff(pageNr, page.twin)
+		page.twin = nil
+	}
+}
+
+//newInterval closes the interval of this host, if it has written any pages since the last one.
 func (t *TreadmarksApi) newInterval() {
+	t.intervalLock.Lock()
+	defer t.intervalLock.Unlock()
+	t.closeInterval()
+}
+
+//closeInterval is newInterval for callers that hold intervalLock.
+func (t *TreadmarksApi) closeInterval() {
 	t.dirtyPagesLock.Lock()
 	if len(t.dirtyPages) > 0 {
 		pages := make([]int16, 0, len(t.dirtyPages))
@@ -296,16 +729,81 @@ func (t *TreadmarksApi) newInterval() {
 }
 
 func (t *TreadmarksApi) addInterval(interval IntervalRecord) {
-	ts := NewTimestamp(t.nrProcs).merge(t.timestamp)
-	if !ts.covers(interval.Timestamp) || !interval.Timestamp.covers(ts) {
+	t.intervalLock.Lock()
+	defer t.intervalLock.Unlock()
+	t.mergeInterval(interval, false)
+}
+
+/*
+	mergeInterval adds the write notices of an interval this host hasn't seen in full. The caller holds intervalLock.
+	A partial interval came with a lock that has a scope, so its pages outside the scope were left out.
+	Only whole intervals that follow the last one of their host here are kept in the proc array, so the proc array
+	of a host is complete, and the intervals that aren't kept are sent again for the next request, see knownTimestamp.
+*/
+func (t *TreadmarksApi) mergeInterval(interval IntervalRecord, partial bool) {
+	seq := interval.Timestamp[interval.Owner]
+	known := t.knownSeq(interval.Owner)
+	if seq <= known {
+		return
+	}
+	keep := !partial && seq == known+1
+	//the notices of a whole interval after a gap would come before those of the gap, so they wait for it as well
+	if keep || partial {
 		for _, p := range interval.Pages {
-			t.addWritenoticeRecord(p, interval.Owner, interval.Timestamp)
+			if !t.hasWritenotice(p, interval.Owner, seq) {
+				t.addWritenoticeRecord(p, interval.Owner, interval.Timestamp)
+			}
 		}
+	}
+	if keep {
 		t.procarray[interval.Owner] = append(t.procarray[interval.Owner], interval)
-		t.timestamp = t.timestamp.merge(interval.Timestamp)
 	}
+	t.timestamp = t.timestamp.merge(interval.Timestamp)
+}
+
+//knownSeq returns the number of intervals of the host that are in the proc array. The caller holds intervalLock.
+func (t *TreadmarksApi) knownSeq(procId uint8) int32 {
+	intervals := t.procarray[procId]
+	if len(intervals) == 0 {
+		return 0
+	}
+	return intervals[len(intervals)-1].Timestamp[procId]
+}
+
+//knownTimestamp returns the timestamp of the intervals this host has in full. It is behind Timestamp for the hosts
+//whose intervals were left out of an acquire with a scope. Requests carry it, so those intervals are sent again.
+func (t *TreadmarksApi) knownTimestamp() Timestamp {
+	t.intervalLock.RLock()
+	defer t.intervalLock.RUnlock()
+	ts := NewTimestamp(t.nrProcs)
+	for proc := range ts {
+		ts[proc] = t.knownSeq(uint8(proc))
+	}
+	return ts
 }
 
+//mergeTimestamp merges the timestamp of another host, so the intervals it has seen, that this host hasn't, are fetched.
+func (t *TreadmarksApi) mergeTimestamp(ts Timestamp) {
+	t.intervalLock.Lock()
+	t.timestamp = t.timestamp.merge(ts)
+	t.intervalLock.Unlock()
+}
+
+//hasWritenotice returns whether the page has the write notice of the interval of the host.
+func (t *TreadmarksApi) hasWritenotice(pageNr int16, procId uint8, seq int32) bool {
+	page := t.pagearray[pageNr]
+	page.Lock()
+	defer page.Unlock()
+	wnl := page.writenotices[procId]
+	for i := len(wnl) - 1; i >= 0; i-- {
+		if wnl[i].Timestamp[procId] == seq {
+			return true
+		}
+	}
+	return false
+}
+
+//generateDiff must be called with the lock of the page entry held.
 func (t *TreadmarksApi) generateDiff(pageNr int16, twin []byte) {
 	pageSize := t.memory.GetPageSize()
 	addr := int(pageNr) * pageSize
@@ -317,12 +815,15 @@ func (t *TreadmarksApi) generateDiff(pageNr int16, twin []byte) {
 			diff[i] = data[i]
 		}
 	}
-	t.pagearray[pageNr].hasMissingDiffs = false
+	page := t.pagearray[pageNr]
+	page.hasMissingDiffs = false
+	t.recordWrites(pageNr, diff)
 
+	own := page.writenotices[t.myId]
 	if len(diff) == 0 {
-		t.pagearray[pageNr].writenotices[t.myId] = t.pagearray[pageNr].writenotices[t.myId][:len(t.pagearray[pageNr].writenotices[t.myId])-1]
+		page.writenotices[t.myId] = own[:len(own)-1]
 	} else {
-		t.pagearray[pageNr].writenotices[t.myId][len(t.pagearray[pageNr].writenotices[t.myId])-1].Diff = diff
+		own[len(own)-1].Diff = diff
 	}
 }
 
@@ -331,15 +832,24 @@ func (t *TreadmarksApi) generateDiff(pageNr int16, twin []byte) {
 //----------------------------------------------------------------//
 
 func (t *TreadmarksApi) getMissingIntervals(ts Timestamp) []IntervalRecord {
+	t.intervalLock.RLock()
+	defer t.intervalLock.RUnlock()
 	var proc uint8
 	intervals := make([]IntervalRecord, 0, int(t.nrProcs)*5)
 	for proc = 0; proc < t.nrProcs; proc++ {
-		intervals = append(intervals, t.getMissingIntervalsForProc(proc, ts)...)
+		intervals = append(intervals, t.missingIntervalsForProc(proc, ts)...)
 	}
 	return intervals
 }
 
 func (t *TreadmarksApi) getMissingIntervalsForProc(procId uint8, ts Timestamp) []IntervalRecord {
+	t.intervalLock.RLock()
+	defer t.intervalLock.RUnlock()
+	return t.missingIntervalsForProc(procId, ts)
+}
+
+//missingIntervalsForProc returns the intervals of the host not covered by ts, newest first. The caller holds intervalLock.
+func (t *TreadmarksApi) missingIntervalsForProc(procId uint8, ts Timestamp) []IntervalRecord {
 	intervals := t.procarray[procId]
 	result := make([]IntervalRecord, 0, len(intervals))
 	for i := len(intervals) - 1; i >= 0; i-- {
@@ -353,7 +863,24 @@ func (t *TreadmarksApi) getMissingIntervalsForProc(procId uint8, ts Timestamp) [
 }
 
 func (t *TreadmarksApi) hasMissingDiffs(pageNr int16) bool {
-	return t.pagearray[pageNr].hasMissingDiffs
+	page := t.pagearray[pageNr]
+	page.Lock()
+	defer page.Unlock()
+	return page.hasMissingDiffs
+}
+
+func (t *TreadmarksApi) setMissingDiffs(pageNr int16, missing bool) {
+	page := t.pagearray[pageNr]
+	page.Lock()
+	page.hasMissingDiffs = missing
+	page.Unlock()
+}
+
+func (t *TreadmarksApi) hasCopy(pageNr int16) bool {
+	page := t.pagearray[pageNr]
+	page.Lock()
+	defer page.Unlock()
+	return page.hasCopy
 }
 
 func (t *TreadmarksApi) createDiffRequests(pageNr int16) []DiffRequest {
@@ -390,7 +917,10 @@ func (t *TreadmarksApi) createDiffRequest(pageNr int16, procId uint8) DiffReques
 		PageNr: pageNr,
 	}
 
-	wnl := t.pagearray[pageNr].writenotices[procId]
+	page := t.pagearray[pageNr]
+	page.Lock()
+	defer page.Unlock()
+	wnl := page.writenotices[procId]
 
 	l := len(wnl) - 1
 	if l >= 0 && wnl[l].Diff == nil {
@@ -410,51 +940,68 @@ func (t *TreadmarksApi) createDiffRequest(pageNr int16, procId uint8) DiffReques
 //----------------------------------------------------------------//
 
 func (t *TreadmarksApi) sendMessage(to, msgType uint8, msg interface{}) {
-	//fmt.Println(t.myId, t.timestamp, "Sending ",reflect.TypeOf(msg)," : ", msg)
+	if t.logger.Enabled(network.LogDebug) {
+		t.logger.Debugf("sending message of type %v to %v at %v: %+v", msgType, to, t.Timestamp(), msg)
+	}
 	var w bytes.Buffer
 	xdr.Marshal(&w, &msg)
-	data := make([]byte, w.Len()+2)
+	body := t.codec.Encode(w.Bytes(), t.conn.Compresses(int(to)))
+	data := make([]byte, len(body)+2)
 	data[0] = byte(to)
 	data[1] = byte(msgType)
-	w.Read(data[2:])
+	copy(data[2:], body)
 	t.log(msgType)
-	t.out <- data
+	if err := t.conn.Send(data); err != nil {
+		t.logger.Warnf("message of type %v to %v was not sent: %v", msgType, to, err)
+	}
 }
 
-func (t *TreadmarksApi) sendLockAcquireRequest(to uint8, lockId uint8) {
+func (t *TreadmarksApi) sendLockAcquireRequest(to uint8, lockId int, scope []int16) {
 	req := LockAcquireRequest{
 		From:      t.myId,
-		LockId:    lockId,
-		Timestamp: t.timestamp,
+		LockId:    int32(lockId),
+		Timestamp: t.knownTimestamp(),
+		Scope:     scope,
 	}
 	t.sendMessage(to, 0, req)
 }
 
-func (t *TreadmarksApi) sendLockAcquireResponse(lockId uint8, to uint8, timestamp Timestamp) {
+func (t *TreadmarksApi) sendLockAcquireResponse(lockId int, to uint8, timestamp Timestamp, shared bool, scope []int16) {
+	t.sendMessage(to, 1, t.lockAcquireResponse(lockId, timestamp, shared, scope))
+}
 
+//lockAcquireResponse grants the lock to a host with the given timestamp. If the lock has a scope at the acquirer,
+//only the write notices for the pages in it are sent.
+func (t *TreadmarksApi) lockAcquireResponse(lockId int, timestamp Timestamp, shared bool, scope []int16) LockAcquireResponse {
 	intervals := t.getMissingIntervals(timestamp)
-	resp := LockAcquireResponse{
-		LockId:    lockId,
+	if len(scope) > 0 {
+		intervals = inScope(intervals, scope)
+	}
+	return LockAcquireResponse{
+		From:      t.myId,
+		LockId:    int32(lockId),
 		Intervals: intervals,
-		Timestamp: t.timestamp,
+		Timestamp: t.Timestamp(),
+		Shared:    shared,
 	}
-
-	t.sendMessage(to, 1, resp)
 }
 
 func (t *TreadmarksApi) forwardLockAcquireRequest(to uint8, req LockAcquireRequest) {
 	t.sendMessage(to, 0, req)
 }
 
-func (t *TreadmarksApi) sendBarrierRequest(barrierId uint8) {
+func (t *TreadmarksApi) sendBarrierRequest(barrierId int) {
 	managerId := t.getManagerId(barrierId)
+	if t.myId != managerId {
+		//The interval must be closed before the timestamp is sent, or the manager sends it back to us.
+		t.newInterval()
+	}
 	req := BarrierRequest{
 		From:      t.myId,
-		BarrierId: barrierId,
-		Timestamp: t.timestamp,
+		BarrierId: int32(barrierId),
+		Timestamp: t.knownTimestamp(),
 	}
 	if t.myId != managerId {
-		t.newInterval()
 		req.Intervals = t.getMissingIntervalsForProc(t.myId, t.getHighestTimestamp(managerId))
 		t.sendMessage(managerId, 3, req)
 	} else {
@@ -466,38 +1013,56 @@ func (t *TreadmarksApi) sendBarrierRequest(barrierId uint8) {
 func (t *TreadmarksApi) sendBarrierResponse(to uint8, ts Timestamp) {
 	resp := BarrierResponse{
 		Intervals: t.getMissingIntervals(ts),
-		Timestamp: t.timestamp,
+		Timestamp: t.Timestamp(),
 	}
 	t.sendMessage(to, 4, resp)
 }
 
+//sendCopyRequest fetches a copy of the page and waits for it to arrive.
 func (t *TreadmarksApi) sendCopyRequest(pageNr int16) {
+	if reply := t.requestCopy(pageNr); reply != nil {
+		<-reply
+	}
+}
+
+//requestCopy asks for a copy of the page. The returned channel receives when it has arrived, or is nil if no request was needed.
+func (t *TreadmarksApi) requestCopy(pageNr int16) chan interface{} {
 	page := t.pagearray[pageNr]
-	copySet := page.copySet
-	to := copySet[len(copySet)-1]
-	if to != t.myId {
-		req := CopyRequest{
-			From:   t.myId,
-			PageNr: pageNr,
-		}
-		t.sendMessage(to, 5, req)
-	} else {
+	page.Lock()
+	to := page.copySet[len(page.copySet)-1]
+	if to == t.myId {
 		page.hasCopy = true
-		t.channel <- true
 	}
+	page.Unlock()
+	if to == t.myId {
+		return nil
+	}
+	id, reply := t.newRequest()
+	req := CopyRequest{
+		From:   t.myId,
+		ReqId:  id,
+		PageNr: pageNr,
+	}
+	t.sendMessage(to, 5, req)
+	return reply
 }
 
-func (t *TreadmarksApi) sendCopyResponse(to uint8, pageNr int16) {
+func (t *TreadmarksApi) sendCopyResponse(to uint8, reqId uint32, pageNr int16) {
 	data := make([]byte, t.pageByteSize)
-	t.twinsLock.Lock()
-	copy(data, t.twins[pageNr])
-	t.twinsLock.Unlock()
-	if data == nil {
-		pageSize := t.memory.GetPageSize()
-		addr := int(pageNr) * pageSize
-		copy(data, t.memory.PrivilegedRead(addr, pageSize))
+	page := t.pagearray[pageNr]
+	page.Lock()
+	if page.twin != nil {
+		copy(data, page.twin)
+	} else {
+		copy(data, t.memory.PrivilegedRead(int(pageNr)*t.pageByteSize, t.pageByteSize))
+	}
+	page.Unlock()
+	if t.conn.Compresses(int(to)) && network.IsZero(data) {
+		//The receiver knows an empty page means a page of zeros.
+		data = nil
 	}
 	resp := CopyResponse{
+		ReqId:  reqId,
 		PageNr: pageNr,
 		Data:   data,
 	}
@@ -506,28 +1071,109 @@ func (t *TreadmarksApi) sendCopyResponse(to uint8, pageNr int16) {
 
 func (t *TreadmarksApi) sendDiffRequests(pageNr int16) {
 	diffRequests := t.createDiffRequests(pageNr)
-	for _, req := range diffRequests {
+	replies := make([]chan interface{}, len(diffRequests))
+	for i, req := range diffRequests {
+		req.ReqId, replies[i] = t.newRequest()
 		t.sendMessage(req.to, 7, req)
 	}
-	for range diffRequests {
-		<-t.channel
+	for _, reply := range replies {
+		<-reply
 	}
-	t.pagearray[pageNr].hasMissingDiffs = false
+	t.setMissingDiffs(pageNr, false)
 }
 
-func (t *TreadmarksApi) sendDiffResponse(to uint8, pageNr int16, writenotices []WritenoticeRecord) {
+func (t *TreadmarksApi) sendDiffResponse(to uint8, reqId uint32, pageNr int16, writenotices []WritenoticeRecord) {
 	resp := DiffResponse{
+		ReqId:        reqId,
 		PageNr:       pageNr,
 		Writenotices: writenotices,
 	}
 	t.sendMessage(to, 8, resp)
 }
 
-func (t *TreadmarksApi) getManagerId(id uint8) uint8 {
+//newRequest registers an outstanding request. The returned channel receives the response once the reply with the same id has been handled.
+func (t *TreadmarksApi) newRequest() (uint32, chan interface{}) {
+	reply := make(chan interface{}, 1)
+	t.pendingLock.Lock()
+	t.nextReqId++
+	id := t.nextReqId
+	t.pending[id] = reply
+	t.pendingLock.Unlock()
+	return id, reply
+}
+
+func (t *TreadmarksApi) reply(reqId uint32, resp interface{}) {
+	t.pendingLock.Lock()
+	reply, ok := t.pending[reqId]
+	delete(t.pending, reqId)
+	t.pendingLock.Unlock()
+	if !ok {
+		panic("reply to unknown request " + strconv.Itoa(int(reqId)))
+	}
+	reply <- resp
+}
+
+func (t *TreadmarksApi) sendDiffBatchResponse(to uint8, reqId uint32, responses []DiffResponse) {
+	resp := DiffBatchResponse{
+		ReqId:     reqId,
+		Responses: responses,
+	}
+	t.sendMessage(to, 10, resp)
+}
+
+func (t *TreadmarksApi) sendMallocResponse(to uint8, reqId uint32, addr int, err error) {
+	resp := MallocResponse{
+		ReqId: reqId,
+		Addr:  int32(addr),
+	}
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	t.sendMessage(to, 12, resp)
+}
+
+func (t *TreadmarksApi) sendFreeResponse(to uint8, reqId uint32, err error) {
+	resp := FreeResponse{
+		ReqId: reqId,
+	}
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	t.sendMessage(to, 14, resp)
+}
+
+//allocError turns an error message from the heap manager back into the memory error it came from.
+func allocError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	for _, err := range []error{memory.InsufficientSpaceErr, memory.InvalidSizeErr, memory.InvalidFreeErr, memory.DoubleFreeErr, memory.UnsupportedFlagErr, memory.InvalidColocationErr} {
+		if err.Error() == msg {
+			return err
+		}
+	}
+	return errors.New(msg)
+}
+
+func (t *TreadmarksApi) getHeapManagerId() uint8 {
 	return 0
 }
 
+//dynamicIdBase is the first id of the locks and barriers made by NewLock and NewBarrier.
+//Lower ids are managed by host 0, higher ones by the host in their low byte.
+const dynamicIdBase = 1 << 8
+
+//getManagerId returns the host that manages a lock or barrier.
+func (t *TreadmarksApi) getManagerId(id int) uint8 {
+	if id < dynamicIdBase {
+		return 0
+	}
+	return uint8(id)
+}
+
 func (t *TreadmarksApi) getHighestTimestamp(procId uint8) Timestamp {
+	t.intervalLock.RLock()
+	defer t.intervalLock.RUnlock()
 	if len(t.procarray[procId]) == 0 {
 		return NewTimestamp(t.nrProcs)
 	}
@@ -551,7 +1197,11 @@ Loop:
 		case <-t.shutdown:
 			break Loop
 		}
-		buf.Write(msg[2:])
+		body, err := network.Decode(msg[2:])
+		if err != nil {
+			panic(err.Error())
+		}
+		buf.Write(body)
 		switch msg[1] {
 		case 0: //lock acquire request
 			var req LockAcquireRequest
@@ -610,19 +1260,169 @@ Loop:
 				panic(err.Error())
 			}
 			t.handleDiffResponse(resp)
+		case 9: //Diff batch request
+			var req DiffBatchRequest
+			_, err := xdr.Unmarshal(buf, &req)
+			if err != nil {
+				panic(err.Error())
+			}
+			req.From = uint8(msg[0])
+			t.handleDiffBatchRequest(req)
+		case 10: //Diff batch response
+			var resp DiffBatchResponse
+			_, err := xdr.Unmarshal(buf, &resp)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.handleDiffBatchResponse(resp)
+		case 11: //Malloc request
+			var req MallocRequest
+			_, err := xdr.Unmarshal(buf, &req)
+			if err != nil {
+				panic(err.Error())
+			}
+			flags := make([]int, len(req.Flags))
+			for i := range req.Flags {
+				flags[i] = int(req.Flags[i])
+			}
+			addr, err := t.memory.Malloc(int(req.Size), memory.FlagsFromInts(flags)...)
+			t.sendMallocResponse(req.From, req.ReqId, addr, err)
+		case 12: //Malloc response
+			var resp MallocResponse
+			_, err := xdr.Unmarshal(buf, &resp)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.reply(resp.ReqId, resp)
+		case 13: //Free request
+			var req FreeRequest
+			_, err := xdr.Unmarshal(buf, &req)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.sendFreeResponse(req.From, req.ReqId, t.memory.Free(int(req.Addr)))
+		case 14: //Free response
+			var resp FreeResponse
+			_, err := xdr.Unmarshal(buf, &resp)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.reply(resp.ReqId, resp)
+		case 15: //Write stats request
+			var req WriteStatsRequest
+			_, err := xdr.Unmarshal(buf, &req)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.handleWriteStatsRequest(req)
+		case 16: //Write stats response
+			var resp WriteStatsResponse
+			_, err := xdr.Unmarshal(buf, &resp)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.reply(resp.ReqId, resp)
+		case 17: //Read lock release
+			var rel ReadLockRelease
+			_, err := xdr.Unmarshal(buf, &rel)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.handleReadLockRelease(rel)
+		case 18: //Sync request
+			var req SyncRequest
+			_, err := xdr.Unmarshal(buf, &req)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.handleSyncRequest(req)
+		case 19: //Sync response
+			var resp SyncResponse
+			_, err := xdr.Unmarshal(buf, &resp)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.reply(resp.ReqId, resp)
+		case 20: //Atomic request
+			var req AtomicRequest
+			_, err := xdr.Unmarshal(buf, &req)
+			if err != nil {
+				panic(err.Error())
+			}
+			//the operation may fault, and the replies to that are handled here
+			go t.handleAtomicRequest(req)
+		case 21: //Atomic response
+			var resp AtomicResponse
+			_, err := xdr.Unmarshal(buf, &resp)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.reply(resp.ReqId, resp)
+		case 22: //Barrier message
+			var msg BarrierMessage
+			_, err := xdr.Unmarshal(buf, &msg)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.handleBarrierMessage(msg)
+		case 23: //Release notice
+			var msg ReleaseNotice
+			_, err := xdr.Unmarshal(buf, &msg)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.handleReleaseNotice(msg)
+		case 24: //Release ack
+			var ack ReleaseAck
+			_, err := xdr.Unmarshal(buf, &ack)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.reply(ack.ReqId, ack)
+		case 25: //Interval request
+			var req IntervalRequest
+			_, err := xdr.Unmarshal(buf, &req)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.handleIntervalRequest(req)
+		case 26: //Interval response
+			var resp IntervalResponse
+			_, err := xdr.Unmarshal(buf, &resp)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.reply(resp.ReqId, resp)
+		case 27: //Participants request
+			var req ParticipantsRequest
+			_, err := xdr.Unmarshal(buf, &req)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.handleParticipantsRequest(req)
+		case 28: //Participants response
+			var resp ParticipantsResponse
+			_, err := xdr.Unmarshal(buf, &resp)
+			if err != nil {
+				panic(err.Error())
+			}
+			t.reply(resp.ReqId, resp)
 		}
 	}
 	t.group.Done()
 }
 
 func (t *TreadmarksApi) handleLockAcquireRequest(req LockAcquireRequest) {
-	id := req.LockId
-	lock := t.locks[id]
+	id := int(req.LockId)
+	lock := t.getLock(id)
 	lock.Lock()
-	if lock.locked {
+	if req.Shared {
+		t.handleReadLockRequest(req, lock)
+	} else if lock.locked || lock.readers > 0 {
 		if lock.nextTimestamp == nil {
 			lock.nextTimestamp = req.Timestamp
 			lock.nextId = req.From
+			lock.nextScope = req.Scope
 		} else {
 			t.forwardLockAcquireRequest(lock.last, req)
 		}
@@ -631,13 +1431,14 @@ func (t *TreadmarksApi) handleLockAcquireRequest(req LockAcquireRequest) {
 		if lock.haveToken {
 			lock.haveToken = false
 			t.newInterval()
-			t.sendLockAcquireResponse(id, req.From, req.Timestamp)
+			t.sendLockAcquireResponse(id, req.From, req.Timestamp, false, req.Scope)
 			lock.last = req.From
 		} else {
 			if lock.last == t.myId {
 				if lock.nextTimestamp == nil {
 					lock.nextTimestamp = req.Timestamp
 					lock.nextId = req.From
+					lock.nextScope = req.Scope
 				} else {
 					t.forwardLockAcquireRequest(lock.last, req)
 				}
@@ -651,38 +1452,59 @@ func (t *TreadmarksApi) handleLockAcquireRequest(req LockAcquireRequest) {
 }
 
 func (t *TreadmarksApi) handleLockAcquireResponse(resp LockAcquireResponse) {
-	id := resp.LockId
-	lock := t.locks[id]
+	id := int(resp.LockId)
+	lock := t.getLock(id)
 	lock.Lock()
 	t.newInterval()
-	for i := len(resp.Intervals); i > 0; i-- {
-		t.addInterval(resp.Intervals[i-1])
+	t.addGrantedIntervals(resp, len(lock.scope) > 0)
+	if resp.Shared {
+		lock.reading = true
+		lock.grantedBy = resp.From
+	} else if lock.withdrawn {
+		//nobody waits for the lock here any more, so it is released at once
+		lock.withdrawn = false
+		lock.haveToken = true
+		t.grantQueuedReaders(id, lock)
+		if lock.readers == 0 && lock.nextTimestamp != nil {
+			t.passToken(id, lock)
+		}
+		lock.Unlock()
+		return
+	} else {
+		lock.locked = true
+		lock.haveToken = true
 	}
-	lock.locked = true
-	lock.haveToken = true
 	lock.Unlock()
-	t.channel <- true
+	lock.granted <- true
 }
 
 func (t *TreadmarksApi) handleBarrierRequest(req BarrierRequest) {
-	t.barrierreq[req.From] = req
-	n := <-t.barrier + 1
-	if n < t.nrProcs {
-		t.barrier <- n
-	} else {
-		t.newInterval()
-		for _, req := range t.barrierreq {
-			for i := len(req.Intervals); i > 0; i-- {
-				t.addInterval(req.Intervals[i-1])
-			}
+	t.logger.Debugf("host %v arrived at barrier %v", req.From, req.BarrierId)
+	t.barriersLock.Lock()
+	defer t.barriersLock.Unlock()
+	b := t.getBarrier(int(req.BarrierId))
+	b.requests = append(b.requests, req)
+	if len(b.requests) < b.size(t.nrProcs) {
+		return
+	}
+	requests := b.requests
+	b.requests = nil
+	t.newInterval()
+	for _, req := range requests {
+		for i := len(req.Intervals); i > 0; i-- {
+			t.addInterval(req.Intervals[i-1])
 		}
-		var i uint8
-		for i = 0; i < t.nrProcs; i++ {
-			if i != t.myId {
-				t.sendBarrierResponse(i, t.barrierreq[i].Timestamp)
-			}
+	}
+	arrived := false
+	for _, req := range requests {
+		if req.From == t.myId {
+			arrived = true
+		} else {
+			t.sendBarrierResponse(req.From, req.Timestamp)
 		}
-		t.barrier <- 0
+	}
+	//the manager of a barrier made by NewBarrier need not take part in it
+	if arrived {
 		t.channel <- true
 	}
 }
@@ -691,42 +1513,60 @@ func (t *TreadmarksApi) handleBarrierResponse(resp BarrierResponse) {
 	for i := len(resp.Intervals); i > 0; i-- {
 		t.addInterval(resp.Intervals[i-1])
 	}
+	t.mergeTimestamp(resp.Timestamp)
 	t.channel <- true
 }
 
 func (t *TreadmarksApi) handleCopyRequest(req CopyRequest) {
-	t.sendCopyResponse(req.From, req.PageNr)
-	copyset := t.pagearray[req.PageNr].copySet
-	copyset = append(copyset, req.From)
+	t.sendCopyResponse(req.From, req.ReqId, req.PageNr)
 }
 
 func (t *TreadmarksApi) handleCopyResponse(resp CopyResponse) {
+	if len(resp.Data) == 0 {
+		resp.Data = make([]byte, t.pageByteSize)
+	}
 	t.memory.PrivilegedWrite(int(resp.PageNr)*t.memory.GetPageSize(), resp.Data)
 	page := t.pagearray[resp.PageNr]
+	page.Lock()
 	page.hasCopy = true
 	page.copySet = append(page.copySet, t.myId)
-	t.channel <- true
+	page.Unlock()
+	t.reply(resp.ReqId, resp)
 }
 
 func (t *TreadmarksApi) handleDiffRequest(req DiffRequest) {
-	t.twinsLock.Lock()
-	if t.twins[req.PageNr] != nil {
-		t.dirtyPagesLock.Lock()
-		if t.dirtyPages[req.PageNr] {
-			t.newWritenoticeRecord(req.PageNr)
+	result := t.collectDiffs(req)
+	t.sendDiffResponse(req.From, req.ReqId, req.PageNr, result)
+}
+
+func (t *TreadmarksApi) handleDiffBatchRequest(req DiffBatchRequest) {
+	responses := make([]DiffResponse, len(req.Requests))
+	for i, r := range req.Requests {
+		r.From = req.From
+		responses[i] = DiffResponse{
+			PageNr:       r.PageNr,
+			Writenotices: t.collectDiffs(r),
 		}
-		t.dirtyPagesLock.Unlock()
-		t.generateDiff(req.PageNr, t.twins[req.PageNr])
-		t.twins[req.PageNr] = nil
 	}
+	t.sendDiffBatchResponse(req.From, req.ReqId, responses)
+}
+
+//collectDiffs returns the write notices, with diffs, that the request asks for.
+func (t *TreadmarksApi) collectDiffs(req DiffRequest) []WritenoticeRecord {
+	t.intervalLock.RLock()
+	t.closeTwin(req.PageNr)
+	t.intervalLock.RUnlock()
 
+	page := t.pagearray[req.PageNr]
+	page.Lock()
+	defer page.Unlock()
 	result := make([]WritenoticeRecord, 0)
 	var proc uint8
 	for proc = 0; proc < t.nrProcs; proc++ {
 		if proc == req.From {
 			continue
 		}
-		list := t.pagearray[req.PageNr].writenotices[proc]
+		list := page.writenotices[proc]
 		for i := len(list) - 1; i >= 0; i-- {
 			wn := list[i]
 			if !wn.Timestamp.covers(req.First) {
@@ -736,14 +1576,29 @@ func (t *TreadmarksApi) handleDiffRequest(req DiffRequest) {
 			}
 		}
 	}
-	t.sendDiffResponse(req.From, req.PageNr, result)
-	t.twinsLock.Unlock()
+	return result
 }
 
 func (t *TreadmarksApi) handleDiffResponse(resp DiffResponse) {
+	t.addDiffs(resp)
+	t.reply(resp.ReqId, resp)
+}
+
+func (t *TreadmarksApi) handleDiffBatchResponse(resp DiffBatchResponse) {
+	for _, r := range resp.Responses {
+		t.addDiffs(r)
+	}
+	t.reply(resp.ReqId, resp)
+}
+
+//addDiffs stores the received diffs in the matching write notices.
+func (t *TreadmarksApi) addDiffs(resp DiffResponse) {
 	var proc uint8
 	wnl := resp.Writenotices
 	j := 0
+	page := t.pagearray[resp.PageNr]
+	page.Lock()
+	defer page.Unlock()
 	for proc = 0; proc < t.nrProcs; proc++ {
 		if proc == t.myId {
 			continue
@@ -751,7 +1606,7 @@ func (t *TreadmarksApi) handleDiffResponse(resp DiffResponse) {
 		if j >= len(wnl) {
 			break
 		}
-		list := t.pagearray[resp.PageNr].writenotices[proc]
+		list := page.writenotices[proc]
 		for i := len(list) - 1; i >= 0; i-- {
 
 			if !(j < len(wnl)) {
@@ -766,7 +1621,6 @@ func (t *TreadmarksApi) handleDiffResponse(resp DiffResponse) {
 
 		}
 	}
-	t.channel <- true
 }
 
 func (t *TreadmarksApi) applyAllDiffs(pageNr int16) {
@@ -774,8 +1628,11 @@ func (t *TreadmarksApi) applyAllDiffs(pageNr int16) {
 	x := 0
 	t.diffLock.Lock()
 	defer t.diffLock.Unlock()
-	wnl := t.pagearray[pageNr].writenotices
-	index := t.pagearray[pageNr].index
+	page := t.pagearray[pageNr]
+	page.Lock()
+	defer page.Unlock()
+	wnl := page.writenotices
+	index := page.index
 	for {
 		var best uint8 = 0
 		var bestTs Timestamp = nil
@@ -798,7 +1655,7 @@ func (t *TreadmarksApi) applyAllDiffs(pageNr int16) {
 		index[best] = index[best] + 1
 	}
 
-	t.pagearray[pageNr].index = index
+	page.index = index
 }
 
 func (t *TreadmarksApi) applyDiff(pageNr int16, diff map[int]byte) {
@@ -814,11 +1671,12 @@ func (t *TreadmarksApi) applyDiff(pageNr int16, diff map[int]byte) {
 }
 
 func (t *TreadmarksApi) addToLockQueue(req LockAcquireRequest) {
-	lockId := req.LockId
-	lock := t.locks[lockId]
+	lockId := int(req.LockId)
+	lock := t.getLock(lockId)
 	if lock.nextTimestamp == nil && (t.myId == lock.last || t.myId != t.getManagerId(lockId)) {
 		lock.nextTimestamp = req.Timestamp
 		lock.nextId = req.From
+		lock.nextScope = req.Scope
 	} else {
 		t.forwardLockAcquireRequest(lock.last, req)
 	}
@@ -832,6 +1690,33 @@ func (t *TreadmarksApi) GetId() int {
 	return int(t.myId)
 }
 
+//Timestamp returns a copy of the vector timestamp of this host.
+func (t *TreadmarksApi) Timestamp() Timestamp {
+	t.intervalLock.RLock()
+	defer t.intervalLock.RUnlock()
+	return NewTimestamp(t.nrProcs).merge(t.timestamp)
+}
+
+//HasCopy returns whether this host has a copy of the page.
+func (t *TreadmarksApi) HasCopy(pageNr int) bool {
+	return t.hasCopy(int16(pageNr))
+}
+
+//Intervals returns the intervals made by the given host that this host knows of, oldest first.
+func (t *TreadmarksApi) Intervals(procId int) []IntervalRecord {
+	t.intervalLock.RLock()
+	defer t.intervalLock.RUnlock()
+	return append([]IntervalRecord(nil), t.procarray[procId]...)
+}
+
+//Writenotices returns the write notices for the page made by the given host that this host knows of, oldest first.
+func (t *TreadmarksApi) Writenotices(procId, pageNr int) []WritenoticeRecord {
+	page := t.pagearray[pageNr]
+	page.Lock()
+	defer page.Unlock()
+	return append([]WritenoticeRecord(nil), page.writenotices[procId]...)
+}
+
 func getGID() uint64 {
 	b := make([]byte, 64)
 	b = b[:runtime.Stack(b, false)]
@@ -841,10 +1726,107 @@ func getGID() uint64 {
 	return n
 }
 
+//SetBatching sets how messages to the same host are coalesced by the connection. Call it after Initialize.
+func (t *TreadmarksApi) SetBatching(maxBatchBytes int, flushDelay time.Duration) {
+	t.conn.SetBatching(maxBatchBytes, flushDelay)
+}
+
+//SetCompression sets whether page copies and diffs are compressed on the wire. Call it after Initialize and before Join.
+func (t *TreadmarksApi) SetCompression(b bool) {
+	t.conn.SetCompression(b)
+}
+
+//BytesSent returns the number of bytes of the messages sent, before and after compression.
+func (t *TreadmarksApi) BytesSent() (raw, wire int) {
+	return t.codec.Stats()
+}
+
+//FramesSent returns the number of frames the connection has written to other hosts, and their size in bytes.
+//Unlike MessagesSent, these show how well messages are batched.
+func (t *TreadmarksApi) FramesSent() (frames, bytes int) {
+	_, frames, bytes = t.conn.Stats()
+	return
+}
+
+//SharedAllocation is an allocation on a page that more than one host writes to.
+type SharedAllocation struct {
+	Addr, Size int
+	PageNr     int
+	Writers    []uint8
+}
+
+//SharingReport lists the allocations on pages written by more than one host, which are likely to suffer from false sharing.
+//Only the heap manager knows all allocations, and it only learns about other hosts' writes when synchronising with them.
+func (t *TreadmarksApi) SharingReport() []SharedAllocation {
+	allocations := t.memory.Allocations()
+	result := make([]SharedAllocation, 0)
+	t.dirtyPagesLock.RLock()
+	defer t.dirtyPagesLock.RUnlock()
+	for pageNr, page := range t.pagearray {
+		writers := make([]uint8, 0)
+		page.Lock()
+		for proc, wnl := range page.writenotices {
+			if len(wnl) > 0 || (uint8(proc) == t.myId && t.dirtyPages[int16(pageNr)]) {
+				writers = append(writers, uint8(proc))
+			}
+		}
+		page.Unlock()
+		if len(writers) < 2 {
+			continue
+		}
+		start, end := pageNr*t.pageByteSize, (pageNr+1)*t.pageByteSize-1
+		for _, a := range allocations {
+			if a.Start <= end && a.End >= start {
+				result = append(result, SharedAllocation{a.Start, a.End - a.Start + 1, pageNr, writers})
+			}
+		}
+	}
+	return result
+}
+
+//PrintSharingReport logs the SharingReport at info level.
+func (t *TreadmarksApi) PrintSharingReport() {
+	report := t.SharingReport()
+	t.logger.Infof("allocations on pages written by more than one host: %v", len(report))
+	for _, s := range report {
+		t.logger.Infof("address %v size %v page %v written by hosts %v", s.Addr, s.Size, s.PageNr, s.Writers)
+	}
+}
+
+//SetReadAhead sets how many pages are prefetched after a fault that continues a sequential walk through memory. Zero turns read-ahead off.
+func (t *TreadmarksApi) SetReadAhead(pages int) {
+	t.readAhead = pages
+}
+
+//SetBarrierAlgorithm sets how hosts meet at barriers. All hosts must use the same algorithm.
+//For a barrier made by NewBarrier, the tree or dissemination is laid out over the hosts that take part in it.
+func (t *TreadmarksApi) SetBarrierAlgorithm(a BarrierAlgorithm) {
+	t.barrierAlgorithm = a
+}
+
+//MessagesSent returns the total number of protocol messages this host has sent.
+func (t *TreadmarksApi) MessagesSent() int {
+	n := 0
+	t.messageLogLock.Lock()
+	defer t.messageLogLock.Unlock()
+	for _, count := range t.messageLog {
+		n += count
+	}
+	return n
+}
+
+//SetLogger sets the logger of the host and its connection. Call it before Initialize.
+func (t *TreadmarksApi) SetLogger(l *network.Logger) {
+	t.logger = l.For("treadmarks")
+	t.logger.SetHost(int(t.myId))
+}
+
 func (t *TreadmarksApi) SetLogging(b bool) {
 	t.shouldLogMessages = b
 }
 
 func (t *TreadmarksApi) log(msgId uint8) {
+	t.messageLogLock.Lock()
 	t.messageLog[msgId]++
+	t.messageLogLock.Unlock()
 }
@@ -0,0 +1,102 @@
+package treadmarks
+
+//AcquireReadLock acquires the lock in shared mode. Any number of hosts can hold a lock in shared mode at once,
+//as long as no host holds it with AcquireLock.
+//The host with the token grants shared access without giving the token away, and sends the intervals the reader
+//is missing, just as for AcquireLock. So concurrent readers see all writes made under the lock before,
+//but don't bounce the token between them. Writes made while holding a lock in shared mode are not allowed.
+func (t *TreadmarksApi) AcquireReadLock(id int) {
+	lock := t.getLock(id)
+	lock.Lock()
+	if lock.locked || lock.reading {
+		panic("locking lock twice")
+	}
+	if lock.haveToken && lock.nextTimestamp == nil && !lock.waiting {
+		lock.readers++
+		lock.reading = true
+		lock.grantedBy = t.myId
+		lock.Unlock()
+		return
+	}
+	req := LockAcquireRequest{
+		From:      t.myId,
+		LockId:    int32(id),
+		Timestamp: t.knownTimestamp(),
+		Shared:    true,
+		Scope:     scopePages(lock.scope),
+	}
+	//The token doesn't move to readers, so unlike AcquireLock this host doesn't become the last in the queue.
+	t.sendMessage(lock.last, 0, req)
+	lock.Unlock()
+	<-lock.granted
+	t.completeAcquire(lock)
+}
+
+func (t *TreadmarksApi) ReleaseReadLock(id int) {
+	lock := t.getLock(id)
+	lock.Lock()
+	defer lock.Unlock()
+	lock.reading = false
+	if lock.grantedBy == t.myId {
+		t.releaseReader(id, lock)
+		return
+	}
+	rel := ReadLockRelease{
+		From:   t.myId,
+		LockId: int32(id),
+	}
+	t.sendMessage(lock.grantedBy, 17, rel)
+}
+
+//handleReadLockRequest grants a shared request if this host has the token and nobody waits for it,
+//queues it if this host is the next to get the token, and forwards it towards the token otherwise.
+//The caller holds the lock.
+func (t *TreadmarksApi) handleReadLockRequest(req LockAcquireRequest, lock *lock) {
+	switch {
+	case lock.haveToken && !lock.locked && lock.nextTimestamp == nil && !lock.waiting:
+		lock.readers++
+		t.newInterval()
+		t.sendLockAcquireResponse(int(req.LockId), req.From, req.Timestamp, true, req.Scope)
+	case lock.last == t.myId:
+		lock.readQueue = append(lock.readQueue, req)
+	default:
+		t.forwardLockAcquireRequest(lock.last, req)
+	}
+}
+
+func (t *TreadmarksApi) handleReadLockRelease(rel ReadLockRelease) {
+	lock := t.getLock(int(rel.LockId))
+	lock.Lock()
+	t.releaseReader(int(rel.LockId), lock)
+	lock.Unlock()
+}
+
+//releaseReader counts a reader out. When the last reader has left, the lock goes to whoever waits for it.
+//The caller holds the lock.
+func (t *TreadmarksApi) releaseReader(id int, lock *lock) {
+	lock.readers--
+	if lock.readers > 0 {
+		return
+	}
+	if lock.waiting {
+		lock.waiting = false
+		lock.locked = true
+		lock.granted <- true
+	} else if lock.nextTimestamp != nil {
+		t.passToken(id, lock)
+	}
+}
+
+//grantQueuedReaders grants the shared requests that waited for this host to release the lock.
+//The caller holds the lock.
+func (t *TreadmarksApi) grantQueuedReaders(id int, lock *lock) {
+	if len(lock.readQueue) == 0 {
+		return
+	}
+	t.newInterval()
+	for _, req := range lock.readQueue {
+		lock.readers++
+		t.sendLockAcquireResponse(id, req.From, req.Timestamp, true, req.Scope)
+	}
+	lock.readQueue = nil
+}
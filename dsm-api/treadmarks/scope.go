@@ -0,0 +1,121 @@
+package treadmarks
+
+//BindLock puts the pages of the address range in the scope of the lock, for scope consistency.
+//The scope is sent with the requests for the lock, and the granter only sends the write notices for the pages in it.
+//The intervals left out are fetched from the hosts that made them at the next barrier or acquire of a lock without
+//a scope. So after the acquire only the data in the scope is guaranteed to be up to date. Locks that are never bound
+//keep lazy release consistency. The scope is the one of the acquirer, so every host binds the locks it acquires.
+func (t *TreadmarksApi) BindLock(id int, addr, size int) {
+	lock := t.getLock(id)
+	lock.Lock()
+	defer lock.Unlock()
+	if lock.scope == nil {
+		lock.scope = make(map[int16]bool)
+	}
+	for p := addr / t.pageByteSize; p <= (addr+size-1)/t.pageByteSize; p++ {
+		lock.scope[int16(p)] = true
+	}
+}
+
+//scopePages returns the pages of a scope, or nil if there is none.
+func scopePages(scope map[int16]bool) []int16 {
+	if len(scope) == 0 {
+		return nil
+	}
+	pages := make([]int16, 0, len(scope))
+	for p := range scope {
+		pages = append(pages, p)
+	}
+	return pages
+}
+
+//inScope returns the intervals with only the pages in the scope. Intervals with no page in it are left out.
+//The intervals themselves are not changed, as they are in the proc array.
+func inScope(intervals []IntervalRecord, scope []int16) []IntervalRecord {
+	in := make(map[int16]bool, len(scope))
+	for _, p := range scope {
+		in[p] = true
+	}
+	result := make([]IntervalRecord, 0, len(intervals))
+	for _, interval := range intervals {
+		pages := make([]int16, 0, len(interval.Pages))
+		for _, p := range interval.Pages {
+			if in[p] {
+				pages = append(pages, p)
+			}
+		}
+		if len(pages) > 0 {
+			result = append(result, IntervalRecord{
+				Owner:     interval.Owner,
+				Timestamp: interval.Timestamp,
+				Pages:     pages,
+			})
+		}
+	}
+	return result
+}
+
+//addGrantedIntervals adds the intervals that came with a lock. If the lock has a scope, they are partial.
+//The timestamp of the granter is merged as well, so the intervals it left out are fetched later.
+func (t *TreadmarksApi) addGrantedIntervals(resp LockAcquireResponse, partial bool) {
+	t.intervalLock.Lock()
+	defer t.intervalLock.Unlock()
+	for i := len(resp.Intervals); i > 0; i-- {
+		t.mergeInterval(resp.Intervals[i-1], partial)
+	}
+	t.timestamp = t.timestamp.merge(resp.Timestamp)
+}
+
+//completeAcquire fetches the intervals held back by acquires with a scope, once a lock without one has been taken.
+func (t *TreadmarksApi) completeAcquire(lock *lock) {
+	lock.Lock()
+	scoped := len(lock.scope) > 0
+	lock.Unlock()
+	if !scoped {
+		t.fetchHeldBack()
+	}
+}
+
+//fetchHeldBack asks the hosts whose intervals this host knows of, but doesn't have in full, for them.
+//Those intervals may know of others in turn, so it goes on until the proc array is complete.
+func (t *TreadmarksApi) fetchHeldBack() {
+	for {
+		procs := t.heldBack()
+		if len(procs) == 0 {
+			return
+		}
+		replies := make([]chan interface{}, len(procs))
+		for i, proc := range procs {
+			req := IntervalRequest{
+				From:      t.myId,
+				Timestamp: t.knownTimestamp(),
+			}
+			req.ReqId, replies[i] = t.newRequest()
+			t.sendMessage(proc, 25, req)
+		}
+		for _, reply := range replies {
+			t.addMissingIntervals((<-reply).(IntervalResponse).Intervals)
+		}
+	}
+}
+
+//heldBack returns the hosts with intervals this host knows of, but doesn't have in full.
+func (t *TreadmarksApi) heldBack() []uint8 {
+	t.intervalLock.RLock()
+	defer t.intervalLock.RUnlock()
+	var procs []uint8
+	for proc := uint8(0); proc < t.nrProcs; proc++ {
+		if proc != t.myId && t.timestamp[proc] > t.knownSeq(proc) {
+			procs = append(procs, proc)
+		}
+	}
+	return procs
+}
+
+func (t *TreadmarksApi) handleIntervalRequest(req IntervalRequest) {
+	resp := IntervalResponse{
+		ReqId:     req.ReqId,
+		Intervals: t.getMissingIntervalsForProc(t.myId, req.Timestamp),
+	}
+	t.sendMessage(req.From, 26, resp)
+}
@@ -0,0 +1,189 @@
+package treadmarks
+
+import (
+	"DSM-project/memory"
+	"sort"
+)
+
+//WriteRange is a range of bytes that one host wrote.
+type WriteRange struct {
+	Host       uint8
+	Start, End int
+	Allocation int //address of the allocation holding the range, or -1
+}
+
+//FalseSharing is a page that several hosts wrote to, without any of them writing the same bytes.
+type FalseSharing struct {
+	PageNr int
+	Ranges []WriteRange
+}
+
+//SetSharingAnalysis turns recording of the bytes this host writes on or off. It should be on at every host for FalseSharingReport.
+func (t *TreadmarksApi) SetSharingAnalysis(b bool) {
+	t.writesLock.Lock()
+	t.analyseSharing = b
+	t.writesLock.Unlock()
+}
+
+//FalseSharingReport collects the recorded writes from all hosts and lists the falsely shared pages.
+//Call it at the heap manager, which knows the allocations, while the other hosts are running.
+func (t *TreadmarksApi) FalseSharingReport() []FalseSharing {
+	writes := make([][]PageWrites, t.nrProcs)
+	replies := make(map[uint8]chan interface{})
+	var proc uint8
+	for proc = 0; proc < t.nrProcs; proc++ {
+		if proc == t.myId {
+			continue
+		}
+		id, reply := t.newRequest()
+		req := WriteStatsRequest{
+			From:  t.myId,
+			ReqId: id,
+		}
+		t.sendMessage(proc, 15, req)
+		replies[proc] = reply
+	}
+	writes[t.myId] = t.ownWrites()
+	for proc, reply := range replies {
+		writes[proc] = (<-reply).(WriteStatsResponse).Pages
+	}
+
+	byPage := make(map[int16]map[uint8][]int32)
+	for proc, pages := range writes {
+		for _, p := range pages {
+			if byPage[p.PageNr] == nil {
+				byPage[p.PageNr] = make(map[uint8][]int32)
+			}
+			byPage[p.PageNr][uint8(proc)] = p.Ranges
+		}
+	}
+	allocations := t.memory.Allocations()
+	result := make([]FalseSharing, 0)
+	for pageNr, hosts := range byPage {
+		if len(hosts) < 2 || overlaps(hosts, t.pageByteSize) {
+			continue
+		}
+		fs := FalseSharing{PageNr: int(pageNr)}
+		base := int(pageNr) * t.pageByteSize
+		for host, ranges := range hosts {
+			for i := 0; i+1 < len(ranges); i += 2 {
+				fs.Ranges = append(fs.Ranges, splitByAllocation(host, base+int(ranges[i]), base+int(ranges[i+1]), allocations)...)
+			}
+		}
+		sort.Slice(fs.Ranges, func(i, j int) bool { return fs.Ranges[i].Start < fs.Ranges[j].Start })
+		result = append(result, fs)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PageNr < result[j].PageNr })
+	return result
+}
+
+//PrintFalseSharingReport logs the FalseSharingReport at info level.
+func (t *TreadmarksApi) PrintFalseSharingReport() {
+	report := t.FalseSharingReport()
+	t.logger.Infof("falsely shared pages: %v", len(report))
+	for _, fs := range report {
+		for _, r := range fs.Ranges {
+			t.logger.Infof("page %v: host %v wrote %v-%v in allocation %v", fs.PageNr, r.Host, r.Start, r.End, r.Allocation)
+		}
+	}
+}
+
+//recordWrites adds the offsets of a diff of one of this host's pages to the recorded writes.
+func (t *TreadmarksApi) recordWrites(pageNr int16, diff map[int]byte) {
+	t.writesLock.Lock()
+	defer t.writesLock.Unlock()
+	if !t.analyseSharing {
+		return
+	}
+	if t.writes[pageNr] == nil {
+		t.writes[pageNr] = make([]bool, t.pageByteSize)
+	}
+	for offset := range diff {
+		t.writes[pageNr][offset] = true
+	}
+}
+
+//ownWrites returns the byte ranges this host has written on each page, including writes that have not been diffed yet.
+func (t *TreadmarksApi) ownWrites() []PageWrites {
+	for pageNr, page := range t.pagearray {
+		page.Lock()
+		if page.twin == nil {
+			page.Unlock()
+			continue
+		}
+		data := t.memory.PrivilegedRead(pageNr*t.pageByteSize, t.pageByteSize)
+		diff := make(map[int]byte)
+		for i := range data {
+			if data[i] != page.twin[i] {
+				diff[i] = data[i]
+			}
+		}
+		page.Unlock()
+		t.recordWrites(int16(pageNr), diff)
+	}
+
+	t.writesLock.Lock()
+	defer t.writesLock.Unlock()
+	result := make([]PageWrites, 0, len(t.writes))
+	for pageNr, written := range t.writes {
+		pw := PageWrites{PageNr: pageNr}
+		for i := 0; i < len(written); i++ {
+			if !written[i] {
+				continue
+			}
+			start := i
+			for i+1 < len(written) && written[i+1] {
+				i++
+			}
+			pw.Ranges = append(pw.Ranges, int32(start), int32(i))
+		}
+		if len(pw.Ranges) > 0 {
+			result = append(result, pw)
+		}
+	}
+	return result
+}
+
+func (t *TreadmarksApi) handleWriteStatsRequest(req WriteStatsRequest) {
+	resp := WriteStatsResponse{
+		ReqId: req.ReqId,
+		Pages: t.ownWrites(),
+	}
+	t.sendMessage(req.From, 16, resp)
+}
+
+//overlaps tells if any byte of the page was written by more than one host.
+func overlaps(hosts map[uint8][]int32, pageSize int) bool {
+	writers := make([]int, pageSize)
+	for _, ranges := range hosts {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			for j := ranges[i]; j <= ranges[i+1]; j++ {
+				writers[j]++
+				if writers[j] > 1 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+//splitByAllocation splits a written range at the borders of the allocations it covers.
+func splitByAllocation(host uint8, start, end int, allocations []memory.AddrPair) []WriteRange {
+	result := make([]WriteRange, 0, 1)
+	for _, a := range allocations {
+		if a.End < start || a.Start > end {
+			continue
+		}
+		if a.Start > start {
+			result = append(result, WriteRange{host, start, a.Start - 1, -1})
+			start = a.Start
+		}
+		if a.End >= end {
+			return append(result, WriteRange{host, start, end, a.Start})
+		}
+		result = append(result, WriteRange{host, start, a.End, a.Start})
+		start = a.End + 1
+	}
+	return append(result, WriteRange{host, start, end, -1})
+}
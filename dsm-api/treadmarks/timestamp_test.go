@@ -40,3 +40,49 @@ func TestTimestamp_loadsave(t *testing.T) {
 	assert.Equal(t, ts1, ts2)
 
 }
+
+func TestTimestamp_incrementCopies(t *testing.T) {
+	ts1 := NewTimestamp(4)
+	ts2 := ts1.increment(1)
+	assert.Equal(t, Timestamp{0, 1, 0, 0}, ts2)
+	assert.Equal(t, Timestamp{0, 0, 0, 0}, ts1)
+}
+
+func TestTimestamp_merge(t *testing.T) {
+	ts1 := Timestamp{4, 0, 0, 0}
+	ts2 := Timestamp{0, 5, 0, 0}
+	ts3 := Timestamp{0, 0, 4, 1}
+	assert.Equal(t, Timestamp{4, 0, 0, 0}, ts1.merge(NewTimestamp(4)))
+	assert.Equal(t, Timestamp{4, 5, 0, 0}, ts1.merge(ts2))
+	assert.Equal(t, Timestamp{0, 5, 4, 1}, ts2.merge(ts3))
+	assert.Equal(t, Timestamp{0, 5, 4, 1}, ts3.merge(ts2))
+	assert.Equal(t, Timestamp{4, 0, 0, 0}, ts1)
+	assert.Panics(t, func() { ts1.merge(Timestamp{0, 0}) })
+}
+
+func TestTimestamp_min(t *testing.T) {
+	ts1 := Timestamp{4, 1, 0}
+	ts2 := Timestamp{2, 5, 0}
+	assert.Equal(t, Timestamp{2, 1, 0}, ts1.min(ts2))
+	assert.Equal(t, Timestamp{2, 1, 0}, ts2.min(ts1))
+}
+
+func TestTimestamp_equals(t *testing.T) {
+	ts1 := Timestamp{0, 0, 0}
+	ts2 := Timestamp{0, 0, 0}
+	ts3 := Timestamp{0, 4, 0}
+	assert.True(t, ts1.equals(ts2))
+	assert.False(t, ts1.equals(ts3))
+	assert.False(t, ts3.equals(ts1))
+	assert.Panics(t, func() { ts1.equals(Timestamp{0}) })
+}
+
+func TestTimestamp_concurrent(t *testing.T) {
+	ts1 := Timestamp{0, 4, 0}
+	ts2 := Timestamp{0, 0, 4}
+	//neither timestamp happened before the other
+	assert.False(t, ts1.covers(ts2))
+	assert.False(t, ts2.covers(ts1))
+	assert.True(t, ts1.merge(ts2).covers(ts1))
+	assert.True(t, ts1.merge(ts2).covers(ts2))
+}
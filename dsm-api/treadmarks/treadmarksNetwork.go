@@ -2,19 +2,28 @@ package treadmarks
 
 type LockAcquireRequest struct {
 	From      uint8 `xdropaque:"false"`
-	LockId    uint8 `xdropaque:"false"`
+	LockId    int32
 	Timestamp Timestamp
+	Shared    bool
+	Scope     []int16 //the pages bound to the lock at the sender, so the granter only sends write notices for them
 }
 
 type LockAcquireResponse struct {
-	LockId    uint8 `xdropaque:"false"`
+	From      uint8 `xdropaque:"false"`
+	LockId    int32
 	Timestamp Timestamp
 	Intervals []IntervalRecord
+	Shared    bool
+}
+
+type ReadLockRelease struct {
+	From   uint8 `xdropaque:"false"`
+	LockId int32
 }
 
 type BarrierRequest struct {
 	From      uint8 `xdropaque:"false"`
-	BarrierId uint8 `xdropaque:"false"`
+	BarrierId int32
 	Timestamp Timestamp
 	Intervals []IntervalRecord
 }
@@ -24,25 +33,141 @@ type BarrierResponse struct {
 	Timestamp Timestamp
 }
 
+//SyncRequest asks the manager of a condition variable or semaphore to wait on it or to signal it.
+//It carries the intervals of the sender, which the manager passes on to the hosts it wakes up.
+type SyncRequest struct {
+	From      uint8 `xdropaque:"false"`
+	ReqId     uint32
+	WakeId    uint32 //the request the manager answers when it wakes up a waiting condition variable
+	Op        uint8 `xdropaque:"false"`
+	Id        int32
+	CondId    int32
+	Timestamp Timestamp
+	Intervals []IntervalRecord
+}
+
+type SyncResponse struct {
+	ReqId     uint32
+	Intervals []IntervalRecord
+}
+
+//AtomicRequest asks the owner of an address to execute an atomic operation on the int64 at it.
+//Like a SyncRequest, it carries the intervals of the sender that the owner may be missing.
+type AtomicRequest struct {
+	From      uint8 `xdropaque:"false"`
+	ReqId     uint32
+	Op        uint8 `xdropaque:"false"`
+	Addr      int32
+	Old       int64
+	Value     int64
+	Timestamp Timestamp
+	Intervals []IntervalRecord
+}
+
+type AtomicResponse struct {
+	ReqId     uint32
+	Value     int64 //the value before the operation
+	Intervals []IntervalRecord
+}
+
+//BarrierMessage is sent between hosts by the tree and dissemination barriers. It carries the intervals the receiver
+//may be missing, and the merged arrival timestamps of the hosts the sender has heard of.
+type BarrierMessage struct {
+	From      uint8 `xdropaque:"false"`
+	BarrierId int32
+	Round     int32
+	Timestamp Timestamp //the timestamp of the sender
+	Arrived   Timestamp
+	Intervals []IntervalRecord
+}
+
+//ReleaseNotice carries the intervals a host made since its last release, when it uses eager release consistency.
+type ReleaseNotice struct {
+	From      uint8 `xdropaque:"false"`
+	ReqId     uint32
+	Intervals []IntervalRecord
+}
+
+type ReleaseAck struct {
+	ReqId uint32
+}
+
 type DiffRequest struct {
 	From   uint8 `xdropaque:"false"`
 	to     uint8
+	ReqId  uint32
 	PageNr int16
 	First  Timestamp
 	Last   Timestamp
 }
 
 type DiffResponse struct {
+	ReqId        uint32
 	PageNr       int16
 	Writenotices []WritenoticeRecord
 }
 
+//DiffBatchRequest asks a single writer for the diffs of several pages at once.
+type DiffBatchRequest struct {
+	From     uint8 `xdropaque:"false"`
+	ReqId    uint32
+	Requests []DiffRequest
+}
+
+type DiffBatchResponse struct {
+	ReqId     uint32
+	Responses []DiffResponse
+}
+
+//MallocRequest and FreeRequest are sent to the heap manager, which does all allocations for the cluster.
+type MallocRequest struct {
+	From  uint8 `xdropaque:"false"`
+	ReqId uint32
+	Size  int32
+	Flags []int32 //the allocation flags as kind, address pairs
+}
+
+type MallocResponse struct {
+	ReqId uint32
+	Addr  int32
+	Err   string
+}
+
+type FreeRequest struct {
+	From  uint8 `xdropaque:"false"`
+	ReqId uint32
+	Addr  int32
+}
+
+type FreeResponse struct {
+	ReqId uint32
+	Err   string
+}
+
+//WriteStatsRequest asks a host for the bytes it has written, for the false sharing analysis.
+type WriteStatsRequest struct {
+	From  uint8 `xdropaque:"false"`
+	ReqId uint32
+}
+
+type WriteStatsResponse struct {
+	ReqId uint32
+	Pages []PageWrites
+}
+
+type PageWrites struct {
+	PageNr int16
+	Ranges []int32 //first and last offset of each written range
+}
+
 type CopyRequest struct {
 	From   uint8 `xdropaque:"false"`
+	ReqId  uint32
 	PageNr int16 `xdropaque:"false"`
 }
 
 type CopyResponse struct {
+	ReqId  uint32
 	PageNr int16 `xdropaque:"false"`
 	Data   []byte
 }
@@ -147,3 +272,27 @@ func (t *DiffRequest) GobDecode(data []byte) error {
 	return nil
 }
 */
+
+//IntervalRequest asks a host for the intervals it made that the sender doesn't have in full, see fetchHeldBack.
+type IntervalRequest struct {
+	From      uint8 `xdropaque:"false"`
+	ReqId     uint32
+	Timestamp Timestamp
+}
+
+type IntervalResponse struct {
+	ReqId     uint32
+	Intervals []IntervalRecord
+}
+
+//ParticipantsRequest asks the manager of a barrier made by NewBarrier which hosts meet at it.
+type ParticipantsRequest struct {
+	From      uint8 `xdropaque:"false"`
+	ReqId     uint32
+	BarrierId int32
+}
+
+type ParticipantsResponse struct {
+	ReqId        uint32
+	Participants []uint8 //nil if all hosts meet at the barrier
+}
@@ -2,10 +2,13 @@ package treadmarks
 
 import (
 	"DSM-project/dsm-api"
+	"DSM-project/memory"
 	"DSM-project/utils"
+	"encoding/binary"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 )
@@ -486,7 +489,7 @@ func TestNewTreadmarksApi_locks(t *testing.T) {
 	//go1 := make(chan bool)
 	go2 := make(chan bool, 1)
 
-	var lockId uint8 = 0
+	lockId := 0
 
 	lock0, lock1, lock2 := tm0.locks[lockId], tm1.locks[lockId], tm2.locks[lockId]
 
@@ -615,7 +618,7 @@ func TestTreadmarksApiTestLock(t *testing.T) {
 	//go2 :=  make(chan bool, 1)
 	//go3 :=  make(chan bool, 1)
 
-	var lockId uint8 = 0
+	lockId := 0
 
 	lock0, lock1, lock2, lock3 := tm0.locks[lockId], tm1.locks[lockId], tm2.locks[lockId], tm3.locks[lockId]
 
@@ -714,7 +717,7 @@ func TestTreadmarksApiTestLock2(t *testing.T) {
 	//go2 :=  make(chan bool, 1)
 	//go3 :=  make(chan bool, 1)
 
-	var lockId uint8 = 0
+	lockId := 0
 
 	lock0, lock1, lock2, lock3 := tm0.locks[lockId], tm1.locks[lockId], tm2.locks[lockId], tm3.locks[lockId]
 
@@ -1089,3 +1092,981 @@ func timeout(channel chan bool) bool {
 		return true
 	}
 }
+
+func TestTreadmarksApi_Compression(t *testing.T) {
+	tm0, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm0.Initialize(1000)
+	tm0.SetCompression(true)
+	defer tm0.Shutdown()
+	tm1, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm1.Initialize(1001)
+	tm1.SetCompression(true)
+	tm1.Join("localhost", 1000)
+	defer tm1.Shutdown()
+	time.Sleep(time.Millisecond * 100)
+	assert.True(t, tm0.conn.Compresses(1))
+	assert.True(t, tm1.conn.Compresses(0))
+
+	//a page of zeros is sent without its data
+	raw, _ := tm0.BytesSent()
+	data, err := tm1.ReadBytes(256, 128)
+	assert.Nil(t, err)
+	assert.Equal(t, make([]byte, 128), data)
+	after, _ := tm0.BytesSent()
+	assert.True(t, after-raw < 128, "The copy of a zero page carries no data.")
+
+	//a page written with a repeating pattern is large enough to be compressed
+	pattern := make([]byte, 128)
+	for i := range pattern {
+		pattern[i] = byte(i%4 + 1)
+	}
+	raw, wire := tm0.BytesSent()
+	go1, go2 := make(chan bool), make(chan bool)
+	go func() {
+		assert.Nil(t, tm0.WriteBytes(0, pattern))
+		tm0.Barrier(1)
+		go1 <- true
+	}()
+	go func() {
+		tm1.Barrier(1)
+		data, err := tm1.ReadBytes(0, 128)
+		assert.Nil(t, err)
+		assert.Equal(t, pattern, data)
+		go2 <- true
+	}()
+	assert.True(t, <-go1)
+	assert.True(t, <-go2)
+	rawAfter, wireAfter := tm0.BytesSent()
+	assert.True(t, wireAfter-wire < rawAfter-raw)
+}
+
+func TestTreadmarksApi_ConcurrentFaults(t *testing.T) {
+	tm0, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm0.Initialize(1002)
+	defer tm0.Shutdown()
+	tm1, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm1.Initialize(1003)
+	tm1.Join("localhost", 1002)
+	defer tm1.Shutdown()
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 8; i++ {
+			assert.Nil(t, tm0.Write(i*128+1, byte(i+1)))
+		}
+		tm0.Barrier(1)
+		tm0.Barrier(1)
+		for i := 0; i < 8; i++ {
+			b, err := tm0.Read(i*128 + 2)
+			assert.Nil(t, err)
+			assert.Equal(t, byte(i+10), b)
+		}
+		done <- true
+	}()
+	tm1.Barrier(1)
+	group := new(sync.WaitGroup)
+	for i := 0; i < 8; i++ {
+		group.Add(1)
+		go func(i int) {
+			b, err := tm1.Read(i*128 + 1)
+			assert.Nil(t, err)
+			assert.Equal(t, byte(i+1), b)
+			assert.Nil(t, tm1.Write(i*128+2, byte(i+10)))
+			group.Done()
+		}(i)
+	}
+	group.Wait()
+	tm1.Barrier(1)
+	assert.True(t, <-done)
+	assert.Len(t, tm1.pending, 0)
+}
+
+func TestTreadmarksApi_Prefetch(t *testing.T) {
+	tm0, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm0.Initialize(1004)
+	defer tm0.Shutdown()
+	tm1, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm1.Initialize(1005)
+	tm1.Join("localhost", 1004)
+	defer tm1.Shutdown()
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 4; i++ {
+			assert.Nil(t, tm0.Write(i*128, byte(i+1)))
+		}
+		tm0.Barrier(1)
+		tm0.Barrier(1)
+		assert.Nil(t, <-tm0.Prefetch(0, 512))
+		copies := tm0.messageLog[5]
+		for i := 0; i < 4; i++ {
+			b, err := tm0.Read(i*128 + 1)
+			assert.Nil(t, err)
+			assert.Equal(t, byte(i+10), b)
+		}
+		assert.Equal(t, copies, tm0.messageLog[5])
+		assert.Equal(t, 0, tm0.messageLog[7])
+		done <- true
+	}()
+	tm1.Barrier(1)
+	assert.Nil(t, tm1.prefetch(tm1.pagesInRange(0, 512)))
+	assert.Equal(t, 4, tm1.messageLog[5])
+	assert.Equal(t, 0, tm1.messageLog[7])
+	assert.Equal(t, 1, tm1.messageLog[9])
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, memory.READ_ONLY, tm1.memory.GetRights(i*128))
+		b, err := tm1.Read(i * 128)
+		assert.Nil(t, err)
+		assert.Equal(t, byte(i+1), b)
+		assert.Nil(t, tm1.Write(i*128+1, byte(i+10)))
+	}
+	assert.Equal(t, 4, tm1.messageLog[5])
+	tm1.Barrier(1)
+	assert.True(t, <-done)
+	assert.NotNil(t, <-tm1.Prefetch(1000, 100))
+	assert.Nil(t, <-tm1.Prefetch(0, 0))
+}
+
+func TestTreadmarksApi_PrefetchShutdown(t *testing.T) {
+	tm0, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm0.Initialize(1057)
+	tm1, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm1.Initialize(1058)
+	tm1.Join("localhost", 1057)
+	//the copies are never sent, so the prefetch is still waiting when its host shuts down
+	tm0.Shutdown()
+	result := tm1.Prefetch(0, 256)
+	time.Sleep(time.Millisecond * 100)
+	tm1.Shutdown()
+	assert.NotNil(t, <-result)
+}
+
+func TestTreadmarksApi_ReadAhead(t *testing.T) {
+	tm0, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm0.Initialize(1006)
+	defer tm0.Shutdown()
+	tm1, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm1.Initialize(1007)
+	tm1.Join("localhost", 1006)
+	defer tm1.Shutdown()
+	tm1.SetReadAhead(2)
+
+	_, err := tm1.Read(0)
+	assert.Nil(t, err)
+	assert.Equal(t, memory.NO_ACCESS, tm1.memory.GetRights(256))
+	_, err = tm1.Read(128)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond * 200)
+	assert.Equal(t, memory.READ_ONLY, tm1.memory.GetRights(256))
+	assert.Equal(t, memory.READ_ONLY, tm1.memory.GetRights(384))
+	assert.Equal(t, memory.NO_ACCESS, tm1.memory.GetRights(512))
+}
+
+func TestTreadmarksApi_DistributedMalloc(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(4096, 128, 3, 3, 3)
+		tms[i].Initialize(1008 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1008)
+		}
+	}
+
+	addrs := make(chan int, 30)
+	group := new(sync.WaitGroup)
+	for _, tm := range tms {
+		for j := 0; j < 10; j++ {
+			group.Add(1)
+			go func(tm *TreadmarksApi) {
+				addr, err := tm.Malloc(50)
+				assert.Nil(t, err)
+				addrs <- addr
+				group.Done()
+			}(tm)
+		}
+	}
+	group.Wait()
+	close(addrs)
+	seen := make(map[int]bool)
+	for addr := range addrs {
+		for a := addr; a < addr+50; a++ {
+			assert.False(t, seen[a], "overlapping allocations")
+			seen[a] = true
+		}
+	}
+	assert.Equal(t, 30, tms[0].memory.MemStats().Allocations)
+
+	addr, err := tms[1].Malloc(100)
+	assert.Nil(t, err)
+	assert.Nil(t, tms[2].Free(addr, 100))
+	assert.Equal(t, memory.DoubleFreeErr, tms[1].Free(addr, 100))
+	_, err = tms[2].Malloc(10000)
+	assert.Equal(t, memory.InsufficientSpaceErr, err)
+	//TreadMarks only does write-invalidate
+	_, err = tms[2].Malloc(10, memory.WriteUpdate)
+	assert.Equal(t, memory.UnsupportedFlagErr, err)
+	_, err = tms[0].Malloc(10, memory.WriteUpdate)
+	assert.Equal(t, memory.UnsupportedFlagErr, err)
+	_, err = tms[2].Malloc(10, memory.ColocatedWith(addr))
+	assert.Equal(t, memory.InvalidColocationErr, err)
+}
+
+func TestTreadmarksApi_SharingReport(t *testing.T) {
+	tm0, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm0.Initialize(1011)
+	defer tm0.Shutdown()
+	tm1, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm1.Initialize(1012)
+	tm1.Join("localhost", 1011)
+	defer tm1.Shutdown()
+
+	a, _ := tm0.Malloc(16)
+	b, _ := tm1.Malloc(16)
+	c, _ := tm0.Malloc(16, memory.PageIsolated)
+	d, _ := tm1.Malloc(16, memory.PageIsolated)
+	assert.Equal(t, 0, c%128)
+	assert.Equal(t, 0, d%128)
+	assert.NotEqual(t, c/128, d/128)
+
+	done := make(chan bool)
+	go func() {
+		tm0.Write(a, 1)
+		tm0.Write(c, 1)
+		tm0.Barrier(1)
+		done <- true
+	}()
+	tm1.Write(b, 2)
+	tm1.Write(d, 2)
+	tm1.Barrier(1)
+	<-done
+
+	report := tm0.SharingReport()
+	assert.Equal(t, []SharedAllocation{{a, 16, 0, []uint8{0, 1}}, {b, 16, 0, []uint8{0, 1}}}, report)
+}
+
+func TestTreadmarksApi_FalseSharingReport(t *testing.T) {
+	tm0, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm0.Initialize(1013)
+	defer tm0.Shutdown()
+	tm1, _ := NewTreadmarksApi(1024, 128, 2, 3, 3)
+	tm1.Initialize(1014)
+	tm1.Join("localhost", 1013)
+	defer tm1.Shutdown()
+	tm0.SetSharingAnalysis(true)
+	tm1.SetSharingAnalysis(true)
+
+	a, _ := tm0.Malloc(16)
+	b, _ := tm0.Malloc(16)
+	c, _ := tm0.Malloc(16, memory.PageIsolated)
+
+	done := make(chan bool)
+	go func() {
+		tm0.WriteBytes(a, []byte{1, 2, 3, 4})
+		tm0.Write(c+5, 1)
+		tm0.Barrier(1)
+		done <- true
+	}()
+	tm1.WriteBytes(b+2, []byte{5, 6})
+	tm1.Write(c+5, 2)
+	tm1.Barrier(1)
+	<-done
+
+	//the page holding c is truly shared, as both hosts wrote the same byte
+	report := tm0.FalseSharingReport()
+	assert.Equal(t, []FalseSharing{{0, []WriteRange{{0, a, a + 3, a}, {1, b + 2, b + 3, b}}}}, report)
+}
+
+func TestTreadmarksApi_ReadLock(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 3, 3)
+		tms[i].Initialize(1015 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1015)
+		}
+	}
+	tms[1].AcquireLock(0)
+	tms[1].Write(5, 42)
+	tms[1].ReleaseLock(0)
+
+	//both readers hold the lock at once, and see the write made under it
+	group := new(sync.WaitGroup)
+	group.Add(2)
+	for _, tm := range []*TreadmarksApi{tms[0], tms[2]} {
+		go func(tm *TreadmarksApi) {
+			tm.AcquireReadLock(0)
+			res, _ := tm.Read(5)
+			assert.Equal(t, byte(42), res)
+			group.Done()
+		}(tm)
+	}
+	group.Wait()
+	lock := tms[1].locks[0]
+	lock.Lock()
+	assert.Equal(t, 2, lock.readers)
+	assert.True(t, lock.haveToken)
+	lock.Unlock()
+
+	//a writer waits for the readers to leave
+	acquired := make(chan bool)
+	go func() {
+		tms[1].AcquireLock(0)
+		acquired <- true
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("got the lock while it was held in shared mode")
+	case <-time.After(time.Millisecond * 100):
+	}
+	tms[0].ReleaseReadLock(0)
+	tms[2].ReleaseReadLock(0)
+	<-acquired
+	tms[1].Write(5, 43)
+	tms[1].ReleaseLock(0)
+
+	tms[2].AcquireReadLock(0)
+	res, _ := tms[2].Read(5)
+	assert.Equal(t, byte(43), res)
+	tms[2].ReleaseReadLock(0)
+}
+
+func TestTreadmarksApi_TryAcquireLock(t *testing.T) {
+	tms := make([]*TreadmarksApi, 2)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 2, 3, 3)
+		tms[i].Initialize(1018 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1018)
+		}
+	}
+	assert.True(t, tms[0].TryAcquireLock(0), "Host 0 starts with the token.")
+	assert.False(t, tms[1].TryAcquireLock(0), "The token is at host 0.")
+	tms[0].ReleaseLock(0)
+	assert.True(t, tms[1].AcquireLockTimeout(0, time.Second))
+	tms[1].Write(5, 42)
+	tms[1].ReleaseLock(0)
+	assert.True(t, tms[1].TryAcquireLock(0), "The token stays at host 1 after it is released.")
+	assert.False(t, tms[0].TryAcquireLock(0))
+	tms[1].ReleaseLock(0)
+}
+
+//Here a request times out while it is forwarded to the holder of the token, and is queued there.
+//The token must pass through the host that gave up, to the hosts that queued up behind it.
+func TestTreadmarksApi_AcquireLockTimeout(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 3, 3)
+		tms[i].Initialize(1020 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1020)
+		}
+	}
+	tms[1].AcquireLock(0)
+	tms[1].Write(5, 42)
+	//host 2 asks host 0, which forwards the request to host 1
+	assert.False(t, tms[2].AcquireLockTimeout(0, time.Millisecond*100))
+	lock := tms[2].locks[0]
+	lock.Lock()
+	assert.True(t, lock.withdrawn)
+	lock.Unlock()
+
+	acquired := make(chan bool)
+	go func() {
+		tms[0].AcquireLock(0)
+		acquired <- true
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("got the lock while it was held")
+	case <-time.After(time.Millisecond * 100):
+	}
+	tms[1].ReleaseLock(0)
+	<-acquired
+	res, _ := tms[0].Read(5)
+	assert.Equal(t, byte(42), res)
+	tms[0].Write(5, 43)
+	tms[0].ReleaseLock(0)
+
+	assert.True(t, tms[2].AcquireLockTimeout(0, time.Second))
+	res, _ = tms[2].Read(5)
+	assert.Equal(t, byte(43), res)
+	tms[2].ReleaseLock(0)
+
+	//waiting for two locks at once, the grant of one lock doesn't wake up the wait for the other
+	tms[1].AcquireLock(1)
+	tms[1].AcquireLock(2)
+	results := make([]chan bool, 3)
+	for _, id := range []int{1, 2} {
+		results[id] = make(chan bool)
+		go func(id int) {
+			results[id] <- tms[2].AcquireLockTimeout(id, time.Millisecond*time.Duration(500*id))
+		}(id)
+	}
+	time.Sleep(time.Millisecond * 100)
+	tms[1].ReleaseLock(1)
+	assert.True(t, <-results[1])
+	assert.False(t, <-results[2])
+	tms[1].ReleaseLock(2)
+	tms[2].ReleaseLock(1)
+	assert.True(t, tms[0].AcquireLockTimeout(2, time.Second))
+	tms[0].ReleaseLock(2)
+
+	//a host that gave up mustn't be woken up by the lock later on
+	group := new(sync.WaitGroup)
+	group.Add(3)
+	for _, tm := range tms {
+		go func(tm *TreadmarksApi) {
+			tm.Barrier(0)
+			group.Done()
+		}(tm)
+	}
+	group.Wait()
+}
+
+func TestTreadmarksApi_NewLock(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 1, 1)
+		tms[i].Initialize(1023 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1023)
+		}
+	}
+	ids := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		for _, tm := range tms {
+			id := tm.NewLock()
+			assert.False(t, ids[id], "Lock ids must be unique across hosts.")
+			ids[id] = true
+		}
+	}
+	id := tms[1].NewLock()
+	assert.True(t, tms[1].TryAcquireLock(id), "The host that made the lock starts with its token.")
+	tms[1].Write(5, 42)
+	tms[1].ReleaseLock(id)
+	tms[2].AcquireLock(id)
+	res, _ := tms[2].Read(5)
+	assert.Equal(t, byte(42), res)
+	tms[2].Write(5, 43)
+	tms[2].ReleaseLock(id)
+	tms[0].AcquireLock(id)
+	res, _ = tms[0].Read(5)
+	assert.Equal(t, byte(43), res)
+	tms[0].ReleaseLock(id)
+
+	//locks beyond nrLocks are made when they are first used
+	tms[2].AcquireLock(200)
+	tms[2].ReleaseLock(200)
+	tms[1].AcquireLock(200)
+	tms[1].ReleaseLock(200)
+}
+
+func TestTreadmarksApi_NewBarrier(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 1, 1)
+		tms[i].Initialize(1026 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1026)
+		}
+	}
+	//host 2 manages a barrier it doesn't take part in
+	id := tms[2].NewBarrier([]int{0, 1})
+	group := new(sync.WaitGroup)
+	group.Add(2)
+	go func() {
+		tms[0].Write(5, 42)
+		tms[0].Barrier(id)
+		group.Done()
+	}()
+	go func() {
+		tms[1].Barrier(id)
+		res, _ := tms[1].Read(5)
+		assert.Equal(t, byte(42), res)
+		group.Done()
+	}()
+	group.Wait()
+
+	//the barrier can be used again, and meets without the hosts that aren't in it
+	group.Add(2)
+	for _, tm := range tms[:2] {
+		go func(tm *TreadmarksApi) {
+			tm.Barrier(id)
+			group.Done()
+		}(tm)
+	}
+	group.Wait()
+	assert.Panics(t, func() { tms[0].NewBarrier([]int{3}) })
+}
+
+func TestTreadmarksApi_NewBarrierAlgorithm(t *testing.T) {
+	for a, algorithm := range []BarrierAlgorithm{TreeBarrier, DisseminationBarrier} {
+		port := 1067 + a*7
+		tms := make([]*TreadmarksApi, 7)
+		for i := range tms {
+			tms[i], _ = NewTreadmarksApi(1024, 128, 7, 1, 1)
+			tms[i].Initialize(port + i)
+			tms[i].SetBarrierAlgorithm(algorithm)
+			if i > 0 {
+				tms[i].Join("localhost", port)
+			}
+		}
+		//host 0 manages a barrier it doesn't take part in. With six hosts the tree rooted at host 6 has two levels.
+		id := tms[0].NewBarrier([]int{6, 5, 4, 3, 2, 1})
+		group := new(sync.WaitGroup)
+		group.Add(len(tms) - 1)
+		for i, tm := range tms[1:] {
+			go func(i int, tm *TreadmarksApi) {
+				for round := byte(1); round <= 2; round++ {
+					tm.Write(i*128, round)
+					tm.Barrier(id)
+					for j := range tms[1:] {
+						res, _ := tm.Read(j * 128)
+						assert.Equal(t, round, res)
+					}
+					tm.Barrier(id)
+				}
+				group.Done()
+			}(i, tm)
+		}
+		group.Wait()
+
+		//a barrier of all hosts after it carries the writes to host 0
+		group.Add(len(tms) - 1)
+		for _, tm := range tms[1:] {
+			go func(tm *TreadmarksApi) {
+				tm.Barrier(0)
+				group.Done()
+			}(tm)
+		}
+		tms[0].Barrier(0)
+		group.Wait()
+		for j := range tms[1:] {
+			res, _ := tms[0].Read(j * 128)
+			assert.Equal(t, byte(2), res)
+		}
+		for _, tm := range tms {
+			tm.Shutdown()
+		}
+	}
+}
+
+func TestTreadmarksApi_Condition(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 1, 1)
+		tms[i].Initialize(1029 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1029)
+		}
+	}
+	lock := tms[1].NewLock()
+	//hosts 0 and 2 wait for host 1 to produce a value
+	group := new(sync.WaitGroup)
+	group.Add(2)
+	for _, tm := range []*TreadmarksApi{tms[0], tms[2]} {
+		go func(tm *TreadmarksApi) {
+			tm.AcquireLock(lock)
+			for {
+				res, _ := tm.Read(5)
+				if res != 0 {
+					break
+				}
+				tm.Wait(lock, 0)
+			}
+			res, _ := tm.Read(5)
+			assert.Equal(t, byte(42), res)
+			tm.ReleaseLock(lock)
+			group.Done()
+		}(tm)
+	}
+	time.Sleep(time.Millisecond * 200)
+	tms[1].AcquireLock(lock)
+	tms[1].Write(5, 42)
+	tms[1].Broadcast(lock, 0)
+	tms[1].ReleaseLock(lock)
+	group.Wait()
+
+	//a signal wakes up a single host
+	done := make(chan bool, 2)
+	for _, tm := range []*TreadmarksApi{tms[0], tms[2]} {
+		go func(tm *TreadmarksApi) {
+			tm.AcquireLock(lock)
+			tm.Wait(lock, 1)
+			tm.ReleaseLock(lock)
+			done <- true
+		}(tm)
+	}
+	time.Sleep(time.Millisecond * 200)
+	tms[1].AcquireLock(lock)
+	tms[1].Signal(lock, 1)
+	tms[1].ReleaseLock(lock)
+	<-done
+	select {
+	case <-done:
+		t.Fatal("a signal woke up two hosts")
+	case <-time.After(time.Millisecond * 200):
+	}
+	tms[1].AcquireLock(lock)
+	tms[1].Signal(lock, 1)
+	tms[1].ReleaseLock(lock)
+	<-done
+}
+
+func TestTreadmarksApi_Semaphore(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 1, 1)
+		tms[i].Initialize(1032 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1032)
+		}
+	}
+	sem := tms[0].NewSemaphore(1)
+	tms[1].AcquireSemaphore(sem)
+	acquired := make(chan bool)
+	go func() {
+		tms[2].AcquireSemaphore(sem)
+		acquired <- true
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("got the semaphore while its value was zero")
+	case <-time.After(time.Millisecond * 100):
+	}
+	//the write is passed on with the semaphore, without a lock
+	tms[1].Write(5, 42)
+	tms[1].ReleaseSemaphore(sem)
+	<-acquired
+	res, _ := tms[2].Read(5)
+	assert.Equal(t, byte(42), res)
+	tms[2].ReleaseSemaphore(sem)
+	tms[0].AcquireSemaphore(sem)
+	res, _ = tms[0].Read(5)
+	assert.Equal(t, byte(42), res)
+	tms[0].ReleaseSemaphore(sem)
+}
+
+func TestTreadmarksApi_Atomic(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 1, 1)
+		tms[i].Initialize(1035 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1035)
+		}
+	}
+	//the counter is on a page owned by host 1
+	counter := 128 + 8
+	group := new(sync.WaitGroup)
+	group.Add(len(tms))
+	for _, tm := range tms {
+		go func(tm *TreadmarksApi) {
+			for i := 0; i < 50; i++ {
+				_, err := tm.FetchAndAdd(counter, 1)
+				assert.Nil(t, err)
+			}
+			group.Done()
+		}(tm)
+	}
+	group.Wait()
+	res, _ := tms[2].FetchAndAdd(counter, 0)
+	assert.Equal(t, int64(150), res)
+
+	swapped, _ := tms[0].CompareAndSwap(counter, 150, 7)
+	assert.True(t, swapped)
+	swapped, _ = tms[2].CompareAndSwap(counter, 150, 8)
+	assert.False(t, swapped)
+	res, _ = tms[2].Swap(counter, 9)
+	assert.Equal(t, int64(7), res)
+
+	//the result is passed on to the next holder of a lock, who can read it without an atomic operation
+	tms[0].AcquireLock(0)
+	tms[0].FetchAndAdd(counter, 1)
+	tms[0].ReleaseLock(0)
+	tms[2].AcquireLock(0)
+	b, _ := tms[2].ReadBytes(counter, 8)
+	assert.Equal(t, int64(10), int64(binary.LittleEndian.Uint64(b)))
+	tms[2].ReleaseLock(0)
+
+	_, err := tms[0].FetchAndAdd(counter+1, 1)
+	assert.NotNil(t, err, "The address isn't aligned.")
+}
+
+//Atomic requests are executed off the incoming goroutine, while lock handoffs add intervals on it.
+//Run with -race to check that they don't touch the intervals at the same time.
+func TestTreadmarksApi_AtomicsDuringLocks(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 1, 1)
+		tms[i].Initialize(1060 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1060)
+		}
+	}
+	//the counter is on a page owned by host 1, which also takes part in the lock handoffs
+	counter := 128 + 8
+	group := new(sync.WaitGroup)
+	group.Add(3)
+	go func() {
+		for i := 0; i < 50; i++ {
+			tms[0].FetchAndAdd(counter, 1)
+		}
+		group.Done()
+	}()
+	for _, tm := range tms[1:] {
+		go func(tm *TreadmarksApi) {
+			for i := 0; i < 20; i++ {
+				tm.AcquireLock(0)
+				tm.Write(512+tm.GetId(), byte(i))
+				tm.ReleaseLock(0)
+			}
+			group.Done()
+		}(tm)
+	}
+	group.Wait()
+	res, _ := tms[1].FetchAndAdd(counter, 0)
+	assert.Equal(t, int64(50), res)
+}
+
+func TestTreadmarksApi_BarrierAlgorithms(t *testing.T) {
+	for a, algorithm := range []BarrierAlgorithm{TreeBarrier, DisseminationBarrier} {
+		//six hosts give the tree two levels, and the dissemination barrier three rounds
+		port := 1038 + a*6
+		tms := make([]*TreadmarksApi, 6)
+		for i := range tms {
+			tms[i], _ = NewTreadmarksApi(1024, 128, 6, 1, 1)
+			tms[i].Initialize(port + i)
+			tms[i].SetBarrierAlgorithm(algorithm)
+			if i > 0 {
+				tms[i].Join("localhost", port)
+			}
+		}
+		group := new(sync.WaitGroup)
+		group.Add(len(tms))
+		for i, tm := range tms {
+			go func(i int, tm *TreadmarksApi) {
+				for round := byte(1); round <= 3; round++ {
+					//every host writes a page of its own, and its byte of a page they all write
+					tm.Write(i*128, round)
+					tm.Write(7*128+i, round)
+					tm.Barrier(0)
+					for j := range tms {
+						res, _ := tm.Read(j * 128)
+						assert.Equal(t, round, res)
+						res, _ = tm.Read(7*128 + j)
+						assert.Equal(t, round, res)
+					}
+					tm.Barrier(0)
+				}
+				group.Done()
+			}(i, tm)
+		}
+		group.Wait()
+		for _, tm := range tms {
+			tm.Shutdown()
+		}
+	}
+}
+
+func TestTreadmarksApi_BindLock(t *testing.T) {
+	tms := make([]*TreadmarksApi, 2)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 2, 1, 1)
+		tms[i].Initialize(1050 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1050)
+		}
+	}
+	//only page 1 is in the scope of lock 0 at host 1
+	tms[1].BindLock(0, 128, 128)
+	tms[1].Read(128)
+	tms[1].Read(256)
+
+	tms[0].AcquireLock(0)
+	tms[0].Write(128, 42)
+	tms[0].Write(256, 43)
+	tms[0].ReleaseLock(0)
+
+	tms[1].AcquireLock(0)
+	//host 0 only sent the write notice for page 1
+	assert.Len(t, tms[1].Writenotices(0, 1), 1)
+	assert.Len(t, tms[1].Writenotices(0, 2), 0)
+	res, _ := tms[1].Read(128)
+	assert.Equal(t, byte(42), res)
+	res, _ = tms[1].Read(256)
+	assert.Equal(t, byte(0), res, "The page is outside the scope, so it isn't invalidated by the acquire.")
+	tms[1].ReleaseLock(0)
+
+	group := new(sync.WaitGroup)
+	group.Add(1)
+	go func() {
+		tms[0].Barrier(0)
+		group.Done()
+	}()
+	tms[1].Barrier(0)
+	group.Wait()
+	res, _ = tms[1].Read(256)
+	assert.Equal(t, byte(43), res)
+}
+
+func TestTreadmarksApi_BindLockUnscopedAcquire(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 3, 1, 1)
+		tms[i].Initialize(1064 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1064)
+		}
+	}
+	tms[1].BindLock(0, 128, 128)
+	for _, tm := range tms[1:] {
+		tm.Read(128)
+		tm.Read(256)
+	}
+
+	tms[0].AcquireLock(0)
+	tms[0].Write(128, 42)
+	tms[0].Write(256, 43)
+	tms[0].ReleaseLock(0)
+
+	tms[1].AcquireLock(0)
+	res, _ := tms[1].Read(128)
+	assert.Equal(t, byte(42), res)
+	res, _ = tms[1].Read(256)
+	assert.Equal(t, byte(0), res)
+	tms[1].ReleaseLock(0)
+
+	//host 1 grants the lock, but has only part of the interval of host 0, so host 2 gets the rest from host 0
+	tms[2].AcquireLock(0)
+	res, _ = tms[2].Read(128)
+	assert.Equal(t, byte(42), res)
+	res, _ = tms[2].Read(256)
+	assert.Equal(t, byte(43), res)
+	tms[2].ReleaseLock(0)
+
+	group := new(sync.WaitGroup)
+	group.Add(2)
+	for _, tm := range tms[:2] {
+		go func(tm *TreadmarksApi) {
+			tm.Barrier(0)
+			group.Done()
+		}(tm)
+	}
+	tms[2].Barrier(0)
+	group.Wait()
+	res, _ = tms[1].Read(256)
+	assert.Equal(t, byte(43), res)
+}
+
+func TestTreadmarksApi_EagerRelease(t *testing.T) {
+	tms := make([]*TreadmarksApi, 3)
+	for i := range tms {
+		tms[i], _ = NewEagerTreadmarksApi(1024, 128, 3, 1, 1)
+		tms[i].Initialize(1052 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1052)
+		}
+	}
+	tms[1].Read(5)
+	tms[0].AcquireLock(0)
+	tms[0].Write(5, 42)
+	tms[0].ReleaseLock(0)
+	//the release has invalidated the copy at host 1 before it returned
+	res, _ := tms[1].Read(5)
+	assert.Equal(t, byte(42), res)
+
+	tms[2].AcquireLock(0)
+	res, _ = tms[2].Read(5)
+	assert.Equal(t, byte(42), res)
+	tms[2].Write(6, 43)
+	tms[2].ReleaseLock(0)
+
+	group := new(sync.WaitGroup)
+	group.Add(len(tms))
+	for _, tm := range tms {
+		go func(tm *TreadmarksApi) {
+			tm.Barrier(0)
+			res, _ := tm.Read(6)
+			assert.Equal(t, byte(43), res)
+			group.Done()
+		}(tm)
+	}
+	group.Wait()
+}
+
+//TestTreadmarksApi_FaultsDuringDiffs faults pages from several goroutines while write notices and diffs for the same
+//pages are handled. Run it with -race.
+func TestTreadmarksApi_FaultsDuringDiffs(t *testing.T) {
+	tms := make([]*TreadmarksApi, 2)
+	for i := range tms {
+		tms[i], _ = NewTreadmarksApi(1024, 128, 2, 1, 1)
+		tms[i].Initialize(1055 + i)
+		defer tms[i].Shutdown()
+		if i > 0 {
+			tms[i].Join("localhost", 1055)
+		}
+	}
+	group := new(sync.WaitGroup)
+	group.Add(4)
+	//the writer and the reader take turns on the lock
+	go func() {
+		for i := byte(1); i <= 20; i++ {
+			tms[0].AcquireLock(0)
+			tms[0].Write(128, i)
+			tms[0].Write(256, i)
+			tms[0].ReleaseLock(0)
+		}
+		group.Done()
+	}()
+	go func() {
+		var last byte
+		for i := 0; i < 20; i++ {
+			tms[1].AcquireLock(0)
+			res, _ := tms[1].Read(128)
+			assert.True(t, res >= last)
+			last = res
+			tms[1].ReleaseLock(0)
+		}
+		group.Done()
+	}()
+	//these read without the lock, so they fault while the lock holders' write notices and diffs come in
+	for _, tm := range tms {
+		go func(tm *TreadmarksApi) {
+			for i := 0; i < 100; i++ {
+				tm.Read(256 + i%2*128)
+			}
+			group.Done()
+		}(tm)
+	}
+	group.Wait()
+
+	tms[1].AcquireLock(0)
+	res, _ := tms[1].Read(128)
+	assert.Equal(t, byte(20), res)
+	res, _ = tms[1].Read(256)
+	assert.Equal(t, byte(20), res)
+	tms[1].ReleaseLock(0)
+}
+
+func TestTreadmarksApi_FaultOutOfBounds(t *testing.T) {
+	tm, _ := NewTreadmarksApi(64, 8, 1, 1, 1)
+	tm.SetLogger(nil)
+	assert.NotPanics(t, func() {
+		assert.NotNil(t, tm.onFault(64, 1, 0, "READ", nil))
+		assert.NotNil(t, tm.onFault(-8, 1, 0, "WRITE", nil))
+	})
+}
@@ -5,7 +5,6 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
@@ -19,9 +18,13 @@ var benchmark = flag.String("benchmark", "default", "choose benchmark algorithm"
 var nrprocs = flag.Int("hosts", 1, "choose number of hosts.")
 var port = flag.Int("port", 2000, "Choose port.")
 var manager = flag.Bool("manager", true, "choose if instance is manager.")
+var compress = flag.Bool("compress", false, "compress page copies and diffs on the wire.")
+var eager = flag.Bool("eager", false, "use eager release consistency in the TreadMarks benchmarks.")
 
 func main() {
 	flag.Parse()
+	Benchmarks.UseCompression = *compress
+	Benchmarks.EagerRelease = *eager
 	var cpuprofFile io.Writer
 	if *cpuprofile == "" {
 		cpuname := *benchmark
@@ -32,7 +35,6 @@ func main() {
 
 	defer pprof.StopCPUProfile()
 	fmt.Println(*benchmark)
-	log.SetOutput(ioutil.Discard)
 	switch *benchmark {
 	case "ModuloMultMW":
 		wg := sync.WaitGroup{}
@@ -76,6 +78,12 @@ func main() {
 		Benchmarks.TestLockMW(2000000, cpuprofFile)
 	case "barrTM":
 		Benchmarks.TestBarrierTimeTM(100000, *nrprocs, cpuprofFile)
+	case "barrScalingTM":
+		hostCounts := make([]int, 0)
+		for n := 2; n <= *nrprocs; n *= 2 {
+			hostCounts = append(hostCounts, n)
+		}
+		Benchmarks.TestBarrierScalingTM(1000, hostCounts)
 	case "locksTM":
 		Benchmarks.TestLockTM(2000000, cpuprofFile)
 	case "SyncOpsCostTM":
@@ -0,0 +1,389 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+)
+
+var InvalidFreeErr = errors.New("invalid reference: no corresponding malloc found")
+var DoubleFreeErr = errors.New("invalid reference: memory has already been freed")
+var InsufficientSpaceErr = errors.New("insufficient space")
+var InvalidSizeErr = errors.New("invalid allocation size")
+var UnsupportedFlagErr = errors.New("allocation flag not supported")
+var InvalidColocationErr = errors.New("invalid co-location: no allocation at the address")
+
+//AllocFlag changes where an allocation is placed, or how it is kept coherent.
+type AllocFlag struct {
+	Kind int
+	Addr int //the allocation to co-locate with
+}
+
+const (
+	pageAligned = iota + 1
+	pageIsolated
+	colocated
+	writeUpdate
+)
+
+//PageAligned starts the allocation on a page boundary.
+var PageAligned = AllocFlag{Kind: pageAligned}
+
+//PageIsolated starts the allocation on a page boundary and pads it to whole pages, so no other allocation shares its pages.
+var PageIsolated = AllocFlag{Kind: pageIsolated}
+
+//ColocatedWith places the allocation on the pages of the allocation at addr if there is room, and otherwise as close after it as possible.
+//The allocation fails with InvalidColocationErr if nothing is allocated at addr.
+func ColocatedWith(addr int) AllocFlag {
+	return AllocFlag{Kind: colocated, Addr: addr}
+}
+
+//WriteUpdate makes the allocation use write-update instead of write-invalidate coherence.
+//It is up to the DSM system; Vmem and systems without write-update fail the allocation with UnsupportedFlagErr.
+var WriteUpdate = AllocFlag{Kind: writeUpdate}
+
+//FlagsToInts and FlagsFromInts convert allocation flags to and from a flat list, for sending them in messages.
+func FlagsToInts(flags []AllocFlag) []int {
+	res := make([]int, 0, 2*len(flags))
+	for _, f := range flags {
+		res = append(res, f.Kind, f.Addr)
+	}
+	return res
+}
+
+func FlagsFromInts(ints []int) []AllocFlag {
+	flags := make([]AllocFlag, 0, len(ints)/2)
+	for i := 0; i+1 < len(ints); i += 2 {
+		flags = append(flags, AllocFlag{ints[i], ints[i+1]})
+	}
+	return flags
+}
+
+//smallest size class. Size classes are powers of two from here up to a quarter page.
+const minClassSize = 8
+
+//MemStats describes how the memory is used.
+//UsedBytes + FreeBytes + SlackBytes always equals TotalBytes.
+type MemStats struct {
+	TotalBytes    int
+	UsedBytes     int //bytes of live allocations, as requested
+	FreeBytes     int //bytes that can still be allocated from the free list
+	SlackBytes    int //bytes held by size class slabs or page padding but not in use
+	LargestFree   int //the largest allocation the free list can serve
+	Allocations   int
+	Fragmentation float64 //share of the free bytes that lie outside the largest free range
+}
+
+//A slab is a page split into objects of a single size class.
+type slab struct {
+	start     int
+	classSize int
+	free      []int //addresses of unused objects
+	used      int
+}
+
+//SetSizeClasses turns size class allocation on or off. With it on, small allocations are rounded up to a power of two
+//and served from pages holding only objects of that size. Set it before the first allocation.
+func (m *Vmem) SetSizeClasses(b bool) {
+	m.sizeClasses = b
+}
+
+func (m *Vmem) Malloc(sizeInBytes int, flags ...AllocFlag) (int, error) {
+	if sizeInBytes <= 0 {
+		return 0, InvalidSizeErr
+	}
+	m.allocLock.Lock()
+	defer m.allocLock.Unlock()
+	return m.malloc(sizeInBytes, 1, flags...)
+}
+
+//MallocAligned allocates memory starting at a multiple of align, which must be a power of two.
+func (m *Vmem) MallocAligned(sizeInBytes, align int) (int, error) {
+	if sizeInBytes <= 0 {
+		return 0, InvalidSizeErr
+	}
+	if align <= 0 || align&(align-1) != 0 {
+		return 0, errors.New("alignment must be a power of two")
+	}
+	m.allocLock.Lock()
+	defer m.allocLock.Unlock()
+	return m.malloc(sizeInBytes, align)
+}
+
+//Allocations returns the address ranges of all live allocations, in address order.
+func (m *Vmem) Allocations() []AddrPair {
+	m.allocLock.Lock()
+	defer m.allocLock.Unlock()
+	res := make([]AddrPair, 0, len(m.mallocHistory))
+	for addr, size := range m.mallocHistory {
+		res = append(res, AddrPair{addr, addr + size - 1})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Start < res[j].Start })
+	return res
+}
+
+func (m *Vmem) malloc(sizeInBytes, align int, flags ...AllocFlag) (int, error) {
+	reserve, from, placed := sizeInBytes, 0, false
+	for _, f := range flags {
+		switch f.Kind {
+		case pageAligned:
+			align = max(align, m.PAGE_BYTESIZE)
+		case pageIsolated:
+			align = max(align, m.PAGE_BYTESIZE)
+			reserve = (sizeInBytes + m.PAGE_BYTESIZE - 1) / m.PAGE_BYTESIZE * m.PAGE_BYTESIZE
+			placed = true
+		case colocated:
+			if _, ok := m.mallocHistory[f.Addr]; !ok {
+				return 0, InvalidColocationErr
+			}
+			from = m.GetPageAddr(f.Addr)
+			placed = true
+		default:
+			return 0, UnsupportedFlagErr
+		}
+	}
+	var addr int
+	var err error
+	if class := m.sizeClass(sizeInBytes); class > 0 && class%align == 0 && !placed {
+		addr, err = m.allocObject(class)
+	} else {
+		addr, err = m.allocRange(reserve, align, from)
+		if err != nil && from > 0 {
+			addr, err = m.allocRange(reserve, align, 0)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	m.mallocHistory[addr] = sizeInBytes
+	if reserve != sizeInBytes {
+		m.reserved[addr] = reserve
+	}
+	delete(m.freed, addr)
+	return addr, nil
+}
+
+func (m *Vmem) Free(pointer int) error {
+	m.allocLock.Lock()
+	defer m.allocLock.Unlock()
+	return m.free(pointer)
+}
+
+func (m *Vmem) free(pointer int) error {
+	size, ok := m.mallocHistory[pointer]
+	if !ok {
+		if m.freed[pointer] {
+			return DoubleFreeErr
+		}
+		return InvalidFreeErr
+	}
+	delete(m.mallocHistory, pointer)
+	m.freed[pointer] = true
+	if reserve, ok := m.reserved[pointer]; ok {
+		size = reserve
+		delete(m.reserved, pointer)
+	}
+	if s := m.slabs[m.GetPageAddr(pointer)]; s != nil {
+		m.freeObject(s, pointer)
+	} else {
+		m.freeRange(pointer, size)
+	}
+	return nil
+}
+
+//Realloc resizes an allocation, moving it and its contents if it cannot grow in place.
+func (m *Vmem) Realloc(pointer, sizeInBytes int) (int, error) {
+	if sizeInBytes <= 0 {
+		return 0, InvalidSizeErr
+	}
+	m.allocLock.Lock()
+	defer m.allocLock.Unlock()
+	size, ok := m.mallocHistory[pointer]
+	if !ok {
+		if m.freed[pointer] {
+			return 0, DoubleFreeErr
+		}
+		return 0, InvalidFreeErr
+	}
+	var flags []AllocFlag
+	if reserve, ok := m.reserved[pointer]; ok {
+		if sizeInBytes <= reserve {
+			m.mallocHistory[pointer] = sizeInBytes
+			return pointer, nil
+		}
+		flags = append(flags, PageIsolated)
+	} else if s := m.slabs[m.GetPageAddr(pointer)]; s != nil {
+		if m.sizeClass(sizeInBytes) == s.classSize {
+			m.mallocHistory[pointer] = sizeInBytes
+			return pointer, nil
+		}
+	} else if sizeInBytes <= size {
+		m.freeRange(pointer+sizeInBytes, size-sizeInBytes)
+		m.mallocHistory[pointer] = sizeInBytes
+		return pointer, nil
+	} else if m.growRange(pointer+size, sizeInBytes-size) {
+		m.mallocHistory[pointer] = sizeInBytes
+		return pointer, nil
+	}
+	newPointer, err := m.malloc(sizeInBytes, 1, flags...)
+	if err != nil {
+		return 0, err
+	}
+	copy(m.Stack[newPointer:newPointer+min(size, sizeInBytes)], m.Stack[pointer:pointer+size])
+	m.free(pointer)
+	return newPointer, nil
+}
+
+func (m *Vmem) MemStats() MemStats {
+	m.allocLock.Lock()
+	defer m.allocLock.Unlock()
+	stats := MemStats{
+		TotalBytes:  len(m.Stack),
+		Allocations: len(m.mallocHistory),
+	}
+	for _, size := range m.mallocHistory {
+		stats.UsedBytes += size
+	}
+	for _, pair := range m.FreeMemObjects {
+		length := pair.End - pair.Start + 1
+		stats.FreeBytes += length
+		stats.LargestFree = max(stats.LargestFree, length)
+	}
+	stats.SlackBytes = stats.TotalBytes - stats.UsedBytes - stats.FreeBytes
+	if stats.FreeBytes > 0 {
+		stats.Fragmentation = 1 - float64(stats.LargestFree)/float64(stats.FreeBytes)
+	}
+	return stats
+}
+
+//----------------------------------------------------------------//
+//                           Free list                            //
+//----------------------------------------------------------------//
+
+//allocRange takes the first free space at or after from that can hold the allocation at the given alignment.
+func (m *Vmem) allocRange(size, align, from int) (int, error) {
+	for i, pair := range m.FreeMemObjects {
+		if pair.End < from {
+			continue
+		}
+		start := (max(pair.Start, from) + align - 1) / align * align
+		if start+size-1 > pair.End {
+			continue
+		}
+		rest := make([]AddrPair, 0, 2)
+		if start > pair.Start {
+			rest = append(rest, AddrPair{pair.Start, start - 1})
+		}
+		if start+size <= pair.End {
+			rest = append(rest, AddrPair{start + size, pair.End})
+		}
+		m.FreeMemObjects = append(m.FreeMemObjects[:i], append(rest, m.FreeMemObjects[i+1:]...)...)
+		return start, nil
+	}
+	return 0, InsufficientSpaceErr
+}
+
+//growRange takes size bytes starting at addr from the free list, if they are all free.
+func (m *Vmem) growRange(addr, size int) bool {
+	for i, pair := range m.FreeMemObjects {
+		if pair.Start == addr && pair.End >= addr+size-1 {
+			if pair.End == addr+size-1 {
+				m.FreeMemObjects = append(m.FreeMemObjects[:i], m.FreeMemObjects[i+1:]...)
+			} else {
+				m.FreeMemObjects[i].Start = addr + size
+			}
+			return true
+		}
+	}
+	return false
+}
+
+//freeRange returns a range to the free list, merging it with its neighbours.
+func (m *Vmem) freeRange(start, size int) {
+	if size <= 0 {
+		return
+	}
+	end := start + size - 1
+	var newlist []AddrPair
+	j := -1
+	for i, pair := range m.FreeMemObjects {
+		if pair.End+1 < start {
+			newlist = append(newlist, pair)
+		} else if end+1 < pair.Start {
+			j = i
+			break
+		} else {
+			start = min(start, pair.Start)
+			end = max(end, pair.End)
+
+		}
+	}
+	newlist = append(newlist, AddrPair{start, end})
+	if j != -1 {
+		newlist = append(newlist, m.FreeMemObjects[j:]...)
+	}
+	m.FreeMemObjects = newlist
+}
+
+//----------------------------------------------------------------//
+//                          Size classes                          //
+//----------------------------------------------------------------//
+
+//sizeClass returns the size class for an allocation, or 0 if it is served from the free list.
+func (m *Vmem) sizeClass(size int) int {
+	if !m.sizeClasses {
+		return 0
+	}
+	class := minClassSize
+	for class < size {
+		class *= 2
+	}
+	if class > m.PAGE_BYTESIZE/4 || m.PAGE_BYTESIZE%class != 0 {
+		return 0
+	}
+	return class
+}
+
+func (m *Vmem) allocObject(class int) (int, error) {
+	var s *slab
+	for _, candidate := range m.classSlabs[class] {
+		if len(candidate.free) > 0 {
+			s = candidate
+			break
+		}
+	}
+	if s == nil {
+		start, err := m.allocRange(m.PAGE_BYTESIZE, m.PAGE_BYTESIZE, 0)
+		if err != nil {
+			return 0, err
+		}
+		s = &slab{start: start, classSize: class}
+		//the free list is used as a stack, so objects are handed out in address order
+		for addr := start + m.PAGE_BYTESIZE - class; addr >= start; addr -= class {
+			s.free = append(s.free, addr)
+		}
+		m.slabs[start] = s
+		m.classSlabs[class] = append(m.classSlabs[class], s)
+	}
+	addr := s.free[len(s.free)-1]
+	s.free = s.free[:len(s.free)-1]
+	s.used++
+	return addr, nil
+}
+
+//freeObject puts the object back in its slab, and gives the page back to the free list once the slab is empty.
+func (m *Vmem) freeObject(s *slab, addr int) {
+	s.free = append(s.free, addr)
+	s.used--
+	if s.used > 0 {
+		return
+	}
+	delete(m.slabs, s.start)
+	slabs := m.classSlabs[s.classSize]
+	for i := range slabs {
+		if slabs[i] == s {
+			m.classSlabs[s.classSize] = append(slabs[:i], slabs[i+1:]...)
+			break
+		}
+	}
+	m.freeRange(s.start, m.PAGE_BYTESIZE)
+}
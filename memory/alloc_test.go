@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestMallocAligned(t *testing.T) {
+	mem := NewVmem(4096, 128)
+	mem.Malloc(3)
+	addr, err := mem.MallocAligned(100, 64)
+	assert.Nil(t, err)
+	assert.Equal(t, 64, addr)
+	//the gap in front of the aligned allocation stays free
+	addr, err = mem.Malloc(50)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, addr)
+	_, err = mem.MallocAligned(10, 3)
+	assert.NotNil(t, err)
+}
+
+func TestDoubleAndInvalidFree(t *testing.T) {
+	mem := NewVmem(4096, 128)
+	addr, _ := mem.Malloc(100)
+	assert.Equal(t, InvalidFreeErr, mem.Free(addr+1))
+	assert.Nil(t, mem.Free(addr))
+	assert.Equal(t, DoubleFreeErr, mem.Free(addr))
+	_, err := mem.Realloc(addr, 10)
+	assert.Equal(t, DoubleFreeErr, err)
+	//handing the address out again makes it valid to free once more
+	addr, _ = mem.Malloc(10)
+	assert.Nil(t, mem.Free(addr))
+	_, err = mem.Malloc(0)
+	assert.Equal(t, InvalidSizeErr, err)
+}
+
+func TestExhaustedRangesAreRemoved(t *testing.T) {
+	mem := NewVmem(256, 128)
+	mem.Malloc(100)
+	mem.Malloc(156)
+	assert.Len(t, mem.FreeMemObjects, 0)
+	_, err := mem.Malloc(1)
+	assert.Equal(t, InsufficientSpaceErr, err)
+}
+
+func TestRealloc(t *testing.T) {
+	mem := NewVmem(4096, 128)
+	a, _ := mem.Malloc(10)
+	mem.Stack[a] = 42
+	//grows in place into the free space behind it
+	b, err := mem.Realloc(a, 20)
+	assert.Nil(t, err)
+	assert.Equal(t, a, b)
+	mem.Malloc(10)
+	//has to move now
+	c, err := mem.Realloc(b, 40)
+	assert.Nil(t, err)
+	assert.NotEqual(t, b, c)
+	assert.Equal(t, byte(42), mem.Stack[c])
+	//shrinking gives back the tail
+	d, err := mem.Realloc(c, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, c, d)
+	assert.Equal(t, 15, mem.MemStats().UsedBytes)
+}
+
+func TestSizeClasses(t *testing.T) {
+	mem := NewVmem(4096, 128)
+	mem.SetSizeClasses(true)
+	a, _ := mem.Malloc(100)
+	b, _ := mem.Malloc(5)
+	c, _ := mem.Malloc(7)
+	d, _ := mem.Malloc(20)
+	assert.Equal(t, 0, a)
+	//small objects of one class share a page, separate from other classes
+	assert.Equal(t, 128, b)
+	assert.Equal(t, 136, c)
+	assert.Equal(t, 256, d)
+	stats := mem.MemStats()
+	assert.Equal(t, 4, stats.Allocations)
+	assert.Equal(t, 128-12+128-20, stats.SlackBytes)
+
+	e, _ := mem.Realloc(b, 8)
+	assert.Equal(t, b, e)
+	assert.Nil(t, mem.Free(e))
+	assert.Nil(t, mem.Free(c))
+	//the empty slab goes back to the free list
+	assert.Equal(t, 128-20, mem.MemStats().SlackBytes)
+}
+
+func TestAllocatorRandomSequences(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+		mem := NewVmem(16384, 256)
+		mem.SetSizeClasses(seed%2 == 0)
+		live := make(map[int]int)
+		for step := 0; step < 500; step++ {
+			switch op := rnd.Intn(10); {
+			case op < 5:
+				size := 1 + rnd.Intn(600)
+				align := 1 << uint(rnd.Intn(7))
+				addr, err := mem.MallocAligned(size, align)
+				if err != nil {
+					assert.Equal(t, InsufficientSpaceErr, err)
+					continue
+				}
+				assert.Equal(t, 0, addr%align)
+				live[addr] = size
+				fill(mem, addr, size)
+			case op < 8 && len(live) > 0:
+				addr := pick(rnd, live)
+				assert.Nil(t, mem.Free(addr))
+				assert.Equal(t, DoubleFreeErr, mem.Free(addr))
+				delete(live, addr)
+			case len(live) > 0:
+				addr := pick(rnd, live)
+				size := 1 + rnd.Intn(600)
+				newAddr, err := mem.Realloc(addr, size)
+				if err != nil {
+					assert.Equal(t, InsufficientSpaceErr, err)
+					continue
+				}
+				//the contents that fit survive the move
+				for i := 0; i < min(size, live[addr]); i++ {
+					assert.Equal(t, byte(addr+i), mem.Stack[newAddr+i])
+				}
+				delete(live, addr)
+				live[newAddr] = size
+				fill(mem, newAddr, size)
+			}
+			checkAllocations(t, mem, live)
+		}
+		for addr := range live {
+			assert.Nil(t, mem.Free(addr))
+		}
+		assert.Equal(t, []AddrPair{{0, 16383}}, mem.FreeMemObjects)
+		assert.Equal(t, MemStats{TotalBytes: 16384, FreeBytes: 16384, LargestFree: 16384}, mem.MemStats())
+	}
+}
+
+//fill writes a pattern that tells which allocation a byte belongs to.
+func fill(mem *Vmem, addr, size int) {
+	for i := 0; i < size; i++ {
+		mem.Stack[addr+i] = byte(addr + i)
+	}
+}
+
+func pick(rnd *rand.Rand, live map[int]int) int {
+	addrs := make([]int, 0, len(live))
+	for addr := range live {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+	return addrs[rnd.Intn(len(addrs))]
+}
+
+func checkAllocations(t *testing.T, mem *Vmem, live map[int]int) {
+	addrs := make([]int, 0, len(live))
+	used := 0
+	for addr, size := range live {
+		addrs = append(addrs, addr)
+		used += size
+	}
+	sort.Ints(addrs)
+	for i, addr := range addrs {
+		assert.True(t, addr >= 0 && addr+live[addr] <= mem.Size())
+		if i > 0 {
+			assert.True(t, addrs[i-1]+live[addrs[i-1]] <= addr, "allocations overlap")
+		}
+		for _, pair := range mem.FreeMemObjects {
+			assert.True(t, pair.End < addr || addr+live[addr]-1 < pair.Start, "allocation overlaps free memory")
+		}
+	}
+	stats := mem.MemStats()
+	assert.Equal(t, len(live), stats.Allocations)
+	assert.Equal(t, used, stats.UsedBytes)
+	assert.Equal(t, stats.TotalBytes, stats.UsedBytes+stats.FreeBytes+stats.SlackBytes)
+	assert.True(t, stats.SlackBytes >= 0)
+	assert.True(t, stats.Fragmentation >= 0 && stats.Fragmentation < 1)
+}
+
+func TestPlacementFlags(t *testing.T) {
+	mem := NewVmem(4096, 128)
+	a, _ := mem.Malloc(10)
+	b, err := mem.Malloc(10, PageAligned)
+	assert.Nil(t, err)
+	assert.Equal(t, 128, b)
+	c, err := mem.Malloc(130, PageIsolated)
+	assert.Nil(t, err)
+	assert.Equal(t, 256, c)
+	//the padding up to the next page is not handed out
+	d, _ := mem.Malloc(200)
+	assert.Equal(t, 512, d)
+	assert.Equal(t, 126, mem.MemStats().SlackBytes)
+	e, err := mem.Malloc(10, ColocatedWith(a))
+	assert.Nil(t, err)
+	assert.Equal(t, 10, e)
+	f, err := mem.Malloc(100, ColocatedWith(d))
+	assert.Nil(t, err)
+	assert.Equal(t, 712, f)
+	_, err = mem.Malloc(10, ColocatedWith(4000))
+	assert.Equal(t, InvalidColocationErr, err)
+	_, err = mem.Malloc(10, WriteUpdate)
+	assert.Equal(t, UnsupportedFlagErr, err)
+	//growing within the padding stays in place, and freeing gives back the padding too
+	g, _ := mem.Realloc(c, 250)
+	assert.Equal(t, c, g)
+	assert.Nil(t, mem.Free(g))
+	assert.Equal(t, 0, mem.MemStats().SlackBytes)
+	assert.Equal(t, []AddrPair{{0, 9}, {10, 19}, {128, 137}, {512, 711}, {712, 811}}, mem.Allocations())
+}
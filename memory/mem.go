@@ -26,8 +26,12 @@ type VirtualMemory interface {
 	SetRights(addr int, access byte)
 	SetRightsList(addr []int, access byte)
 	GetPageAddr(addr int) int
-	Malloc(sizeInBytes int) (int, error)
+	Malloc(sizeInBytes int, flags ...AllocFlag) (int, error)
+	MallocAligned(sizeInBytes, align int) (int, error)
+	Realloc(pointer, sizeInBytes int) (int, error)
 	Free(pointer int) error
+	MemStats() MemStats
+	Allocations() []AddrPair
 	GetPageSize() int
 	Size() int
 	AccessRightsDisabled(b bool)
@@ -40,11 +44,16 @@ type Vmem struct {
 	PAGE_BYTESIZE  int
 	FreeMemObjects []AddrPair
 	mallocHistory  map[int]int //maps address to length
+	reserved       map[int]int //maps address to the bytes reserved for it, where more than its length
+	freed          map[int]bool //addresses freed and not handed out again, for detecting double frees
+	sizeClasses    bool
+	slabs          map[int]*slab //maps page address to the slab occupying the page
+	classSlabs     map[int][]*slab //maps size class to its slabs
+	allocLock      *sync.Mutex
 	arDisabled     bool
 	faultListeners []FaultListener
 	accessLock		*sync.Mutex
-
-	mutex          *sync.Mutex
+	pageLocks      map[int]*sync.Mutex //guarded by accessLock. Faults on different pages can be handled concurrently.
 }
 
 func (m *Vmem) PrivilegedRead(addr, length int) []byte {
@@ -79,50 +88,10 @@ func (m *Vmem) GetPageSize() int {
 	return m.PAGE_BYTESIZE
 }
 
-func (m *Vmem) Free(pointer int) error {
-	size := m.mallocHistory[pointer]
-	if size == 0 {
-		return errors.New("invalid reference: no corresponding malloc found")
-	}
-	start := pointer
-	end := start + size - 1
-	var newlist []AddrPair
-	j := -1
-	for i, pair := range m.FreeMemObjects {
-		if pair.End+1 < start {
-			newlist = append(newlist, pair)
-		} else if end+1 < pair.Start {
-			j = i
-			break
-		} else {
-			start = min(start, pair.Start)
-			end = max(end, pair.End)
-
-		}
-	}
-	newlist = append(newlist, AddrPair{start, end})
-	if j != -1 {
-		newlist = append(newlist, m.FreeMemObjects[j:]...)
-	}
-	m.FreeMemObjects = newlist
-	return nil
-}
-
 type AddrPair struct {
 	Start, End int
 }
 
-func (m *Vmem) Malloc(sizeInBytes int) (int, error) {
-	for i, pair := range m.FreeMemObjects {
-		if pair.End-pair.Start+1 >= sizeInBytes {
-			m.FreeMemObjects[i] = AddrPair{pair.Start + sizeInBytes, pair.End}
-			m.mallocHistory[pair.Start] = sizeInBytes
-			return pair.Start, nil
-		}
-	}
-	return 0, errors.New("insufficient space")
-}
-
 func NewVmem(memSize int, pageByteSize int) *Vmem {
 	m := new(Vmem)
 	m.Stack = make([]byte, max(memSize, pageByteSize))
@@ -131,16 +100,22 @@ func NewVmem(memSize int, pageByteSize int) *Vmem {
 	m.FreeMemObjects = make([]AddrPair, 1)
 	m.FreeMemObjects[0] = AddrPair{0, max(memSize, pageByteSize) - 1}
 	m.mallocHistory = make(map[int]int)
+	m.reserved = make(map[int]int)
+	m.freed = make(map[int]bool)
+	m.slabs = make(map[int]*slab)
+	m.classSlabs = make(map[int][]*slab)
+	m.allocLock = new(sync.Mutex)
 	m.arDisabled = false
 	m.faultListeners = make([]FaultListener, 0)
-	m.mutex = new(sync.Mutex)
 	m.accessLock = new(sync.Mutex)
+	m.pageLocks = make(map[int]*sync.Mutex)
 	return m
 }
 
 func (m *Vmem) Read(addr int) (byte, error) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+	pages := []int{m.GetPageAddr(addr)}
+	m.lockPages(pages)
+	defer m.unlockPages(pages)
 	//If access rights disabled, just read no matter what
 	if m.arDisabled {
 		return m.Stack[addr], nil
@@ -160,8 +135,6 @@ func (m *Vmem) Read(addr int) (byte, error) {
 }
 
 func (m *Vmem) ReadBytes(addr, length int) ([]byte, error) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
 	firstPageAddr := m.GetPageAddr(addr)
 	lastPageAddr := m.GetPageAddr(addr+ length)
 	result := make([]byte, length)
@@ -170,6 +143,12 @@ func (m *Vmem) ReadBytes(addr, length int) ([]byte, error) {
 	for tempAddr := firstPageAddr; tempAddr <= lastPageAddr; tempAddr = tempAddr + m.GetPageSize() {
 		addrList = append(addrList, tempAddr)
 	}
+	m.lockPages(addrList)
+	defer m.unlockPages(addrList)
+	if m.arDisabled {
+		copy(result, m.Stack[addr:addr+length])
+		return result, nil
+	}
 	access := m.GetRightsList(addrList)
 Loop:
 	for i := range access {
@@ -188,8 +167,9 @@ Loop:
 }
 
 func (m *Vmem) Write(addr int, val byte) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+	pages := []int{m.GetPageAddr(addr)}
+	m.lockPages(pages)
+	defer m.unlockPages(pages)
 	if m.arDisabled {
 		m.Stack[addr] = val
 		return nil
@@ -212,8 +192,6 @@ func (m *Vmem) Write(addr int, val byte) error {
 }
 
 func (m *Vmem) WriteBytes(addr int, val []byte) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
 	length := len(val)
 	firstPageAddr := m.GetPageAddr(addr)
 	lastPageAddr := m.GetPageAddr(addr + length-1)
@@ -221,6 +199,12 @@ func (m *Vmem) WriteBytes(addr int, val []byte) error {
 	for tempAddr := firstPageAddr; tempAddr <= lastPageAddr; tempAddr = tempAddr + m.GetPageSize() {
 		addrList = append(addrList, tempAddr)
 	}
+	m.lockPages(addrList)
+	defer m.unlockPages(addrList)
+	if m.arDisabled {
+		copy(m.Stack[addr:addr+length], val)
+		return nil
+	}
 	access := m.GetRightsList(addrList)
 Loop:
 	for i := range access {
@@ -269,6 +253,31 @@ func (m *Vmem) SetRightsList(addrList []int, access byte) {
 	}
 }
 
+//lockPages locks the given page addresses, which must be in ascending order.
+//Accesses to the same page are serialised, including any fault handling, while other pages stay available.
+func (m *Vmem) lockPages(pageAddrs []int) {
+	locks := make([]*sync.Mutex, len(pageAddrs))
+	m.accessLock.Lock()
+	for i, addr := range pageAddrs {
+		if m.pageLocks[addr] == nil {
+			m.pageLocks[addr] = new(sync.Mutex)
+		}
+		locks[i] = m.pageLocks[addr]
+	}
+	m.accessLock.Unlock()
+	for _, l := range locks {
+		l.Lock()
+	}
+}
+
+func (m *Vmem) unlockPages(pageAddrs []int) {
+	m.accessLock.Lock()
+	defer m.accessLock.Unlock()
+	for i := len(pageAddrs) - 1; i >= 0; i-- {
+		m.pageLocks[pageAddrs[i]].Unlock()
+	}
+}
+
 func (m *Vmem) GetPageAddr(addr int) int {
 	return addr - addr%m.PAGE_BYTESIZE
 }
@@ -87,3 +87,25 @@ func TestFreeMemory(t *testing.T) {
 }
 */
 
+
+func TestConcurrentFaultsOnDifferentPages(t *testing.T) {
+	mem := NewVmem(4096, 128)
+	page0, page1 := make(chan bool), make(chan bool)
+	mem.AddFaultListener(func(addr int, length int, faultType byte, accessType string, value []byte) error {
+		if mem.GetPageAddr(addr) == 0 {
+			//the fault on page 0 stays in progress until page 1 has been faulted in
+			<-page1
+		}
+		mem.SetRights(addr, READ_ONLY)
+		return nil
+	})
+	go func() {
+		_, err := mem.Read(5)
+		assert.Nil(t, err)
+		page0 <- true
+	}()
+	_, err := mem.Read(130)
+	assert.Nil(t, err)
+	page1 <- true
+	assert.True(t, <-page0)
+}
@@ -0,0 +1,267 @@
+package multiview
+
+import (
+	"DSM-project/memory"
+	"DSM-project/network"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var InvalidGranularityErr = errors.New("granularity must be a positive multiple of the element size")
+var InvalidElementSizeErr = errors.New("element size must divide the page size")
+var NoSuchArrayErr = errors.New("no array starts at this address")
+var NoFreeViewErr = errors.New("no free view left for a minipage")
+var NoSuchNameErr = errors.New("no array has this name")
+var NameTakenErr = errors.New("another array has this name")
+
+//ArrayHandle maps the element indices of an array allocation to view addresses.
+type ArrayHandle struct {
+	Length      int //number of elements
+	ElementSize int
+	Granularity int //bytes per minipage
+	offsets     []int
+	addrs       []int
+}
+
+//Addr returns the view address of element i.
+func (a *ArrayHandle) Addr(i int) int {
+	b := i * a.ElementSize
+	c := sort.Search(len(a.offsets), func(j int) bool { return a.offsets[j] > b }) - 1
+	return a.addrs[c] + b - a.offsets[c]
+}
+
+//Base returns the view address of the first element. It identifies the array for LookupArray and Free.
+func (a *ArrayHandle) Base() int {
+	return a.addrs[0]
+}
+
+//Size returns the size of the array in bytes.
+func (a *ArrayHandle) Size() int {
+	return a.Length * a.ElementSize
+}
+
+//array is the managers record of an array allocation.
+type array struct {
+	ptr         int //physical address
+	length      int
+	elementSize int
+	granularity int
+	name        string //empty if the array isn't named
+	offsets     []int
+	vpages      []int
+}
+
+//MallocArray allocates an array with the given number of elements, split in minipages of granularity bytes.
+//With a granularity of 0 the manager picks one from how earlier arrays were written.
+func (m *Multiview) MallocArray(length, elementSize, granularity int) (*ArrayHandle, error) {
+	return m.MallocNamedArray("", length, elementSize, granularity)
+}
+
+//MallocNamedArray is MallocArray for an array that other hosts find by its name with LookupNamedArray,
+//so they need not know its address.
+func (m *Multiview) MallocNamedArray(name string, length, elementSize, granularity int) (*ArrayHandle, error) {
+	if elementSize <= 0 || m.GetPageSize()%elementSize != 0 {
+		return nil, InvalidElementSizeErr
+	}
+	if granularity < 0 || granularity%elementSize != 0 {
+		return nil, InvalidGranularityErr
+	}
+	return m.arrayRequest(network.MultiviewMessage{
+		Type:          ARRAY_MALLOC_REQUEST,
+		Minipage_size: length * elementSize,
+		IntArr:        []int{elementSize, granularity},
+		Data:          []byte(name),
+	})
+}
+
+//LookupArray returns the handle of the array that starts at addr, so hosts other than the allocating one can use it.
+func (m *Multiview) LookupArray(addr int) (*ArrayHandle, error) {
+	return m.arrayRequest(network.MultiviewMessage{
+		Type:       ARRAY_LOOKUP_REQUEST,
+		Fault_addr: addr,
+	})
+}
+
+//LookupNamedArray returns the handle of the array allocated with the given name.
+func (m *Multiview) LookupNamedArray(name string) (*ArrayHandle, error) {
+	return m.arrayRequest(network.MultiviewMessage{
+		Type: ARRAY_LOOKUP_REQUEST,
+		Data: []byte(name),
+	})
+}
+
+func (m *Multiview) arrayRequest(msg network.MultiviewMessage) (*ArrayHandle, error) {
+	c := make(chan string)
+	i := m.newEvent(c)
+	msg.From = m.Id
+	msg.To = m.managerId()
+	msg.EventId = i
+	m.sendToManager(msg)
+	s := <-c
+	m.forget(i)
+	if _, err := strconv.Atoi(strings.Split(s, ",")[0]); err != nil {
+		return nil, errors.New(s)
+	}
+	ints := StringOfIntsToIntArray(s)
+	h := &ArrayHandle{
+		Length:      ints[0],
+		ElementSize: ints[1],
+		Granularity: ints[2],
+	}
+	for j := 3; j+1 < len(ints); j += 2 {
+		h.offsets = append(h.offsets, ints[j])
+		h.addrs = append(h.addrs, ints[j+1])
+	}
+	return h, nil
+}
+
+func (m *Manager) handleArrayAlloc(message network.MultiviewMessage) {
+	m.Lock()
+	defer func() {
+		m.Unlock()
+		m.sendArrayReply(message)
+	}()
+	size := message.Minipage_size
+	elementSize, granularity := message.IntArr[0], message.IntArr[1]
+	name := string(message.Data)
+	message.IntArr, message.Data = nil, nil
+	if _, taken := m.names[name]; taken && name != "" {
+		message.Err = NameTakenErr.Error()
+		return
+	}
+	if granularity == 0 {
+		granularity = m.chooseGranularity(elementSize)
+	}
+	ptr, err := m.vmMalloc(size, memory.PageAligned)
+	if err != nil {
+		message.Err = err.Error()
+		return
+	}
+	a := &array{
+		ptr:         ptr,
+		length:      size / elementSize,
+		elementSize: elementSize,
+		granularity: granularity,
+		name:        name,
+	}
+	//cut the array at every granularity bytes and at every page border
+	pageSize := m.vm.GetPageSize()
+	for offset := 0; offset < size; {
+		length := Min(granularity-offset%granularity, size-offset)
+		length = Min(length, pageSize-(ptr+offset)%pageSize)
+		vpage, err := m.findView((ptr+offset)/pageSize, 1)
+		if err != nil {
+			for _, vpage := range a.vpages {
+				m.dropMinipage(vpage)
+			}
+			m.vmFree(ptr)
+			message.Err = err.Error()
+			return
+		}
+		m.insertMinipages(vpage, []minipage{{(ptr + offset) % pageSize, length}}, message.From, false)
+		a.offsets = append(a.offsets, offset)
+		a.vpages = append(a.vpages, vpage)
+		offset += length
+	}
+	message.IntArr = m.arrayToInts(a)
+	m.addArray(message.IntArr[4], a)
+	m.replicate(opArray, arrayEntry(a)...)
+}
+
+func (m *Manager) handleArrayLookup(message network.MultiviewMessage) {
+	m.Lock()
+	if len(message.Data) > 0 {
+		if addr, ok := m.names[string(message.Data)]; ok {
+			message.IntArr = m.arrayToInts(m.arrays[addr])
+		} else {
+			message.Err = NoSuchNameErr.Error()
+		}
+		message.Data = nil
+	} else if a, ok := m.arrays[message.Fault_addr]; ok {
+		message.IntArr = m.arrayToInts(a)
+	} else {
+		message.Err = NoSuchArrayErr.Error()
+	}
+	m.Unlock()
+	m.sendArrayReply(message)
+}
+
+func (m *Manager) sendArrayReply(message network.MultiviewMessage) {
+	message.Minipage_size = 0
+	message.Type = ARRAY_MALLOC_REPLY
+	message.To = message.From
+	message.From = m.myId
+	m.conn.Send(message)
+	m.logMessage(message)
+}
+
+//arrayToInts encodes an array as its length, element size and granularity, followed by the offset and view address of each minipage.
+func (m *Manager) arrayToInts(a *array) []int {
+	res := []int{a.length, a.elementSize, a.granularity}
+	for i, vpage := range a.vpages {
+		mp, _ := m.getMinipage(vpage)
+		res = append(res, a.offsets[i], vpage*m.vm.GetPageSize()+mp.offset)
+	}
+	return res
+}
+
+//addArray records the array at the view address of its first element. The caller holds the manager lock.
+func (m *Manager) addArray(addr int, a *array) {
+	m.arrays[addr] = a
+	if a.name != "" {
+		m.names[a.name] = addr
+	}
+}
+
+//removeArray forgets the array at the view address, and returns it. The caller holds the manager lock.
+func (m *Manager) removeArray(addr int) (*array, bool) {
+	a, ok := m.arrays[addr]
+	if ok {
+		delete(m.arrays, addr)
+		delete(m.names, a.name)
+	}
+	return a, ok
+}
+
+//freeArray removes the minipages of an array and frees its memory.
+func (m *Manager) freeArray(a *array) {
+	for _, vpage := range a.vpages {
+		m.removeMinipage(vpage)
+	}
+	m.vmFree(a.ptr)
+}
+
+//chooseGranularity picks the minipage size for an array from the last writers of the minipages of the earlier arrays.
+//Neighbouring minipages last written by the same host could have shared a minipage, so the average length of such runs is used.
+//Without any history every element gets its own minipage.
+func (m *Manager) chooseGranularity(elementSize int) int {
+	runs, bytes := 0, 0
+	for _, a := range m.arrays {
+		var last byte
+		inRun := false
+		for _, vpage := range a.vpages {
+			writer, written := m.getWriter(vpage)
+			if !written {
+				inRun = false
+				continue
+			}
+			if !inRun || writer != last {
+				runs++
+			}
+			mp, _ := m.getMinipage(vpage)
+			bytes += mp.length
+			last, inRun = writer, true
+		}
+	}
+	if runs == 0 {
+		return elementSize
+	}
+	g := bytes / runs
+	g = Min(g-g%elementSize, m.vm.GetPageSize())
+	if g < elementSize {
+		return elementSize
+	}
+	return g
+}
@@ -0,0 +1,64 @@
+package multiview
+
+import (
+	"DSM-project/memory"
+	"encoding/binary"
+	"errors"
+)
+
+//FetchAndAdd adds delta to the int64 at addr, and returns the value it had.
+//An atomic operation is executed by the host that has write access to the minipage, which it gets like any writer,
+//and the minipage isn't given away while the operation is in progress. So no lock is needed, and as the other
+//hosts get the minipage from the host afterwards, they see the result. The int64 is stored little-endian.
+//The int64 must be in a single minipage, and atomic operations aren't supported on write-update allocations.
+func (m *Multiview) FetchAndAdd(addr int, delta int64) (int64, error) {
+	return m.atomic(addr, func(old int64) int64 {
+		return old + delta
+	})
+}
+
+//CompareAndSwap sets the int64 at addr to val if it is old, and tells if it did.
+func (m *Multiview) CompareAndSwap(addr int, old, val int64) (bool, error) {
+	res, err := m.atomic(addr, func(cur int64) int64 {
+		if cur == old {
+			return val
+		}
+		return cur
+	})
+	return err == nil && res == old, err
+}
+
+//Swap sets the int64 at addr to val, and returns the value it had.
+func (m *Multiview) Swap(addr int, val int64) (int64, error) {
+	return m.atomic(addr, func(int64) int64 {
+		return val
+	})
+}
+
+//atomic applies f to the int64 at addr, and returns the value before.
+func (m *Multiview) atomic(addr int, f func(old int64) int64) (int64, error) {
+	vpage := m.mem.getVPageNr(addr)
+	if addr < 0 || vpage >= len(m.mem.accessMap) {
+		return 0, errors.New("atomic operation out of bounds")
+	}
+	if m.mem.getVPageNr(addr+7) != vpage {
+		return 0, errors.New("atomic operations need an int64 within a single minipage")
+	}
+	for {
+		if m.getInAccessMap(vpage) != memory.READ_WRITE {
+			m.onFault(addr, 8, 1, "WRITE", nil)
+		}
+		m.atomicLock.Lock()
+		//the minipage may have been taken away again before the lock was taken
+		if m.getInAccessMap(vpage) == memory.READ_WRITE {
+			physAddr := m.mem.translateAddr(addr)
+			old := int64(binary.LittleEndian.Uint64(m.mem.vm.PrivilegedRead(physAddr, 8)))
+			b := make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, uint64(f(old)))
+			m.mem.vm.PrivilegedWrite(physAddr, b)
+			m.atomicLock.Unlock()
+			return old, nil
+		}
+		m.atomicLock.Unlock()
+	}
+}
@@ -1,6 +1,7 @@
 package multiview
 
 import (
+	"DSM-project/dsm-api"
 	"DSM-project/memory"
 	"DSM-project/network"
 	"DSM-project/treadmarks"
@@ -12,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -36,6 +38,22 @@ const (
 	BARRIER_RESPONSE      = "barr_resp"
 	MULTI_MALLOC_REQUEST  = "MMR"
 	MULTI_MALLOC_REPLY    = "MMRPL"
+	ARRAY_MALLOC_REQUEST  = "AMR"
+	ARRAY_MALLOC_REPLY    = "AMRPL"
+	ARRAY_LOOKUP_REQUEST  = "ALR"
+	UPDATE_REQUEST        = "UPD_REQ"
+	UPDATE_REPLY          = "UPD_REPL"
+	UPDATE                = "UPD"
+	UPDATE_ACK            = "UPD_ACK"
+	REPLICATE             = "REPL"
+	STANDBY_REGISTER      = "SB_REG"
+	STANDBY_LEAVE         = "SB_LEAVE"
+	NEW_MANAGER           = "NEW_MGR"
+	REJOIN                = "REJOIN"
+	READ_LOCK_REQUEST     = "rlock_acq_req"
+	READ_LOCK_RELEASE     = "rlock_rel"
+	LOCK_TRY_REQUEST      = "lock_try_req"
+	LOCK_CANCEL           = "lock_cancel"
 )
 
 type Multiview struct {
@@ -45,12 +63,23 @@ type Multiview struct {
 	chanMap          map[int]chan string
 	sequenceNumber   int
 	hasLock          map[int]bool
+	hasReadLock      map[int]bool
 	csvLogger        *network.CSVStructLogger
-	shouldLogNetwork bool
-	messagesSent     []int
+	messagesSent     messageLog //see SetShouldLogNetwork
 	manager          *Manager
+	compress         bool
+	updates          map[int]*ownedMinipage //The write-update minipages this host owns, by vpage.
+	updatesLock      *sync.Mutex
+	managerHost      byte                             //The host the manager runs on.
+	pending          map[int]network.MultiviewMessage //Requests to the manager that haven't been answered, by event id.
+	pendingLock      *sync.Mutex
+	standby          *Manager
+	logger           *network.Logger
+	atomicLock       *sync.Mutex //held while an atomic operation is executed, so the minipage isn't given away meanwhile
 }
 
+var _ dsm_api.Prefetcher = new(Multiview)
+
 type hostMem struct {
 	vm        memory.VirtualMemory
 	accessMap []byte
@@ -75,6 +104,13 @@ func NewMultiView() *Multiview {
 	m.sequenceNumber = 0
 	m.chanMap = make(map[int]chan string)
 	m.hasLock = make(map[int]bool)
+	m.hasReadLock = make(map[int]bool)
+	m.updates = make(map[int]*ownedMinipage)
+	m.updatesLock = new(sync.Mutex)
+	m.pending = make(map[int]network.MultiviewMessage)
+	m.pendingLock = new(sync.Mutex)
+	m.atomicLock = new(sync.Mutex)
+	m.SetLogger(network.DefaultLogger())
 	return m
 }
 
@@ -90,28 +126,15 @@ func NewHostMem(virtualMemory memory.VirtualMemory) *hostMem {
 }
 
 func (m *Multiview) Leave() {
-	if m.shouldLogNetwork {
-		fmt.Println("Number of messages sent by host", m.Id, ":")
-		fmt.Println("READ_REQUEST", m.messagesSent[0])
-		fmt.Println("WRITE_REQUEST", m.messagesSent[1])
-		fmt.Println("READ_REPLY", m.messagesSent[2])
-		fmt.Println("WRITE_REPLY", m.messagesSent[3])
-		fmt.Println("INVALIDATE_REPLY", m.messagesSent[4])
-		fmt.Println("INVALIDATE_REQUEST", m.messagesSent[5])
-		fmt.Println("MALLOC_REQUEST", m.messagesSent[6])
-		fmt.Println("FREE_REQUEST", m.messagesSent[7])
-		fmt.Println("MALLOC_REPLY", m.messagesSent[8])
-		fmt.Println("FREE_REPLY", m.messagesSent[9])
-		fmt.Println("WELCOME_MESSAGE", m.messagesSent[10])
-		fmt.Println("READ_ACK", m.messagesSent[11])
-		fmt.Println("WRITE_ACK", m.messagesSent[12])
-		fmt.Println("LOCK_ACQUIRE_REQUEST", m.messagesSent[13])
-		fmt.Println("LOCK_ACQUIRE_RESPONSE", m.messagesSent[14])
-		fmt.Println("LOCK_RELEASE", m.messagesSent[15])
-		fmt.Println("BARRIER_REQUEST", m.messagesSent[16])
-		fmt.Println("BARRIER_RESPONSE", m.messagesSent[17])
-		fmt.Println("MULTI_MALLOC_REQUEST", m.messagesSent[18])
-		fmt.Println("MULTI_MALLOC_REPLY", m.messagesSent[19])
+	if m.messagesSent.isEnabled() {
+		logMessagesSent(m.logger, &m.messagesSent)
+		if client, ok := m.conn.(*network.P2PClient); ok {
+			raw, wire := client.BytesSent()
+			m.logger.Infof("message bytes: %v sent as %v bytes", raw, wire)
+		}
+	}
+	if m.standby != nil {
+		m.standby.leave()
 	}
 	if m.manager != nil {
 		m.manager.Shutdown()
@@ -120,33 +143,19 @@ func (m *Multiview) Leave() {
 }
 
 func (m *Multiview) Shutdown() {
-	if m.shouldLogNetwork {
-		fmt.Println("Number of messages sent by host", m.Id, ":")
-		fmt.Println("READ_REQUEST", m.messagesSent[0])
-		fmt.Println("WRITE_REQUEST", m.messagesSent[1])
-		fmt.Println("READ_REPLY", m.messagesSent[2])
-		fmt.Println("WRITE_REPLY", m.messagesSent[3])
-		fmt.Println("INVALIDATE_REPLY", m.messagesSent[4])
-		fmt.Println("INVALIDATE_REQUEST", m.messagesSent[5])
-		fmt.Println("MALLOC_REQUEST", m.messagesSent[6])
-		fmt.Println("FREE_REQUEST", m.messagesSent[7])
-		fmt.Println("MALLOC_REPLY", m.messagesSent[8])
-		fmt.Println("FREE_REPLY", m.messagesSent[9])
-		fmt.Println("WELCOME_MESSAGE", m.messagesSent[10])
-		fmt.Println("READ_ACK", m.messagesSent[11])
-		fmt.Println("WRITE_ACK", m.messagesSent[12])
-		fmt.Println("LOCK_ACQUIRE_REQUEST", m.messagesSent[13])
-		fmt.Println("LOCK_ACQUIRE_RESPONSE", m.messagesSent[14])
-		fmt.Println("LOCK_RELEASE", m.messagesSent[15])
-		fmt.Println("BARRIER_REQUEST", m.messagesSent[16])
-		fmt.Println("BARRIER_RESPONSE", m.messagesSent[17])
-		fmt.Println("MULTI_MALLOC_REQUEST", m.messagesSent[18])
-		fmt.Println("MULTI_MALLOC_REPLY", m.messagesSent[19])
+	if m.messagesSent.isEnabled() {
+		logMessagesSent(m.logger, &m.messagesSent)
+		if client, ok := m.conn.(*network.P2PClient); ok {
+			raw, wire := client.BytesSent()
+			m.logger.Infof("message bytes: %v sent as %v bytes", raw, wire)
+		}
+	}
+	if m.standby != nil {
+		m.standby.leave()
 	}
 	if m.manager != nil {
-		fmt.Println("BOOOM")
+		m.logger.Infof("shutting down the manager")
 		m.manager.Shutdown()
-		fmt.Println("BOOOM")
 	}
 	m.conn.Close()
 
@@ -166,10 +175,12 @@ func (m *Multiview) Join(memSize, pageByteSize int) error {
 		return m.messageHandler(msg, c)
 	}
 	client := network.NewP2PClient(handler)
+	client.SetCompression(m.compress)
+	client.SetLogger(m.logger)
 	err := m.StartAndConnect(memSize, pageByteSize, client)
 	panicOnErr(err)
 	<-c
-	log.Println("host joined network with id: ", m.Id)
+	m.logger.Infof("joined the network")
 	return err
 }
 
@@ -187,8 +198,13 @@ func (m *Multiview) Initialize(memSize, pageByteSize int, nrProcs int) error {
 	bm := treadmarks.NewBarrierManagerImp(nrProcs)
 	lm := treadmarks.NewLockManagerImp()
 	m.manager = NewUpdatedManager(vm, lm, bm)
-	m.manager.SetShouldLogNetwork(m.shouldLogNetwork)
-	m.manager.Connect("localhost:2000")
+	m.manager.nrProcs = nrProcs
+	m.manager.SetLogger(m.logger)
+	m.manager.SetShouldLogNetwork(m.messagesSent.isEnabled())
+	m.manager.SetCompression(m.compress)
+	if err := m.manager.Connect("localhost:2000"); err != nil {
+		return err
+	}
 	return m.Join(memSize, pageByteSize)
 }
 
@@ -224,11 +240,15 @@ func (t *Multiview) ReadInt64(addr int) int {
 	return int(result)
 }
 
+//WriteBytes faults in all minipages of the range that can't be written with one request, and then copies the range.
 func (t *Multiview) WriteBytes(addr int, val []byte) error {
-	var err error = nil
-	for b, val := range val {
-		err = t.Write(addr+b, val)
-	}
+	t.onFaults(t.missingVpages(addr, len(val), func(access byte) bool { return access != memory.READ_WRITE }), 1)
+	var err error
+	t.mem.forRange(addr, len(val), func(physAddr, start, n int) {
+		if e := t.mem.vm.PrivilegedWrite(physAddr, val[start:start+n]); e != nil {
+			err = e
+		}
+	})
 	return err
 }
 
@@ -255,52 +275,114 @@ func (m *Multiview) Lock(id int) {
 	if m.hasLock[id] {
 		return
 	}
-	c := make(chan string)
-	m.sequenceNumber++
-	i := m.sequenceNumber
-	m.chanMap[i] = c
+	m.requestLock(LOCK_ACQUIRE_REQUEST, id)
+	m.hasLock[id] = true
+}
+
+//AcquireReadLock acquires the lock in shared mode. Any number of hosts can hold a lock in shared mode at once,
+//as long as no host holds it with Lock.
+func (m *Multiview) AcquireReadLock(id int) {
+	if m.hasReadLock[id] {
+		return
+	}
+	m.requestLock(READ_LOCK_REQUEST, id)
+	m.hasReadLock[id] = true
+}
+
+//TryAcquireLock takes the lock if no other host holds it, and tells if it did. It doesn't wait for the lock.
+func (m *Multiview) TryAcquireLock(id int) bool {
+	if m.hasLock[id] {
+		return true
+	}
+	if m.requestLock(LOCK_TRY_REQUEST, id) != "ok" {
+		return false
+	}
+	m.hasLock[id] = true
+	return true
+}
+
+//AcquireLockTimeout waits at most d for the lock, and tells if it got it.
+//When it gives up, the request is cancelled at the manager, so the lock isn't held up by it.
+func (m *Multiview) AcquireLockTimeout(id int, d time.Duration) bool {
+	if m.hasLock[id] {
+		return true
+	}
+	c := make(chan string, 1)
+	i := m.newEvent(c)
 	msg := network.MultiviewMessage{
 		Type:    LOCK_ACQUIRE_REQUEST,
 		From:    m.Id,
-		To:      byte(0),
+		To:      m.managerId(),
 		Id:      id,
 		EventId: i,
 	}
-	m.conn.Send(msg)
-	m.logMessage(msg)
-	<-c
+	m.sendToManager(msg)
+	select {
+	case <-c:
+		m.forget(i)
+	case <-time.After(d):
+		if m.cancelLock(id, i) {
+			return false
+		}
+	}
 	m.hasLock[id] = true
-	m.chanMap[i] = nil
+	return true
+}
+
+//requestLock asks the manager for a lock, and waits for the answer.
+func (m *Multiview) requestLock(msgType string, id int) string {
+	c := make(chan string)
+	i := m.newEvent(c)
+	msg := network.MultiviewMessage{
+		Type:    msgType,
+		From:    m.Id,
+		To:      m.managerId(),
+		Id:      id,
+		EventId: i,
+	}
+	m.sendToManager(msg)
+	res := <-c
+	m.forget(i)
+	return res
 }
 
 func (m *Multiview) Release(id int) {
+	m.pushUpdates()
 	msg := network.MultiviewMessage{
 		Type: LOCK_RELEASE,
 		From: m.Id,
-		To:   byte(0),
+		To:   m.managerId(),
 		Id:   id,
 	}
 	m.hasLock[id] = false
-	m.conn.Send(msg)
-	m.logMessage(msg)
+	m.sendToManager(msg)
+}
+
+func (m *Multiview) ReleaseReadLock(id int) {
+	msg := network.MultiviewMessage{
+		Type: READ_LOCK_RELEASE,
+		From: m.Id,
+		To:   m.managerId(),
+		Id:   id,
+	}
+	m.hasReadLock[id] = false
+	m.sendToManager(msg)
 }
 
 func (m *Multiview) Barrier(id int) {
+	m.pushUpdates()
 	c := make(chan string)
-	m.sequenceNumber++
-	i := m.sequenceNumber
-	m.chanMap[i] = c
+	i := m.newEvent(c)
 	msg := network.MultiviewMessage{
 		Type:    BARRIER_REQUEST,
 		From:    m.Id,
-		To:      byte(0),
+		To:      m.managerId(),
 		Id:      id,
 		EventId: i,
 	}
-	m.conn.Send(msg)
-	m.logMessage(msg)
+	m.sendToManager(msg)
 	<-c
-	m.chanMap[i] = nil
+	m.forget(i)
 }
 
 func (m *hostMem) translateAddr(addr int) int {
@@ -311,6 +393,27 @@ func (m *hostMem) getVPageNr(addr int) int {
 	return addr / m.vm.GetPageSize()
 }
 
+//forRange calls f for each part of a range of view addresses that is contiguous in the privileged view.
+func (m *hostMem) forRange(addr, length int, f func(physAddr, start, n int)) {
+	for start := 0; start < length; {
+		physAddr := m.translateAddr(addr + start)
+		n := Min(length-start, m.vm.Size()-physAddr)
+		f(physAddr, start, n)
+		start += n
+	}
+}
+
+//missingVpages returns an address in each vpage of the range, for which the access rights are missing.
+func (m *Multiview) missingVpages(addr, length int, missing func(access byte) bool) []int {
+	addrs := make([]int, 0)
+	for a := addr; a < addr+length; a = (m.mem.getVPageNr(a) + 1) * m.GetPageSize() {
+		if missing(m.getInAccessMap(m.mem.getVPageNr(a))) {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
 func (m *Multiview) Read(addr int) (byte, error) {
 	if m.getInAccessMap(m.mem.getVPageNr(addr)) == memory.NO_ACCESS {
 		for _, l := range m.mem.faultListeners {
@@ -321,11 +424,29 @@ func (m *Multiview) Read(addr int) (byte, error) {
 	return res, nil
 }
 
-func (m *Multiview) ReadBytes(addr, length int) ([]byte, error) {
-	result := make([]byte, length)
-	for i := range result {
-		result[i], _ = m.Read(addr + i)
+//Prefetch fetches the missing minipages of the range with one request to the manager, like ReadBytes does.
+//It returns at once, and the channel receives nil when they have arrived.
+func (m *Multiview) Prefetch(addr int, length int) <-chan error {
+	result := make(chan error, 1)
+	if addr < 0 || length < 0 || addr+length > len(m.mem.accessMap)*m.GetPageSize() {
+		result <- errors.New("prefetch range out of bounds")
+		return result
 	}
+	vpages := m.missingVpages(addr, length, func(access byte) bool { return access == memory.NO_ACCESS })
+	go func() {
+		m.onFaults(vpages, 0)
+		result <- nil
+	}()
+	return result
+}
+
+//ReadBytes faults in all missing minipages of the range with one request, and then copies the range.
+func (m *Multiview) ReadBytes(addr, length int) ([]byte, error) {
+	m.onFaults(m.missingVpages(addr, length, func(access byte) bool { return access == memory.NO_ACCESS }), 0)
+	result := make([]byte, 0, length)
+	m.mem.forRange(addr, length, func(physAddr, start, n int) {
+		result = append(result, m.mem.vm.PrivilegedRead(physAddr, n)...)
+	})
 	return result, nil
 }
 
@@ -356,22 +477,20 @@ func (m *Multiview) Write(addr int, val byte) error {
 	return m.mem.vm.Write(m.mem.translateAddr(addr), val)
 }
 
-func (m *Multiview) Malloc(sizeInBytes int) (int, error) {
+func (m *Multiview) Malloc(sizeInBytes int, flags ...memory.AllocFlag) (int, error) {
 	c := make(chan string)
-	m.sequenceNumber++
-	i := m.sequenceNumber
-	m.chanMap[i] = c
+	i := m.newEvent(c)
 	msg := network.MultiviewMessage{
 		Type:          MALLOC_REQUEST,
 		From:          m.Id,
-		To:            byte(0),
+		To:            m.managerId(),
 		EventId:       i,
 		Minipage_size: sizeInBytes, //<- contains the size for the allocation!
+		IntArr:        memory.FlagsToInts(flags),
 	}
-	m.conn.Send(msg)
-	m.logMessage(msg)
+	m.sendToManager(msg)
 	s := <-c
-	m.chanMap[i] = nil
+	m.forget(i)
 	res, err := strconv.Atoi(s)
 	if err != nil {
 		return -1, errors.New(s)
@@ -381,40 +500,37 @@ func (m *Multiview) Malloc(sizeInBytes int) (int, error) {
 
 func (m *Multiview) MultiMalloc(sizes []int) ([]int, error) {
 	c := make(chan string)
-	m.sequenceNumber++
-	i := m.sequenceNumber
-	m.chanMap[i] = c
+	i := m.newEvent(c)
 	msg := network.MultiviewMessage{
 		Type:    MULTI_MALLOC_REQUEST,
 		From:    m.Id,
-		To:      byte(0),
+		To:      m.managerId(),
 		EventId: i,
 		IntArr:  sizes, //<- contains the sizes for the allocations
 	}
-	m.conn.Send(msg)
-	m.logMessage(msg)
+	m.sendToManager(msg)
 	s := <-c
-	m.chanMap[i] = nil
+	m.forget(i)
+	if _, err := strconv.Atoi(strings.Split(s, ",")[0]); err != nil {
+		return nil, errors.New(s)
+	}
 	return StringOfIntsToIntArray(s), nil
 }
 
 func (m *Multiview) Free(pointer, length int) error {
 	c := make(chan string)
-	m.sequenceNumber++
-	i := m.sequenceNumber
-	m.chanMap[i] = c
+	i := m.newEvent(c)
 	msg := network.MultiviewMessage{
 		Type:          FREE_REQUEST,
 		From:          m.Id,
-		To:            byte(0),
+		To:            m.managerId(),
 		EventId:       i,
 		Fault_addr:    pointer,
 		Minipage_size: length, //<- length here
 	}
-	m.conn.Send(msg)
-	m.logMessage(msg)
+	m.sendToManager(msg)
 	res := <-c
-	m.chanMap[i] = nil
+	m.forget(i)
 	if res != "ok" {
 		return errors.New(res)
 	}
@@ -442,25 +558,22 @@ func (m *Multiview) onFault(addr int, length int, faultType byte, accessType str
 		str = WRITE_REQUEST
 	}
 	c := make(chan string)
-	m.sequenceNumber++
-	i := m.sequenceNumber
-	m.chanMap[i] = c
+	i := m.newEvent(c)
 	msg := network.MultiviewMessage{
 		Type:       str,
 		From:       m.Id,
-		To:         byte(0),
-		EventId:    m.sequenceNumber,
+		To:         m.managerId(),
+		EventId:    i,
 		Fault_addr: addr,
 	}
-	err := m.conn.Send(msg)
-	m.logMessage(msg)
+	err := m.sendToManager(msg)
 	panicOnErr(err)
 	<-c
-	m.chanMap[i] = nil
+	to := m.forget(i)
 	//send ack
 	msg = network.MultiviewMessage{
 		From:       m.Id,
-		To:         byte(0),
+		To:         to,
 		Fault_addr: addr,
 	}
 	if faultType == 0 {
@@ -473,21 +586,67 @@ func (m *Multiview) onFault(addr int, length int, faultType byte, accessType str
 	return nil
 }
 
+//onFaults fetches the minipages of several vpages with a single request to the manager, which handles them in order.
+//The replies come in one for each vpage, after which they are all acknowledged with a single message.
+func (m *Multiview) onFaults(addrs []int, faultType byte) {
+	if len(addrs) == 0 {
+		return
+	}
+	if len(addrs) == 1 {
+		m.onFault(addrs[0], 1, faultType, "", nil)
+		return
+	}
+	c := make(chan string)
+	i := m.newEvent(c)
+	msg := network.MultiviewMessage{
+		Type:       READ_REQUEST,
+		From:       m.Id,
+		To:         m.managerId(),
+		EventId:    i,
+		Fault_addr: addrs[0],
+		IntArr:     addrs, //<- the faults of the range
+	}
+	if faultType == 1 {
+		msg.Type = WRITE_REQUEST
+	}
+	err := m.sendToManager(msg)
+	panicOnErr(err)
+	for range addrs {
+		<-c
+	}
+	to := m.forget(i)
+	msg = network.MultiviewMessage{
+		Type:       READ_ACK,
+		From:       m.Id,
+		To:         to,
+		Fault_addr: addrs[0],
+		IntArr:     addrs,
+	}
+	if faultType == 1 {
+		msg.Type = WRITE_ACK
+	}
+	m.conn.Send(msg)
+	m.logMessage(msg)
+}
+
 func (m *Multiview) messageHandler(msg network.MultiviewMessage, c chan bool) error {
-	log.Println("received message at host", m.Id, msg)
+	if m.logger.Enabled(network.LogDebug) {
+		m.logger.Debugf("received %+v", msg)
+	}
 	switch msg.Type {
 	case WELCOME_MESSAGE:
 		m.Id = msg.To
+		m.logger.SetHost(int(m.Id))
 		c <- true
 	case READ_REPLY, WRITE_REPLY:
+		if len(msg.Data) == 0 {
+			//the minipage was all zeros, so it wasn't sent.
+			msg.Data = make([]byte, msg.Minipage_size)
+		}
 		privBase := msg.Privbase
 		//write data to privileged view, ie. the actual memory representation
-		for i, byt := range msg.Data {
-			err := m.Write(privBase+i, byt)
-			if err != nil {
-				log.Println("failed to write to privileged view at addr: ", privBase+i, " with error: ", err)
-				break
-			}
+		if err := m.mem.vm.PrivilegedWrite(privBase, msg.Data); err != nil {
+			m.logger.Errorf("failed to write to privileged view at addr %v: %v", privBase, err)
 		}
 		var right byte
 		if msg.Type == READ_REPLY {
@@ -496,10 +655,11 @@ func (m *Multiview) messageHandler(msg network.MultiviewMessage, c chan bool) er
 			right = memory.READ_WRITE
 		}
 		m.setInAccessMap(m.mem.getVPageNr(msg.Fault_addr), right)
-		m.chanMap[msg.EventId] <- "done" //let the blocking caller resume their work
+		m.eventChan(msg.EventId) <- "done" //let the blocking caller resume their work
 	case READ_REQUEST, WRITE_REQUEST:
+		m.atomicLock.Lock()
 		vpagenr := m.mem.getVPageNr(msg.Fault_addr)
-		if msg.Type == READ_REQUEST && m.getInAccessMap(vpagenr) == memory.READ_WRITE {
+		if msg.Type == READ_REQUEST && m.getInAccessMap(vpagenr) == memory.READ_WRITE && !m.isOwned(vpagenr) {
 			m.setInAccessMap(vpagenr, memory.READ_ONLY)
 		} else if msg.Type == WRITE_REQUEST {
 			m.setInAccessMap(vpagenr, memory.NO_ACCESS)
@@ -513,40 +673,60 @@ func (m *Multiview) messageHandler(msg network.MultiviewMessage, c chan bool) er
 		//send reply back to requester including data
 		msg.To = msg.From
 		res, err := m.ReadBytes(msg.Privbase, msg.Minipage_size)
+		m.atomicLock.Unlock()
 		panicOnErr(err)
 		msg.Data = res
+		if client, ok := m.conn.(*network.P2PClient); ok && client.Compresses(msg.To) && network.IsZero(res) {
+			msg.Data = nil
+		}
 		m.conn.Send(msg)
 		m.logMessage(msg)
 
+	case UPDATE_REQUEST:
+		m.handleUpdateRequest(msg)
+	case UPDATE_REPLY:
+		m.handleUpdateReply(msg)
+	case UPDATE:
+		m.handlePushedUpdate(msg)
+	case UPDATE_ACK:
+		m.eventChan(msg.EventId) <- strconv.Itoa(msg.Id)
 	case INVALIDATE_REQUEST:
+		m.atomicLock.Lock()
 		m.setInAccessMap(m.mem.getVPageNr(msg.Fault_addr), memory.NO_ACCESS)
+		m.atomicLock.Unlock()
 		msg.Type = INVALIDATE_REPLY
-		msg.To = byte(0)
+		msg.To = m.managerId()
 		m.conn.Send(msg)
 		m.logMessage(msg)
 	case MALLOC_REPLY:
 		if msg.Err != "" {
-			m.chanMap[msg.EventId] <- msg.Err
+			m.eventChan(msg.EventId) <- msg.Err
 		} else {
 			s := msg.Fault_addr
-			m.chanMap[msg.EventId] <- strconv.Itoa(s)
+			m.eventChan(msg.EventId) <- strconv.Itoa(s)
 		}
-	case MULTI_MALLOC_REPLY:
+	case MULTI_MALLOC_REPLY, ARRAY_MALLOC_REPLY:
 		if msg.Err != "" {
-			m.chanMap[msg.EventId] <- msg.Err
+			m.eventChan(msg.EventId) <- msg.Err
 		} else {
-			m.chanMap[msg.EventId] <- arrayToString(msg.IntArr, ",")
+			m.eventChan(msg.EventId) <- arrayToString(msg.IntArr, ",")
 		}
 	case FREE_REPLY:
 		if msg.Err != "" {
-			m.chanMap[msg.EventId] <- msg.Err
+			m.eventChan(msg.EventId) <- msg.Err
 		} else {
-			m.chanMap[msg.EventId] <- "ok"
+			m.eventChan(msg.EventId) <- "ok"
 		}
 	case LOCK_ACQUIRE_RESPONSE:
-		m.chanMap[msg.EventId] <- "ok"
+		if msg.Err != "" {
+			m.deliver(msg.EventId, msg.Err)
+		} else {
+			m.deliver(msg.EventId, "ok")
+		}
 	case BARRIER_RESPONSE:
-		m.chanMap[msg.EventId] <- "ok"
+		m.eventChan(msg.EventId) <- "ok"
+	case NEW_MANAGER:
+		m.handleNewManager(msg)
 	}
 	return nil
 }
@@ -582,19 +762,134 @@ func StringOfIntsToIntArray(s string) []int {
 	return res
 }
 
-func (m *Multiview) SetShouldLogNetwork(b bool) {
-	m.shouldLogNetwork = b
-	if m.messagesSent == nil {
-		m.messagesSent = make([]int, 20)
+//BytesSent returns the number of bytes of the messages sent by this host and its manager, before and after compression.
+func (m *Multiview) BytesSent() (raw, wire int) {
+	if client, ok := m.conn.(*network.P2PClient); ok {
+		raw, wire = client.BytesSent()
 	}
+	if m.manager != nil {
+		if server, ok := m.manager.conn.(*network.P2PServer); ok {
+			r, w := server.BytesSent()
+			raw, wire = raw+r, wire+w
+		}
+	}
+	return
+}
+
+//ViewStats returns how full the views are. Only the host running the manager knows this, the others get empty stats.
+func (m *Multiview) ViewStats() ViewStats {
+	if m.manager == nil {
+		return ViewStats{}
+	}
+	return m.manager.ViewStats()
+}
+
+//SetCompression sets whether minipage data is compressed on the wire. Call it before Initialize or Join.
+func (m *Multiview) SetCompression(b bool) {
+	m.compress = b
+}
+
+//SetLogger sets the logger of the host, and of the manager and standby if it runs one. Call it before Initialize or Join.
+func (m *Multiview) SetLogger(l *network.Logger) {
+	m.logger = l.For("multiview")
+	m.logger.SetHost(int(m.Id))
+	if m.manager != nil {
+		m.manager.SetLogger(l)
+	}
+	if m.standby != nil {
+		m.standby.SetLogger(l)
+	}
+}
+
+//SetShouldLogNetwork turns counting the messages sent of each type on or off. The counts are logged when the host
+//leaves. It may be called while the host runs.
+func (m *Multiview) SetShouldLogNetwork(b bool) {
+	m.messagesSent.setEnabled(b)
 	if m.manager != nil {
 		m.manager.SetShouldLogNetwork(b)
 	}
 }
 
 func (m *Multiview) logMessage(message network.MultiviewMessage) {
-	if m.shouldLogNetwork {
-		m.messagesSent[mTypeToInt(message.GetType())]++
+	m.messagesSent.log(message.GetType())
+}
+
+//messageLog counts the messages sent of each type while it is enabled. It is safe for concurrent use.
+type messageLog struct {
+	enabled int32
+	counts  [len(messageTypeNames)]int64
+}
+
+func (l *messageLog) setEnabled(b bool) {
+	var enabled int32
+	if b {
+		enabled = 1
+	}
+	atomic.StoreInt32(&l.enabled, enabled)
+}
+
+func (l *messageLog) isEnabled() bool {
+	return atomic.LoadInt32(&l.enabled) == 1
+}
+
+func (l *messageLog) log(msgType string) {
+	if l.isEnabled() {
+		atomic.AddInt64(&l.counts[mTypeToInt(msgType)], 1)
+	}
+}
+
+//count returns the number of messages of the type that have been counted.
+func (l *messageLog) count(msgType string) int {
+	return int(atomic.LoadInt64(&l.counts[mTypeToInt(msgType)]))
+}
+
+//messageTypeNames are the message types, in the order of mTypeToInt.
+var messageTypeNames = [...]string{
+	READ_REQUEST,
+	WRITE_REQUEST,
+	READ_REPLY,
+	WRITE_REPLY,
+	INVALIDATE_REPLY,
+	INVALIDATE_REQUEST,
+	MALLOC_REQUEST,
+	FREE_REQUEST,
+	MALLOC_REPLY,
+	FREE_REPLY,
+	WELCOME_MESSAGE,
+	READ_ACK,
+	WRITE_ACK,
+	LOCK_ACQUIRE_REQUEST,
+	LOCK_ACQUIRE_RESPONSE,
+	LOCK_RELEASE,
+	BARRIER_REQUEST,
+	BARRIER_RESPONSE,
+	MULTI_MALLOC_REQUEST,
+	MULTI_MALLOC_REPLY,
+	ARRAY_MALLOC_REQUEST,
+	ARRAY_MALLOC_REPLY,
+	ARRAY_LOOKUP_REQUEST,
+	UPDATE_REQUEST,
+	UPDATE_REPLY,
+	UPDATE,
+	UPDATE_ACK,
+	REPLICATE,
+	STANDBY_REGISTER,
+	STANDBY_LEAVE,
+	NEW_MANAGER,
+	REJOIN,
+	READ_LOCK_REQUEST,
+	READ_LOCK_RELEASE,
+	LOCK_TRY_REQUEST,
+	LOCK_CANCEL,
+}
+
+//logMessagesSent logs the number of messages sent of each type at info level.
+func logMessagesSent(logger *network.Logger, messagesSent *messageLog) {
+	if !logger.Enabled(network.LogInfo) {
+		return
+	}
+	for _, name := range messageTypeNames {
+		logger.Infof("%v messages: %v", name, messagesSent.count(name))
 	}
 }
 
@@ -640,6 +935,38 @@ func mTypeToInt(s string) int {
 		return 18
 	case MULTI_MALLOC_REPLY:
 		return 19
+	case ARRAY_MALLOC_REQUEST:
+		return 20
+	case ARRAY_MALLOC_REPLY:
+		return 21
+	case ARRAY_LOOKUP_REQUEST:
+		return 22
+	case UPDATE_REQUEST:
+		return 23
+	case UPDATE_REPLY:
+		return 24
+	case UPDATE:
+		return 25
+	case UPDATE_ACK:
+		return 26
+	case REPLICATE:
+		return 27
+	case STANDBY_REGISTER:
+		return 28
+	case STANDBY_LEAVE:
+		return 29
+	case NEW_MANAGER:
+		return 30
+	case REJOIN:
+		return 31
+	case READ_LOCK_REQUEST:
+		return 32
+	case READ_LOCK_RELEASE:
+		return 33
+	case LOCK_TRY_REQUEST:
+		return 34
+	case LOCK_CANCEL:
+		return 35
 	}
 	return -1
 }
@@ -12,7 +12,7 @@ import (
 func TestHandlerREADWRITE_REPLY(t *testing.T) {
 	mw := NewMultiView()
 
-	mw.chanMap = make(map[byte]chan string)
+	mw.chanMap = make(map[int]chan string)
 	cMock := NewClientMock()
 	mw.StartAndConnect(4096, 128, cMock)
 	msg := network.MultiviewMessage{
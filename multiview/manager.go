@@ -6,7 +6,6 @@ import (
 	"DSM-project/treadmarks"
 	"DSM-project/utils"
 	"fmt"
-	"log"
 	"sync"
 )
 
@@ -17,110 +16,125 @@ type minipage struct {
 
 //this is the actual manager.
 type Manager struct {
-	messagesSent     []int
-	shouldLogNetwork bool
+	messagesSent     messageLog //see SetShouldLogNetwork
 	group            *sync.WaitGroup
 	treadmarks.LockManager
 	treadmarks.BarrierManager
-	copyLock *sync.RWMutex
 	conn     network.ServerInterface
 	shutdown chan bool
 	vm       memory.VirtualMemory //The virtual memory object we are working on in the system.
-	mpt      map[int]minipage     //Minipagetable
+	shards   []*shard             //The state of each vpage, sharded by vpage number.
 	log      map[int]int          //A map, where each entrance points to
 	// the first vpage of this allocation. Used for freeing.
-	copies map[int][]byte        //A map of who has copies of what vpage
-	locks  map[int]*sync.RWMutex //A map of locks belonging to each vpage.
 	*sync.Mutex
-	locksLock *sync.RWMutex
+	compress bool
+	arrays   map[int]*array //Array allocations by the view address of their first element.
+	names    map[string]int //The view addresses of the named arrays.
+	views    map[int]int    //The number of vpages in use in each view.
+	logger   *network.Logger
+	replication
 }
 
 // Returns the pointer to a manager object.
 func NewManager(vm memory.VirtualMemory) *Manager {
 	m := Manager{
-		copyLock: new(sync.RWMutex),
-		copies:   make(map[int][]byte),
-		locks:    make(map[int]*sync.RWMutex),
-		vm:       vm,
-		mpt:      make(map[int]minipage),
-		log:      make(map[int]int),
+		vm:          vm,
+		shards:      newShards(),
+		log:         make(map[int]int),
+		Mutex:       new(sync.Mutex),
+		arrays:      make(map[int]*array),
+		names:       make(map[string]int),
+		views:       make(map[int]int),
+		logger:      network.DefaultLogger().For("manager"),
+		replication: newReplication(),
 	}
 	return &m
 }
 
 func NewUpdatedManager(vm memory.VirtualMemory, lm treadmarks.LockManager, bm treadmarks.BarrierManager) *Manager {
 	m := Manager{
-		copyLock:       new(sync.RWMutex),
-		copies:         make(map[int][]byte),
-		locks:          make(map[int]*sync.RWMutex),
 		vm:             vm,
-		mpt:            make(map[int]minipage),
+		shards:         newShards(),
 		log:            make(map[int]int),
 		LockManager:    lm,
 		BarrierManager: bm,
 		Mutex:          new(sync.Mutex),
-		locksLock:      new(sync.RWMutex),
 		group:          new(sync.WaitGroup),
 		shutdown:       make(chan bool),
+		arrays:         make(map[int]*array),
+		names:          make(map[string]int),
+		views:          make(map[int]int),
+		logger:         network.DefaultLogger().For("manager"),
+		replication:    newReplication(),
 	}
 	return &m
 }
 
 func (m *Manager) getCopies(pageNr int) []byte {
-	m.copyLock.RLock()
-	defer m.copyLock.RUnlock()
-	return m.copies[pageNr]
+	s := m.shardOf(pageNr)
+	s.RLock()
+	defer s.RUnlock()
+	return s.copies[pageNr]
 }
 
 func (m *Manager) setCopies(pageNr int, val []byte) {
-	m.copyLock.Lock()
-	defer m.copyLock.Unlock()
-	m.copies[pageNr] = val
+	s := m.shardOf(pageNr)
+	s.Lock()
+	defer s.Unlock()
+	m.putCopies(s, pageNr, val)
 }
 
-func (m *Manager) deleteCopies(pageNr int) {
-	m.copyLock.Lock()
-	defer m.copyLock.Unlock()
-	delete(m.copies, pageNr)
+//putCopies sets the copy set of a vpage. The caller holds the lock of its shard.
+func (m *Manager) putCopies(s *shard, pageNr int, val []byte) {
+	s.copies[pageNr] = val
+	entry := []int{pageNr}
+	for _, c := range val {
+		entry = append(entry, int(c))
+	}
+	m.replicate(opCopies, entry...)
 }
 
 func (m *Manager) doForAllCopies(f func(key int, bytes []byte)) {
-	m.copyLock.Lock()
-	defer m.copyLock.Unlock()
-	for i, bytes := range m.copies {
-		f(i, bytes)
+	for _, s := range m.shards {
+		s.Lock()
+		for i, bytes := range s.copies {
+			f(i, bytes)
+		}
+		s.Unlock()
 	}
 }
 
-func (m *Manager) Connect(address string) {
+//Connect starts listening for hosts on the port of the address. It fails if the port can't be listened on.
+func (m *Manager) Connect(address string) error {
 	_, port := utils.StringToIpAndPort(address)
-	m.conn, _ = network.NewP2PServer(m.HandleMessage, port, nil)
+	server, err := network.NewP2PServer(m.HandleMessage, port, nil)
+	if err != nil {
+		return err
+	}
+	server.SetCompression(m.compress)
+	server.SetLogger(m.logger)
+	m.conn = server
+	return nil
+}
+
+//SetCompression sets whether messages from the manager are compressed on the wire. Call it before Connect.
+func (m *Manager) SetCompression(b bool) {
+	m.compress = b
 }
 
 func (m *Manager) Shutdown() {
-	if m.shouldLogNetwork {
-		fmt.Println("Number of messages sent by manager:")
-		fmt.Println("READ_REQUEST", m.messagesSent[0])
-		fmt.Println("WRITE_REQUEST", m.messagesSent[1])
-		fmt.Println("READ_REPLY", m.messagesSent[2])
-		fmt.Println("WRITE_REPLY", m.messagesSent[3])
-		fmt.Println("INVALIDATE_REPLY", m.messagesSent[4])
-		fmt.Println("INVALIDATE_REQUEST", m.messagesSent[5])
-		fmt.Println("MALLOC_REQUEST", m.messagesSent[6])
-		fmt.Println("FREE_REQUEST", m.messagesSent[7])
-		fmt.Println("MALLOC_REPLY", m.messagesSent[8])
-		fmt.Println("FREE_REPLY", m.messagesSent[9])
-		fmt.Println("WELCOME_MESSAGE", m.messagesSent[10])
-		fmt.Println("READ_ACK", m.messagesSent[11])
-		fmt.Println("WRITE_ACK", m.messagesSent[12])
-		fmt.Println("LOCK_ACQUIRE_REQUEST", m.messagesSent[13])
-		fmt.Println("LOCK_ACQUIRE_RESPONSE", m.messagesSent[14])
-		fmt.Println("LOCK_RELEASE", m.messagesSent[15])
-		fmt.Println("BARRIER_REQUEST", m.messagesSent[16])
-		fmt.Println("BARRIER_RESPONSE", m.messagesSent[17])
-		fmt.Println("MULTI_MALLOC_REQUEST", m.messagesSent[18])
-		fmt.Println("MULTI_MALLOC_REPLY", m.messagesSent[19])
+	if m.messagesSent.isEnabled() {
+		logMessagesSent(m.logger, &m.messagesSent)
+		views := m.ViewStats()
+		m.logger.Infof("vpages in use: %v of %v in %v views", views.Used, views.Views*views.Vpages, len(views.PerView))
+		if server, ok := m.conn.(*network.P2PServer); ok {
+			raw, wire := server.BytesSent()
+			m.logger.Infof("message bytes: %v sent as %v bytes", raw, wire)
+		}
 	}
+	m.replLock.Lock()
+	m.stopReplication()
+	m.replLock.Unlock()
 	m.conn.Close()
 
 }
@@ -130,12 +144,17 @@ func (m *Manager) Shutdown() {
 // then send whatever messages needs to be sent afterwards.
 func (m *Manager) HandleMessage(message network.Message) error {
 	msg := message.(network.MultiviewMessage)
-	log.Println("Manager got message ", message)
+	if m.logger.Enabled(network.LogDebug) {
+		m.logger.Debugf("got %+v", msg)
+	}
+	if m.isCrashed() {
+		return nil
+	}
 	switch t := msg.Type; t {
 	case READ_REQUEST:
-		go m.HandleReadReq(msg)
+		go m.forEachFault(msg, m.HandleReadReq)
 	case WRITE_REQUEST:
-		go m.HandleWriteReq(msg)
+		go m.forEachFault(msg, m.HandleWriteReq)
 	case INVALIDATE_REPLY:
 		m.HandleInvalidateReply(msg)
 	case MALLOC_REQUEST:
@@ -143,44 +162,78 @@ func (m *Manager) HandleMessage(message network.Message) error {
 	case FREE_REQUEST:
 		m.HandleFree(msg)
 	case WRITE_ACK:
-		m.HandleWriteAck(msg)
+		m.forEachFault(msg, m.HandleWriteAck)
 	case READ_ACK:
-		m.HandleReadAck(msg)
+		m.forEachFault(msg, m.HandleReadAck)
 	case LOCK_ACQUIRE_REQUEST:
 		m.handleLockAcquireRequest(&msg)
 	case BARRIER_REQUEST:
 		m.handleBarrierRequest(&msg)
 	case LOCK_RELEASE:
 		m.handleLockReleaseRequest(&msg)
+	case READ_LOCK_REQUEST:
+		m.handleReadLockRequest(&msg)
+	case READ_LOCK_RELEASE:
+		m.handleReadLockRelease(&msg)
+	case LOCK_TRY_REQUEST:
+		m.handleLockTryRequest(&msg)
+	case LOCK_CANCEL:
+		m.handleLockCancel(&msg)
 	case MULTI_MALLOC_REQUEST:
 		m.handleMultiAlloc(msg)
+	case ARRAY_MALLOC_REQUEST:
+		m.handleArrayAlloc(msg)
+	case ARRAY_LOOKUP_REQUEST:
+		m.handleArrayLookup(msg)
+	case UPDATE:
+		go m.handleUpdate(msg)
+	case REPLICATE:
+		m.applyReplicated(msg)
+	case STANDBY_REGISTER:
+		m.handleStandbyRegister(msg)
+	case STANDBY_LEAVE:
+		m.handleStandbyLeave(msg)
+	case REJOIN:
+		m.handleRejoin(msg)
 	}
 	return nil
 }
 
+//forEachFault handles a message for each of the faults of a range in IntArr, in order, or just the message itself.
+//Ranges always come in increasing address order, so the vpage locks are taken in the same order by everyone.
+func (m *Manager) forEachFault(message network.MultiviewMessage, handle func(network.MultiviewMessage)) {
+	if len(message.IntArr) == 0 {
+		handle(message)
+		return
+	}
+	addrs := message.IntArr
+	message.IntArr = nil
+	for _, addr := range addrs {
+		message.Fault_addr = addr
+		handle(message)
+	}
+}
+
 // This translates a message, by adding more information to it. This is information
 // that only the manager knows, but which is important for the hosts.
 func (m *Manager) translate(message *network.MultiviewMessage) int {
-	m.Lock()
 	vpage := message.Fault_addr / m.vm.GetPageSize()
-	if _, ok := m.mpt[vpage]; ok == false {
-		log.Println("vpages in manager before crash:", m.mpt)
+	mp, ok := m.getMinipage(vpage)
+	if ok == false {
+		m.logger.Errorf("vpage %v does not exist. Vpages in use: %v", vpage, m.minipageTable())
 		panic(fmt.Errorf("Vpage[%v] did not exist.", vpage))
 		return 0
 	}
-	message.Minipage_base = m.vm.GetPageAddr(message.Fault_addr) + m.mpt[vpage].offset
-	message.Minipage_size = m.mpt[vpage].length
+	message.Minipage_base = m.vm.GetPageAddr(message.Fault_addr) + mp.offset
+	message.Minipage_size = mp.length
 	message.Privbase = message.Minipage_base % m.vm.Size()
-	m.Unlock()
 	return vpage
 }
 
 // This handles read requests.
 func (m *Manager) HandleReadReq(message network.MultiviewMessage) {
 	vpage := m.translate(&message)
-	m.locksLock.RLock()
-	m.locks[vpage].RLock()
-	m.locksLock.RUnlock()
+	m.vpageLock(vpage).RLock()
 	//log.Println("RLocked vpage", vpage)
 	p := m.getCopies(vpage)[0]
 	message.To = p
@@ -191,18 +244,21 @@ func (m *Manager) HandleReadReq(message network.MultiviewMessage) {
 // This handles write requests.
 func (m *Manager) HandleWriteReq(message network.MultiviewMessage) {
 	vpage := m.translate(&message)
-	log.Println("translated", message.Fault_addr, " to pageNr", vpage)
-	m.locksLock.RLock()
-	m.locks[vpage].Lock()
-	m.locksLock.RUnlock()
-	log.Println("Locked vpage", vpage)
+	m.setWriter(vpage, message.From)
+	m.vpageLock(vpage).Lock()
+	if m.logger.Enabled(network.LogDebug) {
+		m.logger.Debugf("locked vpage %v for a write by host %v", vpage, message.From)
+	}
+	if m.isWriteUpdate(vpage) {
+		m.handleUpdateWrite(message, vpage)
+		return
+	}
 	message.Type = INVALIDATE_REQUEST
 	if len(m.getCopies(vpage)) < 1 {
 		panic("Empty copyset on write request! at vpage" + string(vpage))
 	}
 	for _, p := range m.getCopies(vpage) {
 		message.To = p
-		log.Println("Manager sending", message)
 		m.conn.Send(message)
 		m.logMessage(message)
 	}
@@ -210,61 +266,70 @@ func (m *Manager) HandleWriteReq(message network.MultiviewMessage) {
 
 func (m *Manager) HandleInvalidateReply(message network.MultiviewMessage) {
 	vpage := m.translate(&message)
-	m.Lock()
-	defer m.Unlock()
-	if len(m.getCopies(vpage)) == 1 {
+	s := m.shardOf(vpage)
+	s.Lock()
+	defer s.Unlock()
+	if len(s.copies[vpage]) == 1 {
 		message.Type = WRITE_REQUEST
-		message.To = m.getCopies(vpage)[0]
-		log.Println("manager sending", message)
-		m.setCopies(vpage, []byte{})
+		message.To = s.copies[vpage][0]
+		m.putCopies(s, vpage, []byte{})
 		m.conn.Send(message)
 		m.logMessage(message)
 	} else {
-		m.setCopies(vpage, m.getCopies(vpage)[1:])
+		m.putCopies(s, vpage, s.copies[vpage][1:])
 	}
 
 }
 
 func (m *Manager) HandleReadAck(message network.MultiviewMessage) {
 	vpage := m.handleAck(message)
-	m.locksLock.RLock()
-	m.locks[vpage].RUnlock()
-	m.locksLock.RUnlock()
+	m.vpageLock(vpage).RUnlock()
 	//log.Println("RUnlocked vpage", vpage)
 }
 
 func (m *Manager) HandleWriteAck(message network.MultiviewMessage) {
 	vpage := m.handleAck(message)
-	m.locksLock.RLock()
-	m.locks[vpage].Unlock()
-	m.locksLock.RUnlock()
+	m.vpageLock(vpage).Unlock()
 	//log.Println("Unlocked vpage", vpage)
 }
 
 func (m *Manager) handleAck(message network.MultiviewMessage) int {
 	vpage := m.translate(&message)
-	log.Println("translated", message.Fault_addr, " to pageNr", vpage)
+	s := m.shardOf(vpage)
+	s.Lock()
+	defer s.Unlock()
 	alreadyHas := false
-	for _, c := range m.getCopies(vpage) {
+	for _, c := range s.copies[vpage] {
 		if c == message.From {
 			alreadyHas = true
 			break
 		}
 	}
 	if !alreadyHas {
-		m.setCopies(vpage, append(m.getCopies(vpage), message.From))
+		m.putCopies(s, vpage, append(s.copies[vpage], message.From))
+	}
+	if message.Type == WRITE_ACK && s.updates[vpage] {
+		//the writer is the new owner, which is kept first in the copy set
+		copies := []byte{message.From}
+		for _, c := range s.copies[vpage] {
+			if c != message.From {
+				copies = append(copies, c)
+			}
+		}
+		m.putCopies(s, vpage, copies)
 	}
 	return vpage
 }
 
 func (m *Manager) handleMultiAlloc(message network.MultiviewMessage) {
+	m.Lock()
 	res := make([]int, len(message.IntArr))
 	for r, size := range message.IntArr {
 		// Alloc begins here
-		ptr, err := m.vm.Malloc(size)
+		ptr, err := m.vmMalloc(size)
 		if err != nil {
 			message.Err = err.Error()
-			return
+			break
 		}
 		//generate minipages
 		sizeLeft := size
@@ -278,48 +343,23 @@ func (m *Manager) handleMultiAlloc(message network.MultiviewMessage) {
 			resultArray = append(resultArray, minipage{offset, length})
 		}
 
-		startpg := ptr / m.vm.GetPageSize()
-		endpg := (ptr + size) / m.vm.GetPageSize()
-		npages := m.vm.Size() / m.vm.GetPageSize()
-		rest := m.vm.Size() % m.vm.GetPageSize()
-		if rest > 0 {
-			npages++
-		}
-		//loop over views to find free space
-		for i := 1; i < m.vm.GetPageSize(); i++ {
-			failed := false
-			startpg = startpg + npages
-			endpg = endpg + npages
-			for j := startpg; j <= endpg; j++ {
-				_, exists := m.mpt[j]
-				if exists {
-					failed = true
-					break
-				}
-			}
-			if failed == false {
-				break
-			}
-		}
-
-		//insert into virtual memory
-		m.locksLock.Lock()
-		for i, mp := range resultArray {
-			m.mpt[startpg+i] = mp
-			m.log[startpg+i] = startpg
-			m.locks[startpg+i] = new(sync.RWMutex)
-			m.setCopies(startpg+i, []byte{message.From})
+		startpg, err := m.findView(ptr/m.vm.GetPageSize(), len(resultArray))
+		if err != nil {
+			m.vmFree(ptr)
+			message.Err = err.Error()
+			break
 		}
-		m.locksLock.Unlock()
+		m.insertMinipages(startpg, resultArray, message.From, false)
 		//Alloc ends
 
-		res[r] = startpg*m.vm.GetPageSize() + m.mpt[startpg].offset
+		res[r] = startpg*m.vm.GetPageSize() + resultArray[0].offset
 	}
+	m.Unlock()
 	message.Minipage_size = 0
 	message.IntArr = res
 	message.Type = MULTI_MALLOC_REPLY
 	message.To = message.From
-	message.From = 0
+	message.From = m.myId
 	m.conn.Send(message)
 	m.logMessage(message)
 }
@@ -329,14 +369,21 @@ func (m *Manager) HandleAlloc(message network.MultiviewMessage) {
 	defer func() {
 		m.Unlock()
 		message.To = message.From
-		message.From = 0
+		message.From = m.myId
 		message.Type = MALLOC_REPLY
 		m.conn.Send(message)
 		m.logMessage(message)
 	}()
 
 	size := message.Minipage_size
-	ptr, err := m.vm.Malloc(size)
+	flags := memory.FlagsFromInts(message.IntArr)
+	for i := range flags {
+		//co-location targets are given in the vpage address space
+		flags[i].Addr = flags[i].Addr % m.vm.Size()
+	}
+	message.IntArr = nil
+	flags, writeUpdate := splitWriteUpdate(flags)
+	ptr, err := m.vmMalloc(size, flags...)
 	if err != nil {
 		message.Err = err.Error()
 		return
@@ -354,59 +401,151 @@ func (m *Manager) HandleAlloc(message network.MultiviewMessage) {
 		resultArray = append(resultArray, minipage{offset, length})
 	}
 
-	startpg := ptr / m.vm.GetPageSize()
-	endpg := (ptr + size) / m.vm.GetPageSize()
+	startpg, err := m.findView(ptr/m.vm.GetPageSize(), len(resultArray))
+	if err != nil {
+		m.vmFree(ptr)
+		message.Err = err.Error()
+		return
+	}
+	m.insertMinipages(startpg, resultArray, message.From, writeUpdate)
+	//Send reply to alloc requester
+	message.Fault_addr = startpg*m.vm.GetPageSize() + resultArray[0].offset
+}
+
+//npages returns the number of vpages in a view.
+func (m *Manager) npages() int {
 	npages := m.vm.Size() / m.vm.GetPageSize()
-	rest := m.vm.Size() % m.vm.GetPageSize()
-	if rest > 0 {
+	if m.vm.Size()%m.vm.GetPageSize() > 0 {
 		npages++
 	}
-	//loop over views to find free space
-	for i := 1; i < m.vm.GetPageSize(); i++ {
-		failed := false
-		startpg = startpg + npages
-		endpg = endpg + npages
-		for j := startpg; j <= endpg; j++ {
-			_, exists := m.mpt[j]
-			if exists {
-				failed = true
+	return npages
+}
+
+//findView returns the first vpage of the first view in which n vpages from the given physical page are all free.
+func (m *Manager) findView(page, n int) (int, error) {
+	npages := m.npages()
+	if page+n > npages {
+		return 0, NoFreeViewErr
+	}
+	for v := 1; v < m.vm.GetPageSize(); v++ {
+		if m.views[v] > npages-n {
+			continue
+		}
+		startpg := page + v*npages
+		free := true
+		for j := startpg; j < startpg+n; j++ {
+			if _, exists := m.getMinipage(j); exists {
+				free = false
 				break
 			}
 		}
-		if failed == false {
-			break
+		if free {
+			return startpg, nil
 		}
 	}
-	//insert into virtual memory
-	m.locksLock.Lock()
-	for i, mp := range resultArray {
-		m.mpt[startpg+i] = mp
-		m.log[startpg+i] = startpg
-		m.locks[startpg+i] = new(sync.RWMutex)
-		m.setCopies(startpg+i, []byte{message.From})
+	return 0, NoFreeViewErr
+}
+
+//insertMinipages puts the minipages of an allocation in the vpages from startpg and on. The caller holds the manager lock.
+func (m *Manager) insertMinipages(startpg int, minipages []minipage, owner byte, writeUpdate bool) {
+	entry := []int{startpg, int(owner), 0}
+	if writeUpdate {
+		entry[2] = 1
+	}
+	for i, mp := range minipages {
+		vpage := startpg + i
+		s := m.shardOf(vpage)
+		s.Lock()
+		s.mpt[vpage] = mp
+		s.locks[vpage] = new(sync.RWMutex)
+		s.copies[vpage] = []byte{owner}
+		s.updates[vpage] = writeUpdate
+		s.Unlock()
+		m.log[vpage] = startpg
+		m.views[vpage/m.npages()]++
+		entry = append(entry, mp.offset, mp.length)
 	}
-	m.locksLock.Unlock()
-	//Send reply to alloc requester
-	message.Fault_addr = startpg*m.vm.GetPageSize() + m.mpt[startpg].offset
+	m.replicate(opInsert, entry...)
+}
+
+//removeMinipage frees a vpage once the requests on it are done, so it can be used again by a later allocation.
+func (m *Manager) removeMinipage(vpage int) {
+	m.vpageLock(vpage).Lock()
+	m.Lock()
+	m.dropMinipage(vpage)
+	m.Unlock()
+}
+
+//dropMinipage frees a vpage right away. The caller holds the manager lock.
+func (m *Manager) dropMinipage(vpage int) {
+	s := m.shardOf(vpage)
+	s.Lock()
+	delete(s.mpt, vpage)
+	delete(s.writers, vpage)
+	delete(s.updates, vpage)
+	delete(s.copies, vpage)
+	delete(s.locks, vpage)
+	s.Unlock()
+	delete(m.log, vpage)
+	view := vpage / m.npages()
+	if m.views[view]--; m.views[view] == 0 {
+		delete(m.views, view)
+	}
+	m.replicate(opRemove, vpage)
+}
+
+//ViewStats tells how many of the vpages of the manager are in use.
+type ViewStats struct {
+	Views       int         //number of views, not counting the privileged view
+	Vpages      int         //number of vpages in each view
+	Used        int         //number of vpages in use
+	PerView     map[int]int //number of vpages in use in each view that has any
+	Utilisation float64     //fraction of all vpages in use
+}
+
+//ViewStats returns how full the views are.
+func (m *Manager) ViewStats() ViewStats {
+	m.Lock()
+	defer m.Unlock()
+	stats := ViewStats{
+		Views:   m.vm.GetPageSize() - 1,
+		Vpages:  m.npages(),
+		PerView: make(map[int]int),
+	}
+	for v, n := range m.views {
+		stats.PerView[v] = n
+		stats.Used += n
+	}
+	stats.Utilisation = float64(stats.Used) / float64(stats.Views*stats.Vpages)
+	return stats
 }
 
 func (m *Manager) HandleFree(message network.MultiviewMessage) {
+	m.Lock()
+	a, isArray := m.removeArray(message.Fault_addr)
+	m.Unlock()
+	if isArray {
+		m.replicate(opArrayRemove, message.Fault_addr)
+		m.freeArray(a)
+		message.Type = FREE_REPLY
+		message.To = message.From
+		m.conn.Send(message)
+		m.logMessage(message)
+		return
+	}
 	vpage := m.translate(&message)
 
 	//Then we loop over vpages from that vpage. If they point back to this vpage, we free them.
 	for i := vpage; true; i++ {
-		if m.log[i] != vpage {
+		m.Lock()
+		first, exists := m.log[i]
+		m.Unlock()
+		if !exists || first != vpage {
 			break
 		}
-		m.locksLock.Lock()
-		m.locks[i].Lock()
-		delete(m.log, i)
-		delete(m.mpt, i)
-		m.deleteCopies(i)
-		delete(m.locks, i)
-		m.locksLock.Unlock()
-	}
-	m.vm.Free(message.Fault_addr % m.vm.Size())
+		m.removeMinipage(i)
+	}
+	m.vmFree(message.Fault_addr % m.vm.Size())
 	message.Type = FREE_REPLY
 	message.To = message.From
 	m.conn.Send(message)
@@ -415,22 +554,78 @@ func (m *Manager) HandleFree(message network.MultiviewMessage) {
 
 func (m *Manager) handleLockAcquireRequest(message *network.MultiviewMessage) {
 	id := message.Id
-	m.HandleLockAcquire(id)
+	req := lockRequest{message.From, message.EventId}
+	//a request sent again to a new manager may be for a lock the host already got
+	if !m.claimLock(id, req) {
+		if !m.waitForLock(req) {
+			return
+		}
+		m.HandleLockAcquire(id)
+		if !m.grantLock(id, req) {
+			//the host gave up the request while it waited
+			m.HandleLockRelease(id, message.From)
+			return
+		}
+	}
 	message.From, message.To = message.To, message.From
-	message.From = byte(0)
+	message.From = m.myId
+	message.Type = LOCK_ACQUIRE_RESPONSE
+	m.conn.Send(*message)
+	m.logMessage(*message)
+}
+
+//handleLockTryRequest grants the lock if it is free, and answers with an error if it isn't.
+func (m *Manager) handleLockTryRequest(message *network.MultiviewMessage) {
+	id := message.Id
+	if !m.claimLock(id, lockRequest{message.From, message.EventId}) {
+		if _, ok := m.HandleLockTryAcquire(id); ok {
+			m.setLockHolder(id, message.From)
+		} else {
+			message.Err = "lock is held"
+		}
+	}
+	message.From, message.To = m.myId, message.From
 	message.Type = LOCK_ACQUIRE_RESPONSE
 	m.conn.Send(*message)
 	m.logMessage(*message)
 }
 
+//handleLockCancel cancels a lock request the host has given up. If the lock was granted already, it is released.
+func (m *Manager) handleLockCancel(message *network.MultiviewMessage) {
+	id := message.Id
+	if m.cancelLockRequest(id, lockRequest{message.From, message.EventId}) {
+		m.clearLockHolder(id)
+		m.HandleLockRelease(id, message.From)
+	}
+}
+
 func (m *Manager) handleLockReleaseRequest(message *network.MultiviewMessage) error {
 	id := message.Id
+	m.clearLockHolder(id)
 	return m.HandleLockRelease(id, message.From)
 }
 
+func (m *Manager) handleReadLockRequest(message *network.MultiviewMessage) {
+	id := message.Id
+	if !m.holdsRead(id, message.From) {
+		m.HandleReadLockAcquire(id)
+		m.addReader(id, message.From)
+	}
+	message.From, message.To = m.myId, message.From
+	message.Type = LOCK_ACQUIRE_RESPONSE
+	m.conn.Send(*message)
+	m.logMessage(*message)
+}
+
+func (m *Manager) handleReadLockRelease(message *network.MultiviewMessage) error {
+	id := message.Id
+	m.removeReader(id, message.From)
+	return m.HandleReadLockRelease(id)
+}
+
 func (m *Manager) handleBarrierRequest(message *network.MultiviewMessage) {
 	id := message.Id
-	log.Println("process", message.From, "arrived at barrier", id)
+	m.logger.Debugf("host %v arrived at barrier %v", message.From, id)
 	m.HandleBarrier(id, func() {})
 	//barrier over
 
@@ -448,15 +643,17 @@ func Min(x, y int) int {
 	return y
 }
 
+//SetLogger sets the logger of the manager and its connection. Call it before Connect.
+func (m *Manager) SetLogger(l *network.Logger) {
+	m.logger = l.For("manager")
+	m.logger.SetHost(int(m.myId))
+}
+
+//SetShouldLogNetwork turns counting the messages sent of each type on or off, see Multiview.SetShouldLogNetwork.
 func (m *Manager) SetShouldLogNetwork(b bool) {
-	m.shouldLogNetwork = b
-	if m.messagesSent == nil {
-		m.messagesSent = make([]int, 20)
-	}
+	m.messagesSent.setEnabled(b)
 }
 
 func (m *Manager) logMessage(message network.MultiviewMessage) {
-	if m.shouldLogNetwork {
-		m.messagesSent[mTypeToInt(message.GetType())]++
-	}
+	m.messagesSent.log(message.GetType())
 }
@@ -3,6 +3,7 @@ package multiview
 import (
 	"DSM-project/memory"
 	"DSM-project/network"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"sync"
 	"testing"
@@ -77,11 +78,11 @@ func TestManager_HandleMultipleReadReq(t *testing.T) {
 	m.HandleAlloc(network.MultiviewMessage{From: byte(2), To: byte(1), Minipage_size: 1024})
 	m.HandleReadReq(message)
 	m.HandleReadReq(message)
-	assert.True(t, checkWLockTimeout(m.locks[vpage]))
+	assert.True(t, checkWLockTimeout(m.vpageLock(vpage)))
 	message.Type = READ_ACK
 	m.HandleReadAck(message)
 	m.HandleReadAck(message)
-	assert.False(t, checkWLockTimeout(m.locks[vpage]))
+	assert.False(t, checkWLockTimeout(m.vpageLock(vpage)))
 }
 
 func TestManager_HandleAlloc(t *testing.T) {
@@ -93,15 +94,15 @@ func TestManager_HandleAlloc(t *testing.T) {
 	m.HandleAlloc(network.MultiviewMessage{From: byte(2), To: byte(1), Minipage_size: 200})
 	expmpt[8] = minipage{0, 128}
 	expmpt[9] = minipage{0, 72}
-	assert.Equal(t, expmpt, m.mpt)
-	assert.NotNil(t, m.locks[8])
+	assert.Equal(t, expmpt, m.minipageTable())
+	assert.NotNil(t, m.vpageLock(8))
 	assert.Equal(t, 8, m.log[8])
-	assert.NotNil(t, m.locks[9])
+	assert.NotNil(t, m.vpageLock(9))
 	assert.Equal(t, 8, m.log[9])
 	m.HandleAlloc(network.MultiviewMessage{From: byte(2), To: byte(1), Minipage_size: 150})
 	expmpt[17] = minipage{72, 56}
 	expmpt[18] = minipage{0, 94}
-	assert.Equal(t, expmpt, m.mpt)
+	assert.Equal(t, expmpt, m.minipageTable())
 	m.HandleAlloc(network.MultiviewMessage{From: byte(2), To: byte(1), Minipage_size: 600})
 	expmpt[10] = minipage{94, 34}
 	expmpt[11] = minipage{0, 128}
@@ -109,7 +110,7 @@ func TestManager_HandleAlloc(t *testing.T) {
 	expmpt[13] = minipage{0, 128}
 	expmpt[14] = minipage{0, 128}
 	expmpt[15] = minipage{0, 54}
-	assert.Equal(t, expmpt, m.mpt)
+	assert.Equal(t, expmpt, m.minipageTable())
 }
 
 /*func TestHandleMultiMalloc(t *testing.T) {
@@ -132,12 +133,14 @@ func TestManager_HandleFree(t *testing.T) {
 	pointer := tm.Messages[0]
 	expmpt[8] = minipage{0, 128}
 	expmpt[9] = minipage{0, 72}
-	assert.Equal(t, expmpt, m.mpt)
+	assert.Equal(t, expmpt, m.minipageTable())
 	m.HandleFree(network.MultiviewMessage{From: byte(2), To: byte(1), Fault_addr: pointer.Fault_addr})
-	assert.Equal(t, 0, len(m.mpt))
+	assert.Equal(t, 0, len(m.minipageTable()))
 	assert.Equal(t, 0, len(m.log))
-	assert.Equal(t, 0, len(m.locks))
-	assert.Equal(t, 0, len(m.copies))
+	for _, s := range m.shards {
+		assert.Equal(t, 0, len(s.locks))
+		assert.Equal(t, 0, len(s.copies))
+	}
 }
 
 func TestManager_HandleWriteReq(t *testing.T) {
@@ -157,10 +160,10 @@ func TestManager_HandleWriteReq(t *testing.T) {
 	message.Type = INVALIDATE_REPLY
 	m.HandleInvalidateReply(message)
 	vpage := message.Fault_addr / vmem.GetPageSize()
-	assert.True(t, checkRLockTimeout(m.locks[vpage]))
+	assert.True(t, checkRLockTimeout(m.vpageLock(vpage)))
 	message.Type = WRITE_ACK
 	m.HandleWriteAck(message)
-	assert.False(t, checkRLockTimeout(m.locks[vpage]))
+	assert.False(t, checkRLockTimeout(m.vpageLock(vpage)))
 }
 
 func TestManager_HandleMultipleWriteReq(t *testing.T) {
@@ -190,5 +193,51 @@ func TestManager_HandleMultipleWriteReq(t *testing.T) {
 	m.HandleWriteAck(message)
 	time.Sleep(time.Millisecond * 500)
 	vpage := message.Fault_addr / vmem.GetPageSize()
-	assert.False(t, checkWLockTimeout(m.locks[vpage]))
+	assert.False(t, checkWLockTimeout(m.vpageLock(vpage)))
+}
+
+//discardConn drops all messages, so a benchmark measures only the manager.
+type discardConn struct{}
+
+func (discardConn) Close() {}
+
+func (discardConn) Send(message network.Message) error {
+	return nil
+}
+
+//BenchmarkManager_Throughput lets simulated hosts make read and write faults on their own minipages,
+//driving the manager handlers directly. Each operation is one read fault and one write fault.
+func BenchmarkManager_Throughput(b *testing.B) {
+	for _, hosts := range []int{8, 16, 32} {
+		b.Run(fmt.Sprintf("hosts=%d", hosts), func(b *testing.B) {
+			m := NewUpdatedManager(memory.NewVmem(1<<16, 128), nil, nil)
+			m.conn = discardConn{}
+			addrs := make([]int, 0)
+			for h := 0; h < hosts; h++ {
+				m.HandleAlloc(network.MultiviewMessage{From: byte(h + 1), Minipage_size: 4 * 128})
+			}
+			for vpage, mp := range m.minipageTable() {
+				addrs = append(addrs, vpage*128+mp.offset)
+			}
+			var wg sync.WaitGroup
+			b.ResetTimer()
+			for h := 0; h < hosts; h++ {
+				wg.Add(1)
+				go func(h int) {
+					defer wg.Done()
+					for i := h; i < b.N; i += hosts {
+						msg := network.MultiviewMessage{From: byte(h + 1), Fault_addr: addrs[(h*4+i)%len(addrs)]}
+						m.HandleReadReq(msg)
+						m.HandleReadAck(msg)
+						m.HandleWriteReq(msg)
+						for range m.getCopies(msg.Fault_addr / 128) {
+							m.HandleInvalidateReply(msg)
+						}
+						m.HandleWriteAck(msg)
+					}
+				}(h)
+			}
+			wg.Wait()
+		})
+	}
 }
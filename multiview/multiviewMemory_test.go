@@ -147,7 +147,9 @@ func TestMVMem_ReadMinipage(t *testing.T) {
 
 	res, err := mem.ReadMinipage(ptr + 4)
 	assert.Nil(t, err)
-	val, _ := mem.vm.Read(1006)
+	assert.Len(t, res, 24)
+	physAddr, _ := mem.vPageAddrToMemoryAddr(ptr + 6)
+	val, _ := mem.vm.Read(physAddr)
 	assert.Equal(t, byte(11), val)
 	val, _ = mem.Read(ptr + 6)
 	assert.Equal(t, byte(11), val)
@@ -0,0 +1,674 @@
+package multiview
+
+import (
+	"DSM-project/memory"
+	"DSM-project/network"
+	"DSM-project/treadmarks"
+	"sync"
+	"time"
+)
+
+//The kinds of entries in the replication log. An entry is sent as IntArr, the kind followed by its arguments.
+const (
+	opInit        = iota //nrProcs
+	opHeartbeat          //
+	opMalloc             //size, flags...
+	opFree               //ptr
+	opInsert             //startpg, owner, writeUpdate, (offset, length)*
+	opRemove             //vpage
+	opCopies             //vpage, copies...
+	opArray              //ptr, length, elementSize, granularity, len(name), name bytes, (offset, vpage)*
+	opArrayRemove        //base
+	opLock               //id, holder
+	opUnlock             //id
+	opReadLock           //id, holder
+	opReadUnlock         //id, holder
+)
+
+//How often the primary manager sends a heartbeat to the standby, and how long the standby waits for one before it takes over.
+var heartbeatInterval = time.Millisecond * 100
+var failoverTimeout = time.Second
+
+//replication is the state a manager keeps to replicate itself to a standby, or to be one.
+type replication struct {
+	myId        byte
+	standby     byte //the host running the standby of this manager, 0 if there is none
+	nrProcs     int
+	active      bool //false while this manager is a standby
+	crashed     bool
+	replSeq     int
+	replLock    *sync.Mutex
+	lockHolders map[int]byte          //who holds each lock
+	readHolders map[int]map[byte]bool //who holds each lock in shared mode
+	lockEvents  map[int]int           //the request each lock was granted to
+	lockWaits   map[lockRequest]bool  //the lock requests waiting for their lock, false once cancelled
+	stop        chan bool    //closed to stop the heartbeat or the watchdog
+	logLock     *sync.Mutex
+	applied     int
+	backlog     map[int][]int //log entries that came before the ones preceding them
+	lastHeard   time.Time
+	ready       chan bool
+	left        chan bool
+}
+
+func newReplication() replication {
+	return replication{
+		active:      true,
+		replLock:    new(sync.Mutex),
+		lockHolders: make(map[int]byte),
+		readHolders: make(map[int]map[byte]bool),
+		lockEvents:  make(map[int]int),
+		lockWaits:   make(map[lockRequest]bool),
+		logLock:     new(sync.Mutex),
+		backlog:     make(map[int][]int),
+	}
+}
+
+//replicate sends an entry to the standby, if there is one.
+func (m *Manager) replicate(op int, args ...int) {
+	m.replLock.Lock()
+	m.sendLog(op, args...)
+	m.replLock.Unlock()
+}
+
+//sendLog sends an entry to the standby. The caller holds replLock.
+func (m *Manager) sendLog(op int, args ...int) {
+	if m.standby == 0 || m.crashed {
+		return
+	}
+	m.replSeq++
+	msg := network.MultiviewMessage{
+		Type:    REPLICATE,
+		From:    m.myId,
+		To:      m.standby,
+		EventId: m.replSeq,
+		IntArr:  append([]int{op}, args...),
+	}
+	m.conn.Send(msg)
+	m.logMessage(msg)
+}
+
+//vmMalloc allocates in the virtual memory, keeping the allocations in the same order on the standby.
+func (m *Manager) vmMalloc(size int, flags ...memory.AllocFlag) (int, error) {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	ptr, err := m.vm.Malloc(size, flags...)
+	if err == nil {
+		m.sendLog(opMalloc, append([]int{size}, memory.FlagsToInts(flags)...)...)
+	}
+	return ptr, err
+}
+
+func (m *Manager) vmFree(ptr int) error {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	err := m.vm.Free(ptr)
+	if err == nil {
+		m.sendLog(opFree, ptr)
+	}
+	return err
+}
+
+func (m *Manager) setLockHolder(id int, holder byte) {
+	m.replLock.Lock()
+	m.lockHolders[id] = holder
+	delete(m.lockEvents, id)
+	m.sendLog(opLock, id, int(holder))
+	m.replLock.Unlock()
+}
+
+func (m *Manager) clearLockHolder(id int) {
+	m.replLock.Lock()
+	delete(m.lockHolders, id)
+	delete(m.lockEvents, id)
+	m.sendLog(opUnlock, id)
+	m.replLock.Unlock()
+}
+
+//holds tells if the host holds the lock.
+func (m *Manager) holds(id int, host byte) bool {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	h, held := m.lockHolders[id]
+	return held && h == host
+}
+
+//lockRequest identifies a lock request by the host that sent it and its event id.
+type lockRequest struct {
+	host    byte
+	eventId int
+}
+
+//claimLock tells if the host that sent the request already holds the lock. If so, the request takes over the grant.
+func (m *Manager) claimLock(id int, req lockRequest) bool {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	if h, held := m.lockHolders[id]; !held || h != req.host {
+		return false
+	}
+	m.lockEvents[id] = req.eventId
+	return true
+}
+
+//waitForLock is called before a request waits for its lock. It returns false if the request was cancelled before it came.
+func (m *Manager) waitForLock(req lockRequest) bool {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	if waiting, ok := m.lockWaits[req]; ok && !waiting {
+		delete(m.lockWaits, req)
+		return false
+	}
+	m.lockWaits[req] = true
+	return true
+}
+
+//grantLock makes the host that sent the request the holder of the lock, unless the request was cancelled while it waited.
+func (m *Manager) grantLock(id int, req lockRequest) bool {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	waiting := m.lockWaits[req]
+	delete(m.lockWaits, req)
+	if !waiting {
+		return false
+	}
+	m.lockHolders[id] = req.host
+	m.lockEvents[id] = req.eventId
+	m.sendLog(opLock, id, int(req.host))
+	return true
+}
+
+//cancelLockRequest cancels a lock request. It returns true if the lock has already been granted to it, and must be released.
+func (m *Manager) cancelLockRequest(id int, req lockRequest) bool {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	if h, held := m.lockHolders[id]; held && h == req.host && m.lockEvents[id] == req.eventId {
+		return true
+	}
+	//the request is waiting, or hasn't come yet
+	m.lockWaits[req] = false
+	return false
+}
+
+func (m *Manager) addReader(id int, holder byte) {
+	m.replLock.Lock()
+	if m.readHolders[id] == nil {
+		m.readHolders[id] = make(map[byte]bool)
+	}
+	m.readHolders[id][holder] = true
+	m.sendLog(opReadLock, id, int(holder))
+	m.replLock.Unlock()
+}
+
+func (m *Manager) removeReader(id int, holder byte) {
+	m.replLock.Lock()
+	delete(m.readHolders[id], holder)
+	if len(m.readHolders[id]) == 0 {
+		delete(m.readHolders, id)
+	}
+	m.sendLog(opReadUnlock, id, int(holder))
+	m.replLock.Unlock()
+}
+
+//holdsRead tells if the host holds the lock in shared mode.
+func (m *Manager) holdsRead(id int, host byte) bool {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	return m.readHolders[id][host]
+}
+
+func (m *Manager) isCrashed() bool {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	return m.crashed
+}
+
+//crash makes the manager stop responding and closes its connections, as if its host had died.
+func (m *Manager) crash() {
+	m.replLock.Lock()
+	m.crashed = true
+	m.replLock.Unlock()
+	m.conn.Close()
+}
+
+//handleStandbyRegister makes the sender the standby of this manager, and starts sending it heartbeats.
+func (m *Manager) handleStandbyRegister(message network.MultiviewMessage) {
+	m.replLock.Lock()
+	defer m.replLock.Unlock()
+	m.standby = message.From
+	m.replSeq = 0
+	m.stop = make(chan bool)
+	m.sendLog(opInit, m.nrProcs)
+	go m.heartbeat(m.stop)
+}
+
+func (m *Manager) heartbeat(stop chan bool) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(heartbeatInterval):
+			if m.isCrashed() {
+				return
+			}
+			m.replicate(opHeartbeat)
+		}
+	}
+}
+
+//stopReplication stops the heartbeat to the standby, or the watchdog of a standby. The caller holds replLock.
+func (m *Manager) stopReplication() {
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	m.standby = 0
+}
+
+func (m *Manager) handleStandbyLeave(message network.MultiviewMessage) {
+	if !m.active {
+		//the primary agreed to our leaving
+		close(m.left)
+		return
+	}
+	m.replLock.Lock()
+	m.stopReplication()
+	m.replLock.Unlock()
+	message.To = message.From
+	message.From = m.myId
+	m.conn.Send(message)
+	m.logMessage(message)
+}
+
+//register makes this manager the standby of the manager at host 0, and waits for the first log entry.
+func (m *Manager) register() {
+	m.active = false
+	m.ready = make(chan bool)
+	m.left = make(chan bool)
+	m.stop = make(chan bool)
+	m.lastHeard = time.Now()
+	msg := network.MultiviewMessage{
+		Type: STANDBY_REGISTER,
+		From: m.myId,
+		To:   0,
+	}
+	m.conn.Send(msg)
+	m.logMessage(msg)
+	<-m.ready
+	go m.watchdog(m.stop)
+}
+
+//leave stops this standby. If it hasn't taken over yet, it first unregisters at the primary.
+func (m *Manager) leave() {
+	m.logLock.Lock()
+	active := m.active
+	m.logLock.Unlock()
+	if !active {
+		msg := network.MultiviewMessage{
+			Type: STANDBY_LEAVE,
+			From: m.myId,
+			To:   0,
+		}
+		m.conn.Send(msg)
+		m.logMessage(msg)
+		select {
+		case <-m.left:
+		case <-time.After(failoverTimeout):
+		}
+	}
+	m.Shutdown()
+}
+
+//applyReplicated applies log entries from the primary in the order they were made.
+func (m *Manager) applyReplicated(message network.MultiviewMessage) {
+	m.logLock.Lock()
+	defer m.logLock.Unlock()
+	if m.active {
+		return
+	}
+	m.lastHeard = time.Now()
+	m.backlog[message.EventId] = message.IntArr
+	for {
+		entry, ok := m.backlog[m.applied+1]
+		if !ok {
+			break
+		}
+		delete(m.backlog, m.applied+1)
+		m.applied++
+		m.apply(entry[0], entry[1:])
+	}
+}
+
+func (m *Manager) apply(op int, args []int) {
+	switch op {
+	case opInit:
+		m.nrProcs = args[0]
+		close(m.ready)
+	case opMalloc:
+		m.vm.Malloc(args[0], memory.FlagsFromInts(args[1:])...)
+	case opFree:
+		m.vm.Free(args[0])
+	case opInsert:
+		minipages := make([]minipage, 0, len(args)/2)
+		for i := 3; i+1 < len(args); i += 2 {
+			minipages = append(minipages, minipage{args[i], args[i+1]})
+		}
+		m.Lock()
+		m.insertMinipages(args[0], minipages, byte(args[1]), args[2] == 1)
+		m.Unlock()
+	case opRemove:
+		m.Lock()
+		m.dropMinipage(args[0])
+		m.Unlock()
+	case opCopies:
+		copies := make([]byte, len(args)-1)
+		for i, c := range args[1:] {
+			copies[i] = byte(c)
+		}
+		m.setCopies(args[0], copies)
+	case opArray:
+		a := &array{ptr: args[0], length: args[1], elementSize: args[2], granularity: args[3]}
+		name := make([]byte, args[4])
+		for i := range name {
+			name[i] = byte(args[5+i])
+		}
+		a.name = string(name)
+		for i := 5 + len(name); i+1 < len(args); i += 2 {
+			a.offsets = append(a.offsets, args[i])
+			a.vpages = append(a.vpages, args[i+1])
+		}
+		m.Lock()
+		mp, _ := m.getMinipage(a.vpages[0])
+		m.addArray(a.vpages[0]*m.vm.GetPageSize()+mp.offset, a)
+		m.Unlock()
+	case opArrayRemove:
+		m.Lock()
+		m.removeArray(args[0])
+		m.Unlock()
+	case opLock:
+		m.setLockHolder(args[0], byte(args[1]))
+	case opUnlock:
+		m.clearLockHolder(args[0])
+	case opReadLock:
+		m.addReader(args[0], byte(args[1]))
+	case opReadUnlock:
+		m.removeReader(args[0], byte(args[1]))
+	}
+}
+
+//arrayEntry encodes an array as an opArray log entry.
+func arrayEntry(a *array) []int {
+	res := []int{a.ptr, a.length, a.elementSize, a.granularity, len(a.name)}
+	for i := 0; i < len(a.name); i++ {
+		res = append(res, int(a.name[i]))
+	}
+	for i, vpage := range a.vpages {
+		res = append(res, a.offsets[i], vpage)
+	}
+	return res
+}
+
+//watchdog makes the standby take over when it hasn't heard from the primary for a while.
+func (m *Manager) watchdog(stop chan bool) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(heartbeatInterval):
+			m.logLock.Lock()
+			if time.Since(m.lastHeard) > failoverTimeout {
+				m.takeOver()
+				m.logLock.Unlock()
+				return
+			}
+			m.logLock.Unlock()
+		}
+	}
+}
+
+//takeOver makes the standby the manager. The locks are given back to their holders,
+//and the hosts are told to send their requests here. The caller holds logLock.
+func (m *Manager) takeOver() {
+	m.logger.Warnf("standby taking over as manager")
+	lm := treadmarks.NewLockManagerImp()
+	m.replLock.Lock()
+	for id := range m.lockHolders {
+		lm.HandleLockAcquire(id)
+	}
+	for id, readers := range m.readHolders {
+		for range readers {
+			lm.HandleReadLockAcquire(id)
+		}
+	}
+	m.replLock.Unlock()
+	m.LockManager = lm
+	m.BarrierManager = treadmarks.NewBarrierManagerImp(m.nrProcs)
+	m.active = true
+	for _, id := range m.conn.(*network.P2PClient).Peers() {
+		if id == 0 {
+			continue
+		}
+		msg := network.MultiviewMessage{
+			Type: NEW_MANAGER,
+			From: m.myId,
+			To:   byte(id),
+			Id:   int(m.myId),
+		}
+		m.conn.Send(msg)
+		m.logMessage(msg)
+	}
+}
+
+//handleRejoin brings the copy sets and lock holders up to date with what a host has.
+//Log entries the primary had no time to send before it failed are lost, but the hosts still know the outcome.
+func (m *Manager) handleRejoin(message network.MultiviewMessage) {
+	pairs, locks := message.IntArr[:message.Id], message.IntArr[message.Id:]
+	for i := 0; i+1 < len(pairs); i += 2 {
+		vpage, rights := pairs[i], byte(pairs[i+1])
+		s := m.shardOf(vpage)
+		s.Lock()
+		if _, ok := s.mpt[vpage]; !ok {
+			s.Unlock()
+			continue
+		}
+		copies := make([]byte, 0)
+		for _, c := range s.copies[vpage] {
+			if c != message.From {
+				copies = append(copies, c)
+			}
+		}
+		if rights == memory.READ_WRITE {
+			copies = append([]byte{message.From}, copies...)
+		} else {
+			copies = append(copies, message.From)
+		}
+		m.putCopies(s, vpage, copies)
+		s.Unlock()
+	}
+	held, read := make(map[int]bool), make(map[int]bool)
+	for _, id := range locks {
+		if id < 0 {
+			//locks held in shared mode are sent as -id-1
+			id = -id - 1
+			read[id] = true
+			if !m.holdsRead(id, message.From) {
+				m.addReader(id, message.From)
+				go m.HandleReadLockAcquire(id)
+			}
+			continue
+		}
+		held[id] = true
+		if !m.holds(id, message.From) {
+			m.setLockHolder(id, message.From)
+			go m.HandleLockAcquire(id)
+		}
+	}
+	m.replLock.Lock()
+	released := make([]int, 0)
+	for id, h := range m.lockHolders {
+		if h == message.From && !held[id] {
+			released = append(released, id)
+		}
+	}
+	readReleased := make([]int, 0)
+	for id, readers := range m.readHolders {
+		if readers[message.From] && !read[id] {
+			readReleased = append(readReleased, id)
+		}
+	}
+	m.replLock.Unlock()
+	for _, id := range released {
+		m.clearLockHolder(id)
+		m.HandleLockRelease(id, message.From)
+	}
+	for _, id := range readReleased {
+		m.removeReader(id, message.From)
+		m.HandleReadLockRelease(id)
+	}
+}
+
+//managerId returns the host the manager currently runs on.
+func (m *Multiview) managerId() byte {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	return m.managerHost
+}
+
+//sendToManager sends a message to the manager. Requests are kept until they are answered, so they can be sent again to a new manager.
+func (m *Multiview) sendToManager(msg network.MultiviewMessage) error {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	msg.To = m.managerHost
+	if msg.EventId != 0 {
+		m.pending[msg.EventId] = msg
+	}
+	m.logMessage(msg)
+	return m.conn.Send(msg)
+}
+
+//newEvent registers the channel that receives the answer to a request, and returns the event id of the request.
+func (m *Multiview) newEvent(c chan string) int {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	m.sequenceNumber++
+	m.chanMap[m.sequenceNumber] = c
+	return m.sequenceNumber
+}
+
+//eventChan returns the channel that receives the answer to the request with the event id.
+func (m *Multiview) eventChan(eventId int) chan string {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	return m.chanMap[eventId]
+}
+
+//forget is called when a request has been answered. It returns the manager that answered it.
+func (m *Multiview) forget(eventId int) byte {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	m.chanMap[eventId] = nil
+	to := m.pending[eventId].To
+	delete(m.pending, eventId)
+	return to
+}
+
+//deliver passes an answer to the caller waiting for it. Answers to requests that have been given up are dropped.
+func (m *Multiview) deliver(eventId int, res string) {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	if c := m.chanMap[eventId]; c != nil {
+		c <- res
+	}
+}
+
+//cancelLock gives up a lock request, and tells the manager to cancel it.
+//It returns false if the lock was granted before the request could be given up.
+func (m *Multiview) cancelLock(id int, eventId int) bool {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	c := m.chanMap[eventId]
+	m.chanMap[eventId] = nil
+	delete(m.pending, eventId)
+	select {
+	case <-c:
+		return false
+	default:
+	}
+	msg := network.MultiviewMessage{
+		Type:    LOCK_CANCEL,
+		From:    m.Id,
+		To:      m.managerHost,
+		Id:      id,
+		EventId: eventId,
+	}
+	m.logMessage(msg)
+	m.conn.Send(msg)
+	return true
+}
+
+//handleNewManager switches to a new manager. It is told which minipages and locks this host has,
+//and the requests that haven't been answered are sent to it again.
+func (m *Multiview) handleNewManager(msg network.MultiviewMessage) {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	m.managerHost = byte(msg.Id)
+	rejoin := network.MultiviewMessage{
+		Type:   REJOIN,
+		From:   m.Id,
+		To:     m.managerHost,
+		IntArr: make([]int, 0),
+	}
+	npages := m.GetMemoryByteSize() / m.GetPageSize()
+	for vpage := npages; vpage < len(m.mem.accessMap); vpage++ {
+		if rights := m.getInAccessMap(vpage); rights != memory.NO_ACCESS {
+			rejoin.IntArr = append(rejoin.IntArr, vpage, int(rights))
+		}
+	}
+	rejoin.Id = len(rejoin.IntArr)
+	for id, held := range m.hasLock {
+		if held {
+			rejoin.IntArr = append(rejoin.IntArr, id)
+		}
+	}
+	for id, held := range m.hasReadLock {
+		if held {
+			rejoin.IntArr = append(rejoin.IntArr, -id-1)
+		}
+	}
+	m.conn.Send(rejoin)
+	m.logMessage(rejoin)
+	for i, p := range m.pending {
+		p.To = m.managerHost
+		m.pending[i] = p
+		m.conn.Send(p)
+		m.logMessage(p)
+	}
+}
+
+//StartStandby runs a standby of the manager on this host, which takes over if the manager fails.
+//address is the address of the manager. It must be called before anything is allocated.
+func (m *Multiview) StartStandby(address string) error {
+	s := NewUpdatedManager(memory.NewVmem(m.GetMemoryByteSize(), m.GetPageSize()), nil, nil)
+	s.SetShouldLogNetwork(m.messagesSent.isEnabled())
+	s.SetLogger(m.logger)
+	c := make(chan bool)
+	handler := func(message network.Message) error {
+		if _, ok := message.(network.SimpleMessage); ok {
+			s.myId = message.GetTo()
+			s.logger.SetHost(int(s.myId))
+			c <- true
+			return nil
+		}
+		return s.HandleMessage(message)
+	}
+	client := network.NewP2PClient(handler)
+	client.SetCompression(m.compress)
+	client.SetLogger(m.logger)
+	s.conn = client
+	if err := client.Connect(address); err != nil {
+		return err
+	}
+	<-c
+	m.standby = s
+	s.register()
+	return nil
+}
@@ -0,0 +1,80 @@
+package multiview
+
+import "sync"
+
+//shardCount is the number of shards the vpage state of the manager is split in.
+//Requests on vpages in different shards never wait for each other.
+const shardCount = 64
+
+//shard holds the state of the vpages whose number modulo shardCount is its index.
+type shard struct {
+	*sync.RWMutex
+	mpt     map[int]minipage      //Minipagetable
+	copies  map[int][]byte        //A map of who has copies of what vpage
+	locks   map[int]*sync.RWMutex //A map of locks belonging to each vpage.
+	writers map[int]byte          //The last host to write to each vpage.
+	updates map[int]bool          //The vpages that use write-update.
+}
+
+func newShards() []*shard {
+	shards := make([]*shard, shardCount)
+	for i := range shards {
+		shards[i] = &shard{
+			RWMutex: new(sync.RWMutex),
+			mpt:     make(map[int]minipage),
+			copies:  make(map[int][]byte),
+			locks:   make(map[int]*sync.RWMutex),
+			writers: make(map[int]byte),
+			updates: make(map[int]bool),
+		}
+	}
+	return shards
+}
+
+func (m *Manager) shardOf(vpage int) *shard {
+	return m.shards[vpage%shardCount]
+}
+
+func (m *Manager) getMinipage(vpage int) (minipage, bool) {
+	s := m.shardOf(vpage)
+	s.RLock()
+	defer s.RUnlock()
+	mp, ok := s.mpt[vpage]
+	return mp, ok
+}
+
+//vpageLock returns the lock of a vpage, which is held while a request on the vpage is in progress.
+func (m *Manager) vpageLock(vpage int) *sync.RWMutex {
+	s := m.shardOf(vpage)
+	s.RLock()
+	defer s.RUnlock()
+	return s.locks[vpage]
+}
+
+func (m *Manager) getWriter(vpage int) (byte, bool) {
+	s := m.shardOf(vpage)
+	s.RLock()
+	defer s.RUnlock()
+	w, ok := s.writers[vpage]
+	return w, ok
+}
+
+func (m *Manager) setWriter(vpage int, writer byte) {
+	s := m.shardOf(vpage)
+	s.Lock()
+	s.writers[vpage] = writer
+	s.Unlock()
+}
+
+//minipageTable returns the minipages of all vpages in use.
+func (m *Manager) minipageTable() map[int]minipage {
+	res := make(map[int]minipage)
+	for _, s := range m.shards {
+		s.RLock()
+		for vpage, mp := range s.mpt {
+			res[vpage] = mp
+		}
+		s.RUnlock()
+	}
+	return res
+}
@@ -1,10 +1,11 @@
 package multiview
 
 import (
+	"DSM-project/memory"
+	"DSM-project/network"
+	"encoding/binary"
 	"fmt"
 	"github.com/stretchr/testify/assert"
-	"io/ioutil"
-	"log"
 	"math"
 	"sync"
 	"testing"
@@ -25,7 +26,6 @@ func TestInitialize(t *testing.T) {
 }
 
 func TestMalloc(t *testing.T) {
-	log.SetOutput(ioutil.Discard)
 	mw := NewMultiView()
 	mw.Initialize(4096, 128, 1)
 	ptr, err := mw.Malloc(100)
@@ -40,6 +40,21 @@ func TestMalloc(t *testing.T) {
 	mw.Shutdown()
 }
 
+func TestMalloc_PageIsolated(t *testing.T) {
+	mw := NewMultiView()
+	mw.Initialize(4096, 128, 1)
+	defer mw.Shutdown()
+	_, err := mw.Malloc(100)
+	assert.Nil(t, err)
+	ptr, err := mw.Malloc(10, memory.PageIsolated)
+	assert.Nil(t, err)
+	//the minipage starts at the beginning of a fresh physical page
+	assert.Equal(t, 0, ptr%128)
+	ptr2, err := mw.Malloc(10)
+	assert.Nil(t, err)
+	assert.NotEqual(t, (ptr%4096)/128, (ptr2%4096)/128)
+}
+
 func TestMultipleHosts(t *testing.T) {
 	mw1 := NewMultiView()
 	mw2 := NewMultiView()
@@ -138,7 +153,8 @@ func TestMultiview_Lock(t *testing.T) {
 func TestMultiview_MultiMalloc2(t *testing.T) {
 	mw1 := NewMultiView()
 	gridSize := 1024
-	mw1.Initialize(gridSize*gridSize*4, 4096, 1)
+	assert.Nil(t, mw1.Initialize(gridSize*gridSize*4, 4096, 1))
+	defer mw1.Shutdown()
 	req := make([]int, gridSize*gridSize)
 	for i := range req {
 		req[i] = 4
@@ -274,7 +290,8 @@ func TestReacquireLock(t *testing.T) {
 
 func TestMultiview_MultiMalloc(t *testing.T) {
 	mw1 := NewMultiView()
-	mw1.Initialize(4104, 4096, 1)
+	assert.Nil(t, mw1.Initialize(4104, 4096, 1))
+	defer mw1.Shutdown()
 	addrs, _ := mw1.MultiMalloc([]int{10, 20, 100, 1000})
 	fmt.Println(addrs)
 }
@@ -303,3 +320,399 @@ func TestMemoryMalloc(t *testing.T) {
 	assert.Equal(t, 2*4096, addr1)
 	mw1.Shutdown()
 }
+
+func TestMultiview_MallocArray(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw1.Initialize(1024, 64, 2)
+	mw2.Join(1024, 64)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+
+	_, err := mw1.MallocArray(10, 3, 0)
+	assert.Equal(t, InvalidElementSizeErr, err)
+	_, err = mw1.MallocArray(10, 4, 6)
+	assert.Equal(t, InvalidGranularityErr, err)
+
+	a, err := mw1.MallocArray(20, 4, 8)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, a.Granularity)
+	//two elements share a minipage, the next pair lives in another view of the same page
+	assert.Equal(t, a.Addr(0)+4, a.Addr(1))
+	assert.Equal(t, a.Addr(0)%1024+8, a.Addr(2)%1024)
+	assert.NotEqual(t, a.Addr(0)/64, a.Addr(2)/64)
+
+	b, err := mw2.LookupArray(a.Base())
+	assert.Nil(t, err)
+	assert.Equal(t, a, b)
+	_, err = mw2.LookupArray(a.Base() + 1)
+	assert.NotNil(t, err)
+
+	for i := 0; i < 20; i++ {
+		mw1.Write(a.Addr(i), byte(i))
+	}
+	//host 2 writes the second half, so runs of 40 bytes were written by the same host
+	for i := 10; i < 20; i++ {
+		mw2.Write(b.Addr(i), byte(2*i))
+	}
+	for i := 0; i < 20; i++ {
+		res, _ := mw1.Read(a.Addr(i))
+		if i < 10 {
+			assert.Equal(t, byte(i), res)
+		} else {
+			assert.Equal(t, byte(2*i), res)
+		}
+	}
+
+	c, err := mw1.MallocArray(20, 4, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 40, c.Granularity)
+	assert.Equal(t, c.Addr(0)+36, c.Addr(9))
+
+	assert.Nil(t, mw1.Free(a.Base(), a.Size()))
+	_, err = mw2.LookupArray(a.Base())
+	assert.NotNil(t, err)
+}
+
+func TestMultiview_NamedArray(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw1.Initialize(1024, 64, 2)
+	mw2.Join(1024, 64)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+
+	//the named array is not the first allocation, so only its name tells the other host where it is
+	_, err := mw1.MallocArray(10, 4, 4)
+	assert.Nil(t, err)
+	a, err := mw1.MallocNamedArray("grid", 20, 4, 8)
+	assert.Nil(t, err)
+	_, err = mw1.MallocNamedArray("grid", 20, 4, 8)
+	assert.Equal(t, NameTakenErr.Error(), err.Error())
+
+	b, err := mw2.LookupNamedArray("grid")
+	assert.Nil(t, err)
+	assert.Equal(t, a, b)
+	_, err = mw2.LookupNamedArray("other")
+	assert.Equal(t, NoSuchNameErr.Error(), err.Error())
+
+	assert.Nil(t, mw1.Free(a.Base(), a.Size()))
+	_, err = mw2.LookupNamedArray("grid")
+	assert.NotNil(t, err)
+	_, err = mw1.MallocNamedArray("grid", 20, 4, 8)
+	assert.Nil(t, err)
+}
+
+func TestMultiview_ViewExhaustion(t *testing.T) {
+	mw := NewMultiView()
+	mw.Initialize(64, 8, 1)
+	defer mw.Shutdown()
+	//every byte of the first page gets its own minipage, and there are only 7 views
+	addrs := make([]int, 7)
+	for i := range addrs {
+		ptr, err := mw.Malloc(1)
+		assert.Nil(t, err)
+		assert.Equal(t, (i+1)*64+i, ptr)
+		addrs[i] = ptr
+	}
+	_, err := mw.Malloc(1)
+	assert.EqualError(t, err, NoFreeViewErr.Error())
+	stats := mw.ViewStats()
+	assert.Equal(t, 7, stats.Used)
+	assert.Equal(t, 7, len(stats.PerView))
+	assert.Equal(t, 7.0/56.0, stats.Utilisation)
+
+	//the freed vpage is used again
+	assert.Nil(t, mw.Free(addrs[2], 1))
+	assert.Equal(t, 6, mw.ViewStats().Used)
+	ptr, err := mw.Malloc(1)
+	assert.Nil(t, err)
+	assert.Equal(t, addrs[2], ptr)
+	assert.Equal(t, 7, mw.ViewStats().Used)
+
+	_, err = mw.MultiMalloc([]int{1})
+	assert.NotNil(t, err)
+}
+
+func TestMultiview_RangeFaults(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw1.SetShouldLogNetwork(true)
+	mw1.Initialize(1024, 32, 2)
+	mw2.Join(1024, 32)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+
+	ptr, _ := mw2.Malloc(100)
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	assert.Nil(t, mw2.WriteBytes(ptr, data))
+
+	//the range covers 4 minipages, which are fetched with a single request and acknowledged with a single message
+	reads, readAcks := mw1.messagesSent.count(READ_REQUEST), mw1.messagesSent.count(READ_ACK)
+	res, err := mw1.ReadBytes(ptr, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, data, res)
+	assert.Equal(t, 1, mw1.messagesSent.count(READ_REQUEST)-reads)
+	assert.Equal(t, 1, mw1.messagesSent.count(READ_ACK)-readAcks)
+
+	for i := range data {
+		data[i] = byte(2 * i)
+	}
+	writes, writeAcks := mw1.messagesSent.count(WRITE_REQUEST), mw1.messagesSent.count(WRITE_ACK)
+	assert.Nil(t, mw1.WriteBytes(ptr+10, data[10:90]))
+	assert.Equal(t, 1, mw1.messagesSent.count(WRITE_REQUEST)-writes)
+	assert.Equal(t, 1, mw1.messagesSent.count(WRITE_ACK)-writeAcks)
+	res, err = mw2.ReadBytes(ptr, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(5), res[5])
+	assert.Equal(t, data[10:90], res[10:90])
+	assert.Equal(t, byte(95), res[95])
+}
+
+func TestMultiview_Prefetch(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw1.SetShouldLogNetwork(true)
+	mw1.Initialize(1024, 32, 2)
+	mw2.Join(1024, 32)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+
+	ptr, _ := mw2.Malloc(100)
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	assert.Nil(t, mw2.WriteBytes(ptr, data))
+
+	reads := mw1.messagesSent.count(READ_REQUEST)
+	assert.Nil(t, <-mw1.Prefetch(ptr, 100))
+	assert.Equal(t, 1, mw1.messagesSent.count(READ_REQUEST)-reads)
+	res, err := mw1.ReadBytes(ptr, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, data, res)
+	assert.Equal(t, 1, mw1.messagesSent.count(READ_REQUEST)-reads, "The prefetched minipages are read without faults.")
+	assert.NotNil(t, <-mw1.Prefetch(ptr, 1<<20))
+}
+
+func TestMultiview_WriteUpdate(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw3 := NewMultiView()
+	mw3.SetShouldLogNetwork(true)
+	mw1.Initialize(1024, 32, 3)
+	mw2.Join(1024, 32)
+	mw3.Join(1024, 32)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+	defer mw3.Leave()
+
+	ptr, err := mw2.Malloc(10, memory.WriteUpdate)
+	assert.Nil(t, err)
+	vpage := ptr / 32
+	mw2.Write(ptr, 1)
+	res, _ := mw3.Read(ptr)
+	assert.Equal(t, byte(1), res)
+	//the reader doesn't take away the write access of the owner, and later writes don't invalidate the reader
+	assert.Equal(t, memory.READ_WRITE, mw2.getInAccessMap(vpage))
+	mw2.Write(ptr, 2)
+	assert.Equal(t, memory.READ_ONLY, mw3.getInAccessMap(vpage))
+
+	//the release pushes the data to the reader
+	reads := mw3.messagesSent.count(READ_REQUEST)
+	mw2.Lock(1)
+	mw2.Write(ptr, 3)
+	mw2.Release(1)
+	res, _ = mw3.Read(ptr)
+	assert.Equal(t, byte(3), res)
+	assert.Equal(t, 0, mw3.messagesSent.count(READ_REQUEST)-reads)
+
+	//a write by the reader moves the ownership
+	mw3.Lock(1)
+	mw3.Write(ptr+1, 7)
+	mw3.Release(1)
+	assert.Equal(t, memory.READ_ONLY, mw2.getInAccessMap(vpage))
+	res, _ = mw2.Read(ptr + 1)
+	assert.Equal(t, byte(7), res)
+	res, _ = mw2.Read(ptr)
+	assert.Equal(t, byte(3), res)
+}
+
+func TestMultiview_Failover(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw3 := NewMultiView()
+	mw1.Initialize(1024, 32, 3)
+	mw2.Join(1024, 32)
+	mw3.Join(1024, 32)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+	defer mw3.Leave()
+	assert.Nil(t, mw3.StartStandby("localhost:2000"))
+
+	ptr, err := mw2.Malloc(100)
+	assert.Nil(t, err)
+	mw2.Lock(1)
+	mw2.Write(ptr, 5)
+	res, _ := mw3.Read(ptr)
+	assert.Equal(t, byte(5), res)
+
+	//the primary goes down in the middle of the run, taking its connections with it
+	mw1.manager.crash()
+	assert.Equal(t, network.ServerClosedErr, mw1.manager.conn.Send(network.MultiviewMessage{}))
+	time.Sleep(failoverTimeout + time.Second)
+	assert.Equal(t, mw3.standby.myId, mw2.managerId())
+
+	//the standby knows the copy set and the lock holder
+	mw3.Write(ptr, 6)
+	res, _ = mw2.Read(ptr)
+	assert.Equal(t, byte(6), res)
+	done := make(chan bool)
+	go func() {
+		mw1.Lock(1)
+		mw1.Release(1)
+		done <- true
+	}()
+	time.Sleep(time.Millisecond * 100)
+	mw2.Release(1)
+	<-done
+
+	//allocations don't overlap the ones made before the failure
+	ptr2, err := mw1.Malloc(100)
+	assert.Nil(t, err)
+	assert.NotEqual(t, ptr%1024, ptr2%1024)
+	res, _ = mw1.Read(ptr)
+	assert.Equal(t, byte(6), res)
+
+	go mw1.Barrier(1)
+	go mw3.Barrier(1)
+	mw2.Barrier(1)
+}
+
+func TestMultiview_ReadLock(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw3 := NewMultiView()
+	mw1.Initialize(1024, 32, 3)
+	mw2.Join(1024, 32)
+	mw3.Join(1024, 32)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+	defer mw3.Leave()
+
+	ptr, _ := mw1.Malloc(100)
+	mw1.Lock(1)
+	mw1.Write(ptr, 7)
+	mw1.Release(1)
+
+	//both readers hold the lock at once
+	mw2.AcquireReadLock(1)
+	mw3.AcquireReadLock(1)
+	res, _ := mw2.Read(ptr)
+	assert.Equal(t, byte(7), res)
+	res, _ = mw3.Read(ptr)
+	assert.Equal(t, byte(7), res)
+
+	//a writer waits for the readers to leave
+	acquired := make(chan bool)
+	go func() {
+		mw1.Lock(1)
+		acquired <- true
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("got the lock while it was held in shared mode")
+	case <-time.After(time.Millisecond * 100):
+	}
+	mw2.ReleaseReadLock(1)
+	mw3.ReleaseReadLock(1)
+	<-acquired
+	mw1.Write(ptr, 8)
+	mw1.Release(1)
+
+	mw2.AcquireReadLock(1)
+	res, _ = mw2.Read(ptr)
+	assert.Equal(t, byte(8), res)
+	mw2.ReleaseReadLock(1)
+}
+
+func TestMultiview_LockTimeout(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw3 := NewMultiView()
+	mw1.Initialize(1024, 32, 3)
+	mw2.Join(1024, 32)
+	mw3.Join(1024, 32)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+	defer mw3.Leave()
+
+	ptr, _ := mw1.Malloc(100)
+	assert.True(t, mw1.TryAcquireLock(1))
+	assert.False(t, mw2.TryAcquireLock(1), "The lock is held by host 1.")
+	mw1.Write(ptr, 7)
+
+	//host 2 gives up while host 3 waits behind it
+	acquired := make(chan bool)
+	assert.False(t, mw2.AcquireLockTimeout(1, time.Millisecond*100))
+	go func() {
+		mw3.Lock(1)
+		acquired <- true
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("got the lock while it was held")
+	case <-time.After(time.Millisecond * 100):
+	}
+	mw1.Release(1)
+	<-acquired
+	res, _ := mw3.Read(ptr)
+	assert.Equal(t, byte(7), res)
+	mw3.Release(1)
+
+	assert.True(t, mw2.AcquireLockTimeout(1, time.Second))
+	assert.False(t, mw1.TryAcquireLock(1))
+	mw2.Release(1)
+	assert.True(t, mw1.TryAcquireLock(1))
+	mw1.Release(1)
+}
+
+func TestMultiview_Atomic(t *testing.T) {
+	mw1 := NewMultiView()
+	mw2 := NewMultiView()
+	mw3 := NewMultiView()
+	mw1.Initialize(1024, 32, 3)
+	mw2.Join(1024, 32)
+	mw3.Join(1024, 32)
+	defer mw1.Shutdown()
+	defer mw2.Leave()
+	defer mw3.Leave()
+
+	ptr, _ := mw1.Malloc(16)
+	group := new(sync.WaitGroup)
+	group.Add(3)
+	for _, mw := range []*Multiview{mw1, mw2, mw3} {
+		go func(mw *Multiview) {
+			for i := 0; i < 50; i++ {
+				_, err := mw.FetchAndAdd(ptr, 1)
+				assert.Nil(t, err)
+			}
+			group.Done()
+		}(mw)
+	}
+	group.Wait()
+	b, _ := mw2.ReadBytes(ptr, 8)
+	assert.Equal(t, int64(150), int64(binary.LittleEndian.Uint64(b)))
+
+	swapped, _ := mw3.CompareAndSwap(ptr, 150, 7)
+	assert.True(t, swapped)
+	swapped, _ = mw1.CompareAndSwap(ptr, 150, 8)
+	assert.False(t, swapped)
+	res, _ := mw2.Swap(ptr, 9)
+	assert.Equal(t, int64(7), res)
+	_, err := mw1.FetchAndAdd(ptr-ptr%32+28, 1)
+	assert.NotNil(t, err, "The int64 isn't in a single minipage.")
+}
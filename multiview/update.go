@@ -0,0 +1,169 @@
+package multiview
+
+import (
+	"DSM-project/memory"
+	"DSM-project/network"
+	"bytes"
+	"strconv"
+)
+
+//ownedMinipage is a write-update minipage this host has owned since its last push.
+type ownedMinipage struct {
+	addr, privbase, size int
+	pushed               []byte //the data at the last push
+}
+
+//splitWriteUpdate removes the memory.WriteUpdate flag from the flags, and tells if it was there.
+//A write fault on a write-update minipage makes the writer its owner without invalidating the other copies,
+//and the owner pushes the minipage to all copy holders at its next release or barrier.
+func splitWriteUpdate(flags []memory.AllocFlag) ([]memory.AllocFlag, bool) {
+	res := make([]memory.AllocFlag, 0, len(flags))
+	found := false
+	for _, f := range flags {
+		if f == memory.WriteUpdate {
+			found = true
+		} else {
+			res = append(res, f)
+		}
+	}
+	return res, found
+}
+
+func (m *Manager) isWriteUpdate(vpage int) bool {
+	s := m.shardOf(vpage)
+	s.RLock()
+	defer s.RUnlock()
+	return s.updates[vpage]
+}
+
+//handleUpdateWrite gives the writer the minipage from the owner, who is first in the copy set, without invalidating anything.
+func (m *Manager) handleUpdateWrite(message network.MultiviewMessage, vpage int) {
+	message.Type = UPDATE_REQUEST
+	message.To = m.getCopies(vpage)[0]
+	m.conn.Send(message)
+	m.logMessage(message)
+}
+
+//handleUpdate forwards pushed minipage data to the copy holders, except the pusher and the current owner.
+//The pusher is told how many acknowledgements to wait for, which the copy holders send to it directly.
+//A write fault that is still in progress on the vpage is waited for, as it changes the owner.
+func (m *Manager) handleUpdate(message network.MultiviewMessage) {
+	vpage := message.Fault_addr / m.vm.GetPageSize()
+	l := m.vpageLock(vpage)
+	l.RLock()
+	copies := m.getCopies(vpage)
+	l.RUnlock()
+	n := 0
+	for i, c := range copies {
+		if c == message.From || i == 0 {
+			continue
+		}
+		message.To = c
+		m.conn.Send(message)
+		m.logMessage(message)
+		n++
+	}
+	reply := network.MultiviewMessage{
+		Type:    UPDATE_ACK,
+		From:    m.myId,
+		To:      message.From,
+		EventId: message.EventId,
+		Id:      n,
+	}
+	m.conn.Send(reply)
+	m.logMessage(reply)
+}
+
+//handleUpdateRequest sends the minipage to the new owner, keeping a read only copy.
+func (m *Multiview) handleUpdateRequest(msg network.MultiviewMessage) {
+	vpagenr := m.mem.getVPageNr(msg.Fault_addr)
+	if m.getInAccessMap(vpagenr) == memory.READ_WRITE {
+		m.setInAccessMap(vpagenr, memory.READ_ONLY)
+	}
+	msg.Type = UPDATE_REPLY
+	msg.To = msg.From
+	msg.Data = m.mem.vm.PrivilegedRead(msg.Privbase, msg.Minipage_size)
+	m.conn.Send(msg)
+	m.logMessage(msg)
+}
+
+//handleUpdateReply makes this host the owner of the minipage.
+func (m *Multiview) handleUpdateReply(msg network.MultiviewMessage) {
+	m.mem.vm.PrivilegedWrite(msg.Privbase, msg.Data)
+	vpagenr := m.mem.getVPageNr(msg.Fault_addr)
+	m.updatesLock.Lock()
+	if _, ok := m.updates[vpagenr]; !ok {
+		m.updates[vpagenr] = &ownedMinipage{
+			addr:     msg.Fault_addr,
+			privbase: msg.Privbase,
+			size:     msg.Minipage_size,
+		}
+	}
+	m.updatesLock.Unlock()
+	m.setInAccessMap(vpagenr, memory.READ_WRITE)
+	m.eventChan(msg.EventId) <- "done"
+}
+
+//handlePushedUpdate applies minipage data pushed by its owner.
+func (m *Multiview) handlePushedUpdate(msg network.MultiviewMessage) {
+	m.mem.vm.PrivilegedWrite(msg.Privbase, msg.Data)
+	msg.Type = UPDATE_ACK
+	msg.To = msg.From
+	msg.From = m.Id
+	msg.Data = nil
+	msg.Id = -1
+	m.conn.Send(msg)
+	m.logMessage(msg)
+}
+
+//isOwned tells if this host owns a write-update minipage of the vpage.
+func (m *Multiview) isOwned(vpagenr int) bool {
+	m.updatesLock.Lock()
+	defer m.updatesLock.Unlock()
+	_, ok := m.updates[vpagenr]
+	return ok
+}
+
+//pushUpdates sends the write-update minipages that changed since the last push to all their copy holders,
+//and waits until they have all been applied. Minipages this host no longer owns are pushed a last time.
+func (m *Multiview) pushUpdates() {
+	m.updatesLock.Lock()
+	defer m.updatesLock.Unlock()
+	for vpagenr, o := range m.updates {
+		data := m.mem.vm.PrivilegedRead(o.privbase, o.size)
+		if o.pushed == nil || !bytes.Equal(o.pushed, data) {
+			m.push(o, data)
+			o.pushed = data
+		}
+		if m.getInAccessMap(vpagenr) != memory.READ_WRITE {
+			delete(m.updates, vpagenr)
+		}
+	}
+}
+
+func (m *Multiview) push(o *ownedMinipage, data []byte) {
+	c := make(chan string)
+	i := m.newEvent(c)
+	msg := network.MultiviewMessage{
+		Type:          UPDATE,
+		From:          m.Id,
+		To:            m.managerId(),
+		EventId:       i,
+		Fault_addr:    o.addr,
+		Privbase:      o.privbase,
+		Minipage_size: o.size,
+		Data:          data,
+	}
+	m.sendToManager(msg)
+	//the manager says how many copy holders there are, and each of them acknowledges
+	acks, expected := 0, -1
+	for expected < 0 || acks < expected {
+		n, _ := strconv.Atoi(<-c)
+		if n >= 0 {
+			expected = n
+		} else {
+			acks++
+		}
+	}
+	m.forget(i)
+}
@@ -1,6 +1,8 @@
 package network
 
 import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
 	"testing"
 	"os"
 	"log"
@@ -41,4 +43,39 @@ func TestNewCSVLogger(t *testing.T) {
 	l.Log(msg, MessageEncoder{})
 	time.Sleep(time.Millisecond * 500)
 	l.Close()
-}
\ No newline at end of file
+}
+
+func TestLogger_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	root := NewLogger(&buf, LogInfo)
+	l := root.For("manager")
+	l.SetHost(3)
+	l.Debugf("dropped %v", 1)
+	assert.Equal(t, 0, buf.Len())
+	l.Infof("kept %v", 2)
+	assert.Contains(t, buf.String(), "INFO  manager host 3: kept 2")
+
+	//the level is shared with the root
+	root.SetLevel(LogError)
+	buf.Reset()
+	l.Warnf("dropped")
+	assert.Equal(t, 0, buf.Len())
+	l.Errorf("kept")
+	assert.Contains(t, buf.String(), "ERROR manager host 3: kept")
+}
+
+func TestLogger_Disabled(t *testing.T) {
+	var nilLogger *Logger
+	assert.False(t, nilLogger.Enabled(LogError))
+	nilLogger.Errorf("nothing")
+	assert.Nil(t, nilLogger.For("host"))
+
+	l := NewLogger(new(bytes.Buffer), LogOff).For("host")
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Debugf("received message")
+		if l.Enabled(LogDebug) {
+			l.Debugf("received %v", t)
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -77,3 +79,110 @@ func (l *CSVStructLogger) Enable() {
 func (l *CSVStructLogger) Close() {
 	close(l.consumerChan)
 }
+
+//LogLevel is how much a Logger writes. Records of a level above the level of the logger are dropped.
+type LogLevel int32
+
+const (
+	LogOff LogLevel = iota
+	LogError
+	LogWarn
+	LogInfo
+	LogDebug
+)
+
+var levelNames = []string{"OFF", "ERROR", "WARN", "INFO", "DEBUG"}
+
+func (l LogLevel) String() string {
+	if l < LogOff || int(l) >= len(levelNames) {
+		return fmt.Sprint("LogLevel(", int32(l), ")")
+	}
+	return levelNames[l]
+}
+
+//Logger is the leveled logger of the hosts, managers and connections. Each record is tagged with the component
+//that wrote it and the id of its host. A nil Logger writes nothing.
+//Callers on hot paths check Enabled first, so a disabled logger doesn't even build the arguments.
+type Logger struct {
+	level     *int32 //shared by all loggers made from the same root by For
+	out       *log.Logger
+	component string
+	host      int32 //-1 until the host knows its id
+}
+
+var defaultLogger = NewLogger(os.Stderr, LogWarn)
+
+//NewLogger returns a logger writing records of at most the given level to w.
+func NewLogger(w io.Writer, level LogLevel) *Logger {
+	lvl := int32(level)
+	return &Logger{
+		level:     &lvl,
+		out:       log.New(w, "", log.LstdFlags|log.Lmicroseconds),
+		component: "dsm",
+		host:      -1,
+	}
+}
+
+//DefaultLogger is the logger hosts use until they are given one. It writes warnings and errors to stderr.
+func DefaultLogger() *Logger {
+	return defaultLogger
+}
+
+//For returns a logger for a component, sharing the output and level of l. Its host id is unset.
+func (l *Logger) For(component string) *Logger {
+	if l == nil {
+		return nil
+	}
+	return &Logger{
+		level:     l.level,
+		out:       l.out,
+		component: component,
+		host:      -1,
+	}
+}
+
+//SetHost sets the host id records are tagged with.
+func (l *Logger) SetHost(id int) {
+	if l != nil {
+		atomic.StoreInt32(&l.host, int32(id))
+	}
+}
+
+//SetLevel sets the level of l and of all loggers sharing its level.
+func (l *Logger) SetLevel(level LogLevel) {
+	if l != nil {
+		atomic.StoreInt32(l.level, int32(level))
+	}
+}
+
+func (l *Logger) Enabled(level LogLevel) bool {
+	return l != nil && level <= LogLevel(atomic.LoadInt32(l.level))
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(LogError, format, args)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(LogWarn, format, args)
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(LogInfo, format, args)
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(LogDebug, format, args)
+}
+
+func (l *Logger) logf(level LogLevel, format string, args []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	host := atomic.LoadInt32(&l.host)
+	if host < 0 {
+		l.out.Printf("%-5v %v: %v", level, l.component, fmt.Sprintf(format, args...))
+	} else {
+		l.out.Printf("%-5v %v host %v: %v", level, l.component, host, fmt.Sprintf(format, args...))
+	}
+}
@@ -24,16 +24,20 @@ type P2PClient struct {
 	handler  func(Message) error
 	running  bool
 	in       <-chan []byte
-	out      chan<- []byte
 	shutdown chan bool
 	group    *sync.WaitGroup
+	compress bool
+	codec    *Codec
+	logger   *Logger
 }
 
 func NewP2PClient(handler func(Message) error) *P2PClient {
 	c := new(P2PClient)
 	c.handler = handler
 	c.group = new(sync.WaitGroup)
+	c.codec = new(Codec)
 	c.shutdown = make(chan bool)
+	c.logger = DefaultLogger()
 	return c
 }
 
@@ -41,12 +45,14 @@ func (c *P2PClient) Connect(address string) error {
 	ip, port := utils.StringToIpAndPort(address)
 	err := errors.New("Not connected yet.")
 	for i := 1; i < 20 && err != nil; i++ {
-		c.conn, c.in, c.out, err = NewConnection(port+i, 1000)
+		c.conn, c.in, _, err = NewConnection(port+i, 1000)
 	}
 	if err != nil {
 		panic(fmt.Sprint("Couldn't start listener. Tried ports ", port+1, " - ", port+19, ".\n"+
 			"Error was ", err.Error()))
 	}
+	c.conn.SetCompression(c.compress)
+	c.conn.SetLogger(c.logger)
 	myId, _ := c.conn.Connect(ip, port)
 	go c.recieveLoop()
 	welcomeMsg := SimpleMessage{From: 255, To: byte(myId), Type: "WELC"}
@@ -66,9 +72,13 @@ Loop:
 		case <-c.shutdown:
 			break Loop
 		}
-		buf.Write(data[1:])
+		body, err := Decode(data[1:])
+		if err != nil {
+			panic(err.Error())
+		}
+		buf.Write(body)
 		var multiviewMsg MultiviewMessage
-		_, err := xdr.Unmarshal(buf, &multiviewMsg)
+		_, err = xdr.Unmarshal(buf, &multiviewMsg)
 		if err != nil {
 			panic(err.Error())
 		}
@@ -90,12 +100,37 @@ func (c *P2PClient) Send(message Message) error {
 	if err != nil {
 		panic("Error: " + err.Error())
 	}
-	data := make([]byte, w.Len()+1)
+	body := c.codec.Encode(w.Bytes(), c.conn.Compresses(int(msg.GetTo())))
+	data := make([]byte, len(body)+1)
 	data[0] = msg.GetTo()
 
-	w.Read(data[1:])
-	c.out <- data
-	return nil
+	copy(data[1:], body)
+	return c.conn.Send(data)
+}
+
+//SetCompression sets whether messages are compressed on the wire. Call it before Connect.
+func (c *P2PClient) SetCompression(b bool) {
+	c.compress = b
+}
+
+//SetLogger sets the logger handed to the connection. Call it before Connect.
+func (c *P2PClient) SetLogger(l *Logger) {
+	c.logger = l
+}
+
+//Compresses reports whether messages to the given host are compressed.
+func (c *P2PClient) Compresses(id byte) bool {
+	return c.conn.Compresses(int(id))
+}
+
+//BytesSent returns the number of bytes of the messages sent, before and after compression.
+func (c *P2PClient) BytesSent() (raw, wire int) {
+	return c.codec.Stats()
+}
+
+//Peers returns the ids of the hosts this client is connected to.
+func (c *P2PClient) Peers() []int {
+	return c.conn.Peers()
 }
 
 func (c *P2PClient) GetTransciever() ITransciever {
@@ -0,0 +1,77 @@
+package network
+
+import (
+	"errors"
+	"github.com/golang/snappy"
+	"sync/atomic"
+)
+
+//The first byte of every encoded message tells how the rest of it was encoded.
+const (
+	rawCodec    byte = 0
+	snappyCodec byte = 1
+)
+
+//Messages shorter than this are never compressed, since it wouldn't save anything.
+const minCompressSize = 64
+
+/*
+	A Codec encodes outgoing messages, compressing them when that is allowed, and keeps count of the bytes
+	before and after encoding.
+*/
+type Codec struct {
+	rawBytes, wireBytes int64
+}
+
+/*
+	Encode returns the data prefixed with the codec used. If compress is false, or compressing wouldn't make
+	the message smaller, the data is sent raw.
+*/
+func (c *Codec) Encode(data []byte, compress bool) []byte {
+	var result []byte
+	if compress && len(data) >= minCompressSize {
+		buf := make([]byte, 1+snappy.MaxEncodedLen(len(data)))
+		//snappy encodes into buf after the codec byte, since buf is large enough.
+		if compressed := snappy.Encode(buf[1:], data); len(compressed) < len(data) {
+			buf[0] = snappyCodec
+			result = buf[:1+len(compressed)]
+		}
+	}
+	if result == nil {
+		result = make([]byte, len(data)+1)
+		result[0] = rawCodec
+		copy(result[1:], data)
+	}
+	atomic.AddInt64(&c.rawBytes, int64(len(data)))
+	atomic.AddInt64(&c.wireBytes, int64(len(result)))
+	return result
+}
+
+//Stats returns the number of bytes given to Encode, and the number of bytes it returned.
+func (c *Codec) Stats() (raw, wire int) {
+	return int(atomic.LoadInt64(&c.rawBytes)), int(atomic.LoadInt64(&c.wireBytes))
+}
+
+//Decode returns the original data of a message encoded by Encode.
+func Decode(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, errors.New("empty message: missing codec")
+	}
+	switch b[0] {
+	case rawCodec:
+		return b[1:], nil
+	case snappyCodec:
+		return snappy.Decode(nil, b[1:])
+	}
+	return nil, errors.New("unknown codec")
+}
+
+//IsZero reports whether all bytes of data are zero. Such pages don't have to be sent at all.
+func IsZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package network
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCodec_roundtrip(t *testing.T) {
+	codec := new(Codec)
+	page := make([]byte, 4096)
+	page[17] = 5
+	page[4000] = 9
+	encoded := codec.Encode(page, true)
+	assert.Equal(t, snappyCodec, encoded[0])
+	assert.True(t, len(encoded) < len(page))
+	decoded, err := Decode(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, page, decoded)
+
+	raw, wire := codec.Stats()
+	assert.Equal(t, 4096, raw)
+	assert.Equal(t, len(encoded), wire)
+}
+
+func TestCodec_raw(t *testing.T) {
+	codec := new(Codec)
+	page := make([]byte, 4096)
+	encoded := codec.Encode(page, false)
+	assert.Equal(t, rawCodec, encoded[0])
+	decoded, err := Decode(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, page, decoded)
+
+	small := []byte{1, 2, 3}
+	encoded = codec.Encode(small, true)
+	assert.Equal(t, append([]byte{rawCodec}, small...), encoded)
+
+	_, err = Decode([]byte{42, 1, 2})
+	assert.NotNil(t, err)
+}
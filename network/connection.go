@@ -3,55 +3,139 @@ package network
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Connection interface {
 	Connect(ip string, port int) (int, error)
+	Send(msg []byte) error
 	Close()
+	SetBatching(maxBatchBytes int, flushDelay time.Duration)
+	SetCompression(b bool)
+	Compresses(id int) bool
+	Stats() (messages, frames, bytes int)
+	Dropped() int
+	Peers() []int
+	SetLogger(l *Logger)
 }
 
+//The first byte of every frame written to a peer tells what kind of frame it is.
+const (
+	controlFrame byte = 0
+	messageFrame byte = 1
+	batchFrame   byte = 2
+	optionsFrame byte = 3 //tells the peer which options we accept. Sent before any messages.
+)
+
+//Options that can be set in an options frame.
+const (
+	compressionOption byte = 1
+)
+
+const (
+	defaultMaxBatchBytes = 64 * 1024
+	defaultFlushDelay    = 0
+	defaultPeerTimeout   = time.Second * 10
+)
+
 var _ Connection = new(connection)
 
+var ConnectionClosedErr = errors.New("the connection is closed")
+
 type connection struct {
 	myId     int
 	myPort   int
-	running  bool
+	running  int32 //1 until the connection is closed
 	group    *sync.WaitGroup
 	listener *net.TCPListener
 	peers    []*peer
 	in, out  chan []byte
+
+	queues      map[int]*sendQueue //outgoing messages for each peer, including peers that haven't connected yet
+	queueSize   int
+	peerTimeout time.Duration
+	peersLock   *sync.Mutex
+	writers     *sync.WaitGroup
+	closed      bool
+
+	compression   int32 //1 if we accept, and send, compressed messages
+	maxBatchBytes int64 //batching is disabled if this is <= 0
+	flushDelay    int64 //nanoseconds a batch may wait for more messages
+	messagesSent  int64
+	framesSent    int64
+	bytesSent     int64
+	dropped       int64 //messages dropped because their peer didn't connect in time
+	logger        *Logger
+}
+
+//A batch holds the messages queued for a single peer, that have not been written yet.
+type batch struct {
+	msgs [][]byte
+	size int
+}
+
+//A sendQueue holds the messages for a single peer. Messages are held until the peer has connected.
+//It has room for size messages, and adding to a full queue waits until its writer has taken some.
+type sendQueue struct {
+	lock      *sync.Mutex
+	msgs      []queuedMessage
+	size      int
+	closed    bool
+	signal    chan bool  //receives when messages are added or the queue is closed
+	space     *sync.Cond //signalled when messages leave the queue or it is closed
+	ready     chan bool //closed when the peer has connected
+	connected bool
+}
+
+type queuedMessage struct {
+	data     []byte
+	deadline time.Time //the message is dropped if the peer hasn't connected by then
 }
 
 type peer struct {
-	id   int
-	ip   string
-	port int
-	conn *net.TCPConn
+	id         int
+	ip         string
+	port       int
+	conn       *net.TCPConn
+	compresses bool //the peer has told us it accepts compressed messages
 }
 
 /*
 	NewConnection gives a new connection, that will be listening on the given port.
 	The host will have ID 0 at this point.
+	bufferSize is the number of messages that can be queued for a single peer. Sending to a peer whose queue is full
+	blocks until there is room, see Send.
 */
 func NewConnection(port int, bufferSize int) (*connection, <-chan []byte, chan<- []byte, error) {
 	c := new(connection)
 	c.peers = make([]*peer, 1)
 	c.in, c.out = make(chan []byte, 1000), make(chan []byte, 1000)
-	c.running = true
+	c.running = 1
 	c.group = new(sync.WaitGroup)
+	c.queues = make(map[int]*sendQueue)
+	c.queueSize = bufferSize
+	c.peerTimeout = defaultPeerTimeout
+	c.peersLock = new(sync.Mutex)
+	c.writers = new(sync.WaitGroup)
 	c.myPort = port
+	c.SetLogger(DefaultLogger())
+	c.SetBatching(defaultMaxBatchBytes, defaultFlushDelay)
 	listener, err := net.Listen("tcp", fmt.Sprint(":", port))
 	if err != nil {
 		return nil, nil, nil, err
 	}
 	c.listener = listener.(*net.TCPListener)
+	//with port 0 the system picks a free port, which is the one told to peers
+	c.myPort = c.listener.Addr().(*net.TCPAddr).Port
+	c.group.Add(2)
 	go c.listen()
 	go c.sendLoop()
 	return c, c.in, c.out, nil
@@ -75,14 +159,17 @@ func (c *connection) Connect(ip string, port int) (int, error) {
 		panic("Couldnt dial the host: " + err.Error())
 	}
 
-	write(conn, []byte{0, 0, byte(c.myPort / 256), byte(c.myPort % 256)})
+	mustWrite(conn, []byte{controlFrame, 0, byte(c.myPort / 256), byte(c.myPort % 256)})
 	//conn.SetReadDeadline(time.Now().Add(time.Second*5))
 
-	msg := read(conn)
+	msg, _ := read(conn)
 	c.myId = int(msg[0])
+	c.logger.SetHost(c.myId)
 	c.addPeer(c.myId, nil, 0)
 	otherId := int(msg[1])
+	c.peersLock.Lock()
 	c.peers = make([]*peer, c.myId+1)
+	c.peersLock.Unlock()
 	c.addPeer(otherId, conn, port)
 	j := 2
 	for j < len(msg) {
@@ -93,70 +180,432 @@ func (c *connection) Connect(ip string, port int) (int, error) {
 		if err != nil {
 			panic("Got error when connecting to addr " + fmt.Sprint(ip, ":", port) + ": " + err.Error())
 		}
-		write(newConn, []byte{0, byte(c.myId), byte(c.myPort / 256), byte(c.myPort % 256)})
+		mustWrite(newConn, []byte{controlFrame, byte(c.myId), byte(c.myPort / 256), byte(c.myPort % 256)})
 		c.addPeer(id, newConn.(*net.TCPConn), port)
-		go c.receive(c.peers[id])
+		c.group.Add(1)
+		go c.receive(c.getPeer(id))
 		j += k
 	}
-	go c.receive(c.peers[otherId])
+	c.group.Add(1)
+	go c.receive(c.getPeer(otherId))
 	return c.myId, nil
 }
 
+func (c *connection) isRunning() bool {
+	return atomic.LoadInt32(&c.running) == 1
+}
+
 func (c *connection) Close() {
 	close(c.out)
 	c.group.Wait()
 }
 
 /*
-	This is a locally used method that sends messages read from the incoming channel.
+	SetBatching sets how the connection coalesces messages going to the same peer.
+	Messages queued back to back for a peer are written as one batch frame of at most maxBatchBytes.
+	A batch is written when nothing more is queued, unless flushDelay is positive, in which case the
+	batch may wait up to flushDelay for more messages. A maxBatchBytes <= 0 disables batching.
+*/
+func (c *connection) SetBatching(maxBatchBytes int, flushDelay time.Duration) {
+	atomic.StoreInt64(&c.maxBatchBytes, int64(maxBatchBytes))
+	atomic.StoreInt64(&c.flushDelay, int64(flushDelay))
+}
+
+//SetLogger sets the logger of the connection. Call it before connecting.
+func (c *connection) SetLogger(l *Logger) {
+	c.logger = l.For("connection")
+	c.logger.SetHost(c.myId)
+}
+
+/*
+	SetCompression sets whether this host accepts compressed messages, and compresses messages to peers that do.
+	The choice is sent to each peer when it connects, so it should be set before joining.
+*/
+func (c *connection) SetCompression(b bool) {
+	var v int32
+	if b {
+		v = 1
+	}
+	atomic.StoreInt32(&c.compression, v)
+}
+
+//Compresses reports whether both this host and the given peer have chosen to use compression.
+func (c *connection) Compresses(id int) bool {
+	if atomic.LoadInt32(&c.compression) == 0 {
+		return false
+	}
+	c.peersLock.Lock()
+	defer c.peersLock.Unlock()
+	return id < len(c.peers) && c.peers[id] != nil && c.peers[id].compresses
+}
+
+func (c *connection) options() byte {
+	var options byte
+	if atomic.LoadInt32(&c.compression) == 1 {
+		options |= compressionOption
+	}
+	return options
+}
+
+//Dropped returns the number of messages that were dropped, because their peer didn't connect in time.
+func (c *connection) Dropped() int {
+	return int(atomic.LoadInt64(&c.dropped))
+}
+
+//Stats returns the number of messages sent to other peers, and the number of frames and bytes used to send them.
+func (c *connection) Stats() (messages, frames, bytes int) {
+	return int(atomic.LoadInt64(&c.messagesSent)), int(atomic.LoadInt64(&c.framesSent)), int(atomic.LoadInt64(&c.bytesSent))
+}
+
+//Peers returns the ids of the peers this host is connected to.
+func (c *connection) Peers() []int {
+	c.peersLock.Lock()
+	defer c.peersLock.Unlock()
+	ids := make([]int, 0, len(c.peers))
+	for id, p := range c.peers {
+		if p != nil && p.conn != nil && id != c.myId {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+//SetPeerTimeout sets how long messages to a peer that hasn't connected yet are held, before they are dropped.
+func (c *connection) SetPeerTimeout(d time.Duration) {
+	c.peersLock.Lock()
+	c.peerTimeout = d
+	c.peersLock.Unlock()
+}
+
+/*
+	Send puts the message in the send queue of the peer in its first byte. If the queue is full, because the peer
+	is slow or hasn't connected yet, Send blocks until the writer of the queue has taken or dropped a message.
+	Only senders to that peer are held up. It returns ConnectionClosedErr if the connection has been closed.
+*/
+func (c *connection) Send(msg []byte) error {
+	id := int(msg[0])
+	c.peersLock.Lock()
+	if c.closed {
+		c.peersLock.Unlock()
+		return ConnectionClosedErr
+	}
+	if id == c.myId {
+		c.peersLock.Unlock()
+		c.in <- msg
+		return nil
+	}
+	q, timeout := c.queue(id), c.peerTimeout
+	c.peersLock.Unlock()
+	if !q.push(queuedMessage{msg, time.Now().Add(timeout)}) {
+		return ConnectionClosedErr
+	}
+	return nil
+}
+
+/*
+	This is a locally used method that sends messages read from the incoming channel, see Send.
+	The channel is shared by all peers, so while the queue of a peer is full, whoever writes to it waits as well.
 */
 func (c *connection) sendLoop() {
-	c.group.Add(1)
-	var id int
 	for msg := range c.out {
 		time.Sleep(0)
-		id = int(msg[0])
-		if id == c.myId {
-			c.in <- msg
-		} else {
-			if id >= len(c.peers) {
-				go func() {
-					time.Sleep(time.Millisecond * 500)
-					c.out <- msg
-				}()
-			} else {
-				msg[0] = 1
-
-				write(c.peers[id].conn, msg)
-			}
-		}
+		c.Send(msg)
 	}
-	c.running = false
+	c.peersLock.Lock()
+	c.closed = true
+	for _, q := range c.queues {
+		q.close()
+	}
+	c.peersLock.Unlock()
+	c.writers.Wait()
+	atomic.StoreInt32(&c.running, 0)
 	c.group.Done()
 	c.group.Wait()
 	close(c.in)
 }
 
+//getQueue returns the send queue of the given peer, and how long messages may be held in it.
+func (c *connection) getQueue(id int) (*sendQueue, time.Duration) {
+	c.peersLock.Lock()
+	defer c.peersLock.Unlock()
+	return c.queue(id), c.peerTimeout
+}
+
+//queue returns the send queue of the given peer, and starts a writer for it if it didn't exist.
+//peersLock must be held by the caller.
+func (c *connection) queue(id int) *sendQueue {
+	q, ok := c.queues[id]
+	if !ok {
+		size := c.queueSize
+		if size < 1 {
+			size = 1
+		}
+		q = &sendQueue{
+			lock:   new(sync.Mutex),
+			msgs:   make([]queuedMessage, 0, size),
+			size:   size,
+			signal: make(chan bool, 1),
+			ready:  make(chan bool),
+		}
+		q.space = sync.NewCond(q.lock)
+		c.queues[id] = q
+		c.writers.Add(1)
+		go c.writeLoop(id, q)
+	}
+	return q
+}
+
+/*
+	writeLoop writes the messages of a send queue to its peer. Until the peer has connected, messages are held
+	in the queue, and those that have been waiting past their deadline are dropped.
+	When the peer is connected, messages queued back to back are coalesced into batches, see SetBatching.
+*/
+func (c *connection) writeLoop(id int, q *sendQueue) {
+	defer c.writers.Done()
+Waiting:
+	for {
+		var timeout <-chan time.Time
+		if head, ok := q.peek(); ok {
+			timeout = time.After(time.Until(head.deadline))
+		} else if q.isClosed() {
+			return
+		}
+		select {
+		case <-q.ready:
+			break Waiting
+		case <-q.signal:
+			if q.isClosed() {
+				if n := q.drop(time.Now().Add(time.Hour)); n > 0 {
+					c.logger.Warnf("dropped %v messages to peer %v that never connected", n, id)
+					atomic.AddInt64(&c.dropped, int64(n))
+				}
+				return
+			}
+		case <-timeout:
+			n := q.drop(time.Now())
+			c.logger.Warnf("dropped %v messages to peer %v that didn't connect in time", n, id)
+			atomic.AddInt64(&c.dropped, int64(n))
+		}
+	}
+	conn := c.getPeer(id).conn
+	if err := write(conn, []byte{optionsFrame, c.options()}); err != nil {
+		c.discard(id, q, err)
+		return
+	}
+	b := new(batch)
+	var deadline <-chan time.Time
+	for {
+		msg, ok, closed := q.pop()
+		if ok {
+			b.add(msg.data)
+			if maxBytes := atomic.LoadInt64(&c.maxBatchBytes); maxBytes <= 0 || int64(b.size) >= maxBytes {
+				if err := c.flush(conn, b); err != nil {
+					c.discard(id, q, err)
+					return
+				}
+				deadline = nil
+			}
+			continue
+		}
+		if closed {
+			break
+		}
+		if len(b.msgs) == 0 {
+			<-q.signal
+			continue
+		}
+		flushDelay := time.Duration(atomic.LoadInt64(&c.flushDelay))
+		if flushDelay <= 0 {
+			if err := c.flush(conn, b); err != nil {
+				c.discard(id, q, err)
+				return
+			}
+			continue
+		}
+		if deadline == nil {
+			deadline = time.After(flushDelay)
+		}
+		select {
+		case <-q.signal:
+		case <-deadline:
+			if err := c.flush(conn, b); err != nil {
+				c.discard(id, q, err)
+				return
+			}
+			deadline = nil
+		}
+	}
+	if len(b.msgs) > 0 {
+		c.flush(conn, b)
+	}
+}
+
+//discard drops the messages for a peer whose connection is lost, until the queue is closed.
+func (c *connection) discard(id int, q *sendQueue, err error) {
+	c.logger.Warnf("lost the connection to peer %v: %v", id, err)
+	for {
+		_, ok, closed := q.pop()
+		if ok {
+			atomic.AddInt64(&c.dropped, 1)
+			continue
+		}
+		if closed {
+			return
+		}
+		<-q.signal
+	}
+}
+
+//push adds the message to the queue, and waits for room if it is full. It returns false if the queue was closed.
+func (q *sendQueue) push(msg queuedMessage) bool {
+	q.lock.Lock()
+	for len(q.msgs) >= q.size && !q.closed {
+		q.space.Wait()
+	}
+	if q.closed {
+		q.lock.Unlock()
+		return false
+	}
+	q.msgs = append(q.msgs, msg)
+	q.lock.Unlock()
+	q.notify()
+	return true
+}
+
+//pop takes the first message of the queue. If the queue is empty, ok is false, and closed tells if more can come.
+func (q *sendQueue) pop() (msg queuedMessage, ok, closed bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.msgs) == 0 {
+		return msg, false, q.closed
+	}
+	msg = q.msgs[0]
+	q.msgs[0] = queuedMessage{}
+	q.msgs = q.msgs[1:]
+	q.space.Signal()
+	return msg, true, false
+}
+
+func (q *sendQueue) peek() (queuedMessage, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.msgs) == 0 {
+		return queuedMessage{}, false
+	}
+	return q.msgs[0], true
+}
+
+//drop removes the messages whose deadline is before the given time, and returns how many there were.
+func (q *sendQueue) drop(before time.Time) int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	n := 0
+	for n < len(q.msgs) && !q.msgs[n].deadline.After(before) {
+		n++
+	}
+	q.msgs = q.msgs[n:]
+	if n > 0 {
+		q.space.Broadcast()
+	}
+	return n
+}
+
+func (q *sendQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.msgs)
+}
+
+func (q *sendQueue) isClosed() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.closed
+}
+
+func (q *sendQueue) close() {
+	q.lock.Lock()
+	q.closed = true
+	q.space.Broadcast()
+	q.lock.Unlock()
+	q.notify()
+}
+
+//notify wakes up the writer of the queue, if it isn't awake already.
+func (q *sendQueue) notify() {
+	select {
+	case q.signal <- true:
+	default:
+	}
+}
+
+func (b *batch) add(msg []byte) {
+	b.msgs = append(b.msgs, msg)
+	b.size += len(msg)
+}
+
+/*
+	flush writes all messages of the batch to the peer and empties the batch. A batch with a single message
+	is written as a normal message frame, so the receiver doesn't have to unpack it.
+	If the write fails, the messages of the batch are counted as dropped.
+*/
+func (c *connection) flush(conn *net.TCPConn, b *batch) error {
+	var frame []byte
+	if len(b.msgs) == 1 {
+		frame = b.msgs[0]
+		frame[0] = messageFrame
+	} else {
+		frame = packBatch(b.msgs)
+	}
+	if err := write(conn, frame); err != nil {
+		atomic.AddInt64(&c.dropped, int64(len(b.msgs)))
+		b.msgs = b.msgs[:0]
+		b.size = 0
+		return err
+	}
+	atomic.AddInt64(&c.messagesSent, int64(len(b.msgs)))
+	atomic.AddInt64(&c.framesSent, 1)
+	//write prefixes every frame with its length in 8 bytes
+	atomic.AddInt64(&c.bytesSent, int64(len(frame)+8))
+	b.msgs = b.msgs[:0]
+	b.size = 0
+	return nil
+}
+
 /*
 	This is a locally used method that reads messages from a certain connection, and puts them in the
 	outgoing channel.
 */
 func (c *connection) receive(peer *peer) {
-	c.group.Add(1)
-	for c.running {
+	for c.isRunning() {
 
-		b := read(peer.conn)
-		if b == nil {
+		b, err := read(peer.conn)
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
 			continue
 		}
-		if peer.id == 0 || peer.id == 1 {
+		if err != nil {
+			if c.isRunning() {
+				c.logger.Infof("peer %v closed the connection: %v", peer.id, err)
+			}
+			break
 		}
-
-		if len(b) > 0 && b[0] == 0 {
+		if len(b) > 0 && b[0] == controlFrame {
 			id := int(b[1])
 			ip, port, _ := addrFromBytes(b[2:])
 			conn := c.connectToHost(ip, port)
 			c.addPeer(id, conn, port)
+		} else if len(b) > 1 && b[0] == optionsFrame {
+			c.peersLock.Lock()
+			peer.compresses = b[1]&compressionOption != 0
+			c.peersLock.Unlock()
+		} else if len(b) > 0 && b[0] == batchFrame {
+			payloads, err := unpackBatch(b[1:])
+			if err != nil {
+				c.logger.Warnf("dropped a batch frame from peer %v: %v", peer.id, err)
+				continue
+			}
+			for _, payload := range payloads {
+				c.in <- append([]byte{byte(peer.id)}, payload...)
+			}
 		} else {
 			msg := append([]byte{byte(peer.id)}, b[1:]...)
 			c.in <- msg
@@ -172,9 +621,8 @@ func (c *connection) receive(peer *peer) {
 	on all new connections.
 */
 func (c *connection) listen() {
-	c.group.Add(1)
 
-	for c.running {
+	for c.isRunning() {
 		c.listener.SetDeadline(time.Now().Add(time.Millisecond * 500))
 		conn, err := c.listener.AcceptTCP()
 		if err == nil {
@@ -192,11 +640,12 @@ func (c *connection) listen() {
 	If the ID is different from 0, the host has already joined the network, and should just be added to our list of peers.
 */
 func (c *connection) addHost(conn *net.TCPConn) {
-	msg := read(conn)
+	msg, _ := read(conn)
 	var buf bytes.Buffer
 	id := int(msg[1])
 	port := int(msg[2])*256 + int(msg[3])
 	if id == 0 {
+		c.peersLock.Lock()
 		id = len(c.peers)
 		buf.Write([]byte{byte(id), byte(c.myId)})
 		for i := range c.peers {
@@ -205,11 +654,13 @@ func (c *connection) addHost(conn *net.TCPConn) {
 				buf.Write(addrToBytes(c.peers[i].ip, c.peers[i].port))
 			}
 		}
-		write(conn, buf.Bytes())
+		c.peersLock.Unlock()
+		mustWrite(conn, buf.Bytes())
 
 	}
 	c.addPeer(id, conn, port)
-	go c.receive(c.peers[id])
+	c.group.Add(1)
+	go c.receive(c.getPeer(id))
 }
 
 /*
@@ -218,7 +669,7 @@ func (c *connection) addHost(conn *net.TCPConn) {
 func (c *connection) connectToHost(ip string, port int) *net.TCPConn {
 	var conn net.Conn
 	var err error
-	for c.running {
+	for c.isRunning() {
 		conn, err = net.DialTimeout("tcp", fmt.Sprint(ip, ":", port), time.Millisecond*500)
 		if err != nil && !strings.HasSuffix(err.Error(), "i/o timeout") {
 			panic("Something went wrong when trying to connect to " + fmt.Sprint(ip, ":", port))
@@ -227,7 +678,12 @@ func (c *connection) connectToHost(ip string, port int) *net.TCPConn {
 	return conn.(*net.TCPConn)
 }
 
+/*
+	addPeer adds the peer to the list of peers, and releases any messages that were held for it.
+*/
 func (c *connection) addPeer(id int, conn *net.TCPConn, port int) {
+	c.peersLock.Lock()
+	defer c.peersLock.Unlock()
 	if len(c.peers) <= id {
 		c.peers = append(c.peers, make([]*peer, (id-len(c.peers))+1)...)
 	}
@@ -240,15 +696,29 @@ func (c *connection) addPeer(id int, conn *net.TCPConn, port int) {
 		}
 	}
 
-	c.peers[id] = &peer{id, ip, port, conn}
+	c.peers[id] = &peer{id: id, ip: ip, port: port, conn: conn}
+	if conn == nil || c.closed {
+		return
+	}
+	q := c.queue(id)
+	if !q.connected {
+		q.connected = true
+		close(q.ready)
+	}
+}
+
+func (c *connection) getPeer(id int) *peer {
+	c.peersLock.Lock()
+	defer c.peersLock.Unlock()
+	return c.peers[id]
 }
 
 func (c *connection) getAddr(id int) string {
-	peer := c.peers[id]
+	peer := c.getPeer(id)
 	return fmt.Sprint(peer.ip, ":", peer.port)
 }
 
-func write(conn net.Conn, data []byte) {
+func write(conn net.Conn, data []byte) error {
 	length := uint64(len(data))
 	if len(data) != int(length) {
 		panic(fmt.Sprint("Length did not match.", length, len(data)))
@@ -256,31 +726,68 @@ func write(conn net.Conn, data []byte) {
 	l := make([]byte, 8)
 	binary.PutVarint(l, int64(len(data)))
 	msg := append(l, data...)
-	n := 0
-	var err error
-	for {
-		n, err = conn.Write(msg[n:])
-		if err == nil {
-			break
-		}
+	_, err := conn.Write(msg)
+	return err
+}
+
+//mustWrite writes a frame of the handshake, which can't go on without it.
+func mustWrite(conn net.Conn, data []byte) {
+	if err := write(conn, data); err != nil {
 		panic(err.Error())
 	}
 }
 
-func read(conn net.Conn) []byte {
+//read reads a frame. It gives up after a second without data, with an error whose Timeout() is true.
+func read(conn net.Conn) ([]byte, error) {
 	length := make([]byte, 8)
 	conn.SetReadDeadline(time.Now().Add(time.Second))
 	_, err := io.ReadFull(conn, length)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	l, _ := binary.Varint(length)
 	msg := make([]byte, l)
 	_, err = io.ReadFull(conn, msg)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	return msg, nil
+}
+
+/*
+	packBatch builds a batch frame from a list of outgoing messages. The first byte of each message is the
+	destination id, which is left out. Each payload is prefixed with its length as a uvarint.
+*/
+func packBatch(msgs [][]byte) []byte {
+	size := 1
+	for _, msg := range msgs {
+		size += binary.MaxVarintLen64 + len(msg) - 1
+	}
+	frame := make([]byte, 1, size)
+	frame[0] = batchFrame
+	l := make([]byte, binary.MaxVarintLen64)
+	for _, msg := range msgs {
+		n := binary.PutUvarint(l, uint64(len(msg)-1))
+		frame = append(frame, l[:n]...)
+		frame = append(frame, msg[1:]...)
+	}
+	return frame
+}
+
+//unpackBatch returns the payloads of a batch frame, without the leading frame type.
+//A frame with a truncated length prefix or payload gives an error, and none of its payloads.
+func unpackBatch(b []byte) ([][]byte, error) {
+	payloads := make([][]byte, 0)
+	for len(b) > 0 {
+		l, n := binary.Uvarint(b)
+		if n <= 0 || l > uint64(len(b)-n) {
+			return nil, errors.New(fmt.Sprint("malformed batch frame at ", len(b), " bytes from the end"))
+		}
+		b = b[n:]
+		payloads = append(payloads, b[:l])
+		b = b[l:]
 	}
-	return msg
+	return payloads, nil
 }
 
 func addrFromBytes(b []byte) (string, int, int) {
@@ -50,12 +50,12 @@ func TestConnection_iptransform(t *testing.T) {
 }
 
 func TestNewConnection(t *testing.T) {
-	c0,_,_ := NewConnection(2334, 10)
-	c1,_,_ := NewConnection(1123, 10)
-	c2,_,_ := NewConnection(1523, 10)
+	c0,_,_,_ := NewConnection(2334, 10)
+	c1,_,_,_ := NewConnection(1123, 10)
+	c2,_,_,_ := NewConnection(1523, 10)
 	control1 := make(chan bool)
 	control2 := make(chan bool)
-	assert.True(t, c0.running)
+	assert.True(t, c0.isRunning())
 	assert.Len(t, c0.peers, 1)
 	assert.Equal(t, 0, c0.myId)
 	go func(){
@@ -147,3 +147,127 @@ func TestNewConnection(t *testing.T) {
 
 }
 
+
+func TestConnection_packUnpackBatch(t *testing.T) {
+	msgs := [][]byte{
+		{1, 5, 6, 7},
+		{1},
+		append([]byte{1}, make([]byte, 300)...),
+	}
+	frame := packBatch(msgs)
+	assert.Equal(t, batchFrame, frame[0])
+	payloads, err := unpackBatch(frame[1:])
+	assert.Nil(t, err)
+	assert.Len(t, payloads, 3)
+	for i := range msgs {
+		assert.Equal(t, msgs[i][1:], payloads[i])
+	}
+	//a frame cut short in a length prefix or in a payload is rejected
+	_, err = unpackBatch(append(frame[1:len(frame)-300], 0xAC))
+	assert.NotNil(t, err)
+	_, err = unpackBatch(frame[1 : len(frame)-1])
+	assert.NotNil(t, err)
+}
+
+func TestConnection_batching(t *testing.T) {
+	c0, in0, _, _ := NewConnection(2434, 10)
+	c1, _, out1, _ := NewConnection(2435, 10)
+	c1.SetBatching(1024, time.Millisecond*10)
+	_, err := c1.Connect("localhost", 2434)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond * 100)
+	for i := 0; i < 100; i++ {
+		out1 <- []byte{0, byte(i), 2, 3}
+	}
+	for i := 0; i < 100; i++ {
+		select {
+		case msg := <-in0:
+			assert.Equal(t, []byte{1, byte(i), 2, 3}, msg)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for message", i)
+		}
+	}
+	//the stats are counted once a write returns, which may be after the peer has read the frame
+	messages, frames, bytes := c1.Stats()
+	for deadline := time.Now().Add(time.Second); messages < 100 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		messages, frames, bytes = c1.Stats()
+	}
+	assert.Equal(t, 100, messages)
+	assert.True(t, frames < messages)
+	//unbatched, each message takes a frame of its 4 bytes after an 8 byte length
+	assert.True(t, bytes < messages*12, "The batches share frame headers.")
+	c1.Close()
+	c0.Close()
+}
+
+func TestConnection_heldUntilPeerConnects(t *testing.T) {
+	c0, _, out0, _ := NewConnection(2436, 10)
+	c1, in1, _, _ := NewConnection(2437, 10)
+	out0 <- []byte{1, 9, 8, 7}
+	time.Sleep(time.Millisecond * 100)
+	_, err := c1.Connect("localhost", 2436)
+	assert.Nil(t, err)
+	select {
+	case msg := <-in1:
+		assert.Equal(t, []byte{0, 9, 8, 7}, msg)
+	case <-time.After(time.Second):
+		t.Fatal("held message was never delivered")
+	}
+	c1.Close()
+	c0.Close()
+}
+
+func TestConnection_dropAfterPeerTimeout(t *testing.T) {
+	c0, _, out0, _ := NewConnection(2438, 2)
+	c0.SetPeerTimeout(time.Millisecond * 50)
+	for i := 0; i < 5; i++ {
+		out0 <- []byte{3, byte(i)}
+	}
+	time.Sleep(time.Millisecond * 500)
+	q, _ := c0.getQueue(3)
+	assert.Equal(t, 0, q.len())
+	messages, _, _ := c0.Stats()
+	assert.Equal(t, 0, messages)
+	assert.Equal(t, 5, c0.Dropped())
+	c0.Close()
+}
+
+func TestConnection_fullQueueBlocksSender(t *testing.T) {
+	c0, _, _, _ := NewConnection(2439, 2)
+	c1, in1, _, _ := NewConnection(2440, 2)
+	c0.SetPeerTimeout(time.Millisecond * 500)
+	_, err := c1.Connect("localhost", 2439)
+	assert.Nil(t, err)
+	//peer 5 never connects, so its queue is full after two messages
+	assert.Nil(t, c0.Send([]byte{5, 0}))
+	assert.Nil(t, c0.Send([]byte{5, 1}))
+	sent := make(chan bool)
+	go func() {
+		assert.Nil(t, c0.Send([]byte{5, 2}))
+		sent <- true
+	}()
+	select {
+	case <-sent:
+		t.Fatal("the message was queued for a peer whose queue is full")
+	case <-time.After(time.Millisecond * 200):
+	}
+	//the full queue of peer 5 doesn't hold up messages to other peers
+	assert.Nil(t, c0.Send([]byte{1, 9, 8, 7}))
+	select {
+	case msg := <-in1:
+		assert.Equal(t, []byte{0, 9, 8, 7}, msg)
+	case <-time.After(time.Second):
+		t.Fatal("the message to a connected peer waited for the unconnected one")
+	}
+	//when the first messages are dropped, there is room for the last
+	select {
+	case <-sent:
+	case <-time.After(time.Second * 2):
+		t.Fatal("the sender was never let go")
+	}
+	c1.Close()
+	c0.Close()
+	assert.Equal(t, 3, c0.Dropped())
+	assert.Equal(t, ConnectionClosedErr, c0.Send([]byte{1, 0}))
+}
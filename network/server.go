@@ -2,6 +2,7 @@ package network
 
 import (
 	"bytes"
+	"errors"
 	"github.com/davecgh/go-xdr/xdr2"
 	"github.com/orcaman/concurrent-map"
 	"log"
@@ -19,24 +20,42 @@ type P2PServer struct {
 	conn     Connection
 	port     int
 	in       <-chan []byte
-	out      chan<- []byte
 	shutdown chan bool
 	group    *sync.WaitGroup
 	handler  func(message Message) error
+	codec    *Codec
+	closed   bool
+	sendLock *sync.RWMutex //held for writing while the server closes, so nothing is sent on the closed connection
 }
 
+var ServerClosedErr = errors.New("the server is closed")
+
 func NewP2PServer(handler func(Message) error, port int, logger *CSVStructLogger) (*P2PServer, error) {
+	conn, in, _, err := NewConnection(port, 1000)
+	if err != nil {
+		return nil, err
+	}
 	s := new(P2PServer)
-	s.conn, s.in, s.out, _ = NewConnection(port, 1000)
+	s.conn, s.in = conn, in
 	s.shutdown = make(chan bool, 1)
 	s.handler = handler
 	s.group = new(sync.WaitGroup)
+	s.codec = new(Codec)
+	s.sendLock = new(sync.RWMutex)
 	go s.recieveLoop()
 	return s, nil
 }
 
+//Close closes the connection to all hosts. Closing a closed server does nothing.
 func (s *P2PServer) Close() {
+	s.sendLock.Lock()
+	if s.closed {
+		s.sendLock.Unlock()
+		return
+	}
+	s.closed = true
 	s.conn.Close()
+	s.sendLock.Unlock()
 	s.shutdown <- true
 	s.group.Wait()
 }
@@ -48,12 +67,32 @@ func (s *P2PServer) Send(message Message) error {
 	if err != nil {
 		panic("Error: " + err.Error())
 	}
-	data := make([]byte, w.Len()+1)
+	body := s.codec.Encode(w.Bytes(), s.conn.Compresses(int(msg.GetTo())))
+	data := make([]byte, len(body)+1)
 	data[0] = msg.GetTo()
 
-	w.Read(data[1:])
-	s.out <- data
-	return nil
+	copy(data[1:], body)
+	s.sendLock.RLock()
+	defer s.sendLock.RUnlock()
+	if s.closed {
+		return ServerClosedErr
+	}
+	return s.conn.Send(data)
+}
+
+//SetCompression sets whether messages are compressed on the wire. Call it before any hosts connect.
+func (s *P2PServer) SetCompression(b bool) {
+	s.conn.SetCompression(b)
+}
+
+//SetLogger sets the logger of the connection. Call it before any hosts connect.
+func (s *P2PServer) SetLogger(l *Logger) {
+	s.conn.SetLogger(l)
+}
+
+//BytesSent returns the number of bytes of the messages sent, before and after compression.
+func (s *P2PServer) BytesSent() (raw, wire int) {
+	return s.codec.Stats()
 }
 
 func (s *P2PServer) recieveLoop() {
@@ -70,9 +109,13 @@ Loop:
 		if data == nil {
 			break Loop
 		}
-		buf.Write(data[1:])
+		body, err := Decode(data[1:])
+		if err != nil {
+			panic(err.Error())
+		}
+		buf.Write(body)
 		var multiviewMsg MultiviewMessage
-		_, err := xdr.Unmarshal(buf, &multiviewMsg)
+		_, err = xdr.Unmarshal(buf, &multiviewMsg)
 		if err != nil {
 			panic(err.Error())
 		}
@@ -1,21 +1,18 @@
 package treadmarks
 
 import (
-	"DSM-project/network"
 	"errors"
-	"fmt"
-	"log"
-	"net"
 	"strconv"
 	"sync"
 )
 
-var _ = fmt.Print //TODO: remove when done
-
 //Interfaces
 type LockManager interface {
 	HandleLockAcquire(id int) byte
+	HandleLockTryAcquire(id int) (byte, bool)
 	HandleLockRelease(id int, newOwner byte) error
+	HandleReadLockAcquire(id int) byte
+	HandleReadLockRelease(id int) error
 }
 
 type BarrierManager interface {
@@ -24,30 +21,47 @@ type BarrierManager interface {
 
 //Lock manager implementation
 type LockManagerImp struct {
-	locks map[int]*sync.Mutex
+	locks map[int]*sync.RWMutex
 	last  map[int]byte
 	*sync.Mutex
 }
 
 func NewLockManagerImp() *LockManagerImp {
 	lm := new(LockManagerImp)
-	lm.locks = make(map[int]*sync.Mutex)
+	lm.locks = make(map[int]*sync.RWMutex)
 	lm.Mutex = new(sync.Mutex)
 	lm.last = make(map[int]byte)
 	return lm
 }
 
 func (lm *LockManagerImp) HandleLockAcquire(id int) byte {
+	lock, lastId := lm.getLock(id)
+	lock.Lock()
+	return lastId
+}
+
+//HandleLockTryAcquire acquires the lock if it is free, and tells if it did.
+func (lm *LockManagerImp) HandleLockTryAcquire(id int) (byte, bool) {
+	lock, lastId := lm.getLock(id)
+	return lastId, lock.TryLock()
+}
+
+//HandleReadLockAcquire acquires the lock in shared mode, so it is only held up by exclusive holders.
+func (lm *LockManagerImp) HandleReadLockAcquire(id int) byte {
+	lock, lastId := lm.getLock(id)
+	lock.RLock()
+	return lastId
+}
+
+func (lm *LockManagerImp) getLock(id int) (*sync.RWMutex, byte) {
 	lm.Lock()
+	defer lm.Unlock()
 	lock, ok := lm.locks[id]
 	if ok == false {
-		lock = new(sync.Mutex)
+		lock = new(sync.RWMutex)
 		lm.locks[id] = lock
 	}
-	lastId := lm.last[id]
-	lm.Unlock()
-	lock.Lock()
-	return lastId
+	return lock, lm.last[id]
 }
 
 func (lm *LockManagerImp) HandleLockRelease(id int, newOwner byte) error {
@@ -62,7 +76,21 @@ func (lm *LockManagerImp) HandleLockRelease(id int, newOwner byte) error {
 	return nil
 }
 
+func (lm *LockManagerImp) HandleReadLockRelease(id int) error {
+	lm.Lock()
+	lock, ok := lm.locks[id]
+	lm.Unlock()
+	if ok == false {
+		return errors.New("LockManager doesn't have a lock with ID " + strconv.Itoa(id))
+	}
+	lock.RUnlock()
+	return nil
+}
+
 //Barrier manager implementation
+//BarrierManagerImp is the barrier of MultiView, where every host meets at the manager. It has no tree or dissemination
+//algorithm like the TreadMarks engine: MultiView hosts carry no intervals to merge at a barrier, and their requests
+//must reach the manager so they can be sent again to a standby that takes over.
 type BarrierManagerImp struct {
 	barriers map[int]*sync.WaitGroup
 	nodes    int
@@ -88,7 +116,6 @@ func (bm *BarrierManagerImp) HandleBarrier(id int, f func()) *sync.WaitGroup {
 	f()
 	barrier.Done()
 	bm.Unlock()
-	log.Println("process arrived at barrier", id)
 	barrier.Wait()
 	bm.Lock()
 	if bm.barriers[id] != nil {
@@ -97,121 +124,3 @@ func (bm *BarrierManagerImp) HandleBarrier(id int, f func()) *sync.WaitGroup {
 	bm.Unlock()
 	return barrier
 }
-
-type tm_Manager struct {
-	vc Vectorclock
-	BarrierManager
-	LockManager
-	network.ITransciever //embedded type
-	nodes                int
-	tm                   *TreadMarks //the host instance on which this manager runs
-	doOnce               *sync.Once
-}
-
-func NewTest_TM_Manager(tr network.ITransciever, bm BarrierManager, lm LockManager, nodes int, tm *TreadMarks) *tm_Manager {
-	m := new(tm_Manager)
-	m.BarrierManager = bm
-	m.LockManager = lm
-	m.ITransciever = tr
-	m.nodes = nodes
-	m.tm = tm
-
-	return m
-}
-
-func NewTM_Manager(conn net.Conn, bm BarrierManager, lm LockManager, tm *TreadMarks) *tm_Manager {
-	m := new(tm_Manager)
-	m.BarrierManager = bm
-	m.LockManager = lm
-	m.ITransciever = network.NewTransciever(conn, func(message network.Message) error {
-		return m.HandleMessage(message)
-	})
-	m.vc = *NewVectorclock(tm.nrProcs)
-	m.nodes = tm.nrProcs
-	m.tm = tm
-	return m
-}
-
-func (m *tm_Manager) HandleMessage(message network.Message) error {
-	msg, ok := message.(TM_Message)
-	if ok == false {
-		if _, ok := message.(network.SimpleMessage); !ok {
-			panic("Message could not be converted.")
-		} else {
-			return nil
-		}
-	}
-	response := new(TM_Message)
-	var err error
-	switch msg.Type {
-	case LOCK_ACQUIRE_REQUEST:
-		response = m.handleLockAcquireRequest(&msg)
-	case LOCK_RELEASE:
-		err = m.handleLockReleaseRequest(&msg)
-	case BARRIER_REQUEST:
-		response = m.handleBarrierRequest(&msg)
-	case MALLOC_REQUEST:
-		panic("Implement me!")
-	case FREE_REQUEST:
-		panic("Implement me!")
-	case COPY_REQUEST:
-		response.From = m.tm.ProcId
-		response.To = msg.From
-		response.Type = COPY_RESPONSE
-		response.PageNr = msg.PageNr
-		response.Event = msg.Event
-	default:
-		panic("Saw an unknown message type in manager:" + message.GetType())
-	}
-
-	if response.Type != "" {
-		m.ITransciever.Send(*response)
-	}
-	return err
-}
-
-func (m *tm_Manager) handleLockAcquireRequest(message *TM_Message) *TM_Message {
-	id := message.Id
-	lastOwner := m.HandleLockAcquire(id)
-	message.To = lastOwner
-	if lastOwner == 0 {
-		message.To = message.From
-		message.From = m.tm.ProcId
-		message.Type = LOCK_ACQUIRE_RESPONSE
-	}
-	return message
-}
-
-func (m *tm_Manager) handleLockReleaseRequest(message *TM_Message) error {
-	id := message.Id
-	return m.HandleLockRelease(id, message.From)
-}
-
-func (m *tm_Manager) handleBarrierRequest(message *TM_Message) *TM_Message {
-	m.doOnce = new(sync.Once)
-	var msg TM_Message = *message
-	id := message.Id
-	m.HandleBarrier(id, func() {
-		log.Println("Manager trying to incorporate intervals from ", message.From, " into datastructure.")
-		if m.tm != nil {
-			m.tm.incorporateIntervalsIntoDatastructures(message)
-			m.vc = *m.vc.Merge(message.VC)
-		}
-		log.Println("Manager done incorporating intervals form ", message.From, " into datastructure")
-	})
-	//barrier over
-
-	m.doOnce.Do(func() {
-		//m.vc.Increment(m.tm.ProcId)
-	})
-
-	if m.tm != nil {
-		msg.Intervals = m.tm.GetAllUnseenIntervals(msg.VC)
-	}
-
-	msg.From, msg.To = msg.To, msg.From
-	msg.VC = m.vc
-	msg.Event = message.Event
-	msg.Type = BARRIER_RESPONSE
-	return &msg
-}
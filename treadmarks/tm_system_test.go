@@ -1,6 +1,7 @@
 package treadmarks
 
 import (
+	tm "DSM-project/dsm-api/treadmarks"
 	"DSM-project/memory"
 	"fmt"
 	"github.com/stretchr/testify/assert"
@@ -164,9 +165,9 @@ func TestBarrierManagerVCUpdate(t *testing.T) {
 	host2.Join("localhost:2000")
 	host3.Join("localhost:2000")
 
-	assert.Equal(t, *NewVectorclock(3), host1.vc)
-	assert.Equal(t, *NewVectorclock(3), host2.vc)
-	assert.Equal(t, *NewVectorclock(3), host3.vc)
+	assert.Equal(t, tm.NewTimestamp(3), host1.Timestamp())
+	assert.Equal(t, tm.NewTimestamp(3), host2.Timestamp())
+	assert.Equal(t, tm.NewTimestamp(3), host3.Timestamp())
 
 	host1.AcquireLock(1)
 	host1.ReleaseLock(1)
@@ -175,9 +176,9 @@ func TestBarrierManagerVCUpdate(t *testing.T) {
 	host3.AcquireLock(1)
 	host3.ReleaseLock(1)
 	time.Sleep(time.Millisecond * 300)
-	assert.Equal(t, Vectorclock{Value: []uint{0, 0, 0}}, host1.vc)
-	assert.Equal(t, Vectorclock{Value: []uint{0, 0, 0}}, host2.vc)
-	assert.Equal(t, Vectorclock{Value: []uint{0, 0, 0}}, host3.vc)
+	assert.Equal(t, tm.Timestamp{0, 0, 0}, host1.Timestamp())
+	assert.Equal(t, tm.Timestamp{0, 0, 0}, host2.Timestamp())
+	assert.Equal(t, tm.Timestamp{0, 0, 0}, host3.Timestamp())
 
 	done := make(chan bool)
 	go func() {
@@ -195,9 +196,9 @@ func TestBarrierManagerVCUpdate(t *testing.T) {
 	<-done
 	<-done
 	<-done
-	assert.Equal(t, Vectorclock{Value: []uint{0, 0, 0}}, host1.vc)
-	assert.Equal(t, Vectorclock{Value: []uint{0, 0, 0}}, host2.vc)
-	assert.Equal(t, Vectorclock{Value: []uint{0, 0, 0}}, host3.vc)
+	assert.Equal(t, tm.Timestamp{0, 0, 0}, host1.Timestamp())
+	assert.Equal(t, tm.Timestamp{0, 0, 0}, host2.Timestamp())
+	assert.Equal(t, tm.Timestamp{0, 0, 0}, host3.Timestamp())
 	host1.Shutdown()
 	host2.Shutdown()
 	host3.Shutdown()
@@ -215,23 +216,25 @@ func TestCreationAndPropagationOfWriteNotices(t *testing.T) {
 	host1.Write(13, byte(10))
 	host1.ReleaseLock(1)
 	host2.AcquireLock(1)
-	assert.Equal(t, Vectorclock{Value: []uint{1, 0, 0}}, host1.GetIntervalRecord(byte(1), Vectorclock{Value: []uint{1, 0, 0}}).Timestamp)
-	assert.Len(t, host1.GetIntervalRecord(byte(1), Vectorclock{Value: []uint{1, 0, 0}}).WriteNotices, 1)
-	assert.Equal(t, Vectorclock{Value: []uint{1, 0, 0}}, host2.vc)
+	assert.Equal(t, tm.Timestamp{1, 0, 0}, host1.GetIntervalRecords(byte(1))[0].Timestamp)
+	assert.Len(t, host1.GetIntervalRecords(byte(1))[0].Pages, 1)
+	assert.Equal(t, tm.Timestamp{1, 0, 0}, host2.Timestamp())
 
 	assert.Len(t, host2.GetIntervalRecords(byte(1)), 1)
 	assert.Len(t, host2.GetIntervalRecords(byte(2)), 0)
 	assert.Len(t, host2.GetIntervalRecords(byte(3)), 0)
+	res, _ := host2.Read(13)
+	assert.Equal(t, byte(10), res)
 	host2.Write(25, byte(8))
 	host2.Write(13, byte(12))
 	host2.Write(0, byte(1))
 	host2.ReleaseLock(1)
 
 	host3.AcquireLock(1)
-	assert.Equal(t, Vectorclock{Value: []uint{1, 0, 0}}, host2.GetIntervalRecord(byte(1), Vectorclock{Value: []uint{1, 0, 0}}).Timestamp)
-	assert.Equal(t, Vectorclock{Value: []uint{1, 1, 0}}, host2.GetIntervalRecord(byte(2), Vectorclock{Value: []uint{1, 1, 0}}).Timestamp)
-	assert.Len(t, host2.GetIntervalRecord(byte(1), Vectorclock{Value: []uint{1, 0, 0}}).WriteNotices, 1)
-	assert.Len(t, host2.GetIntervalRecord(byte(2), Vectorclock{Value: []uint{1, 1, 0}}).WriteNotices, 3)
+	assert.Equal(t, tm.Timestamp{1, 0, 0}, host2.GetIntervalRecords(byte(1))[0].Timestamp)
+	assert.Equal(t, tm.Timestamp{1, 1, 0}, host2.GetIntervalRecords(byte(2))[0].Timestamp)
+	assert.Len(t, host2.GetIntervalRecords(byte(1))[0].Pages, 1)
+	assert.Len(t, host2.GetIntervalRecords(byte(2))[0].Pages, 3)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(1), 1), 1)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(2), 0), 1)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(2), 1), 1)
@@ -240,7 +243,7 @@ func TestCreationAndPropagationOfWriteNotices(t *testing.T) {
 
 	host3.ReleaseLock(1)
 
-	res, _ := host1.Read(13)
+	res, _ = host1.Read(13)
 	assert.Equal(t, byte(10), res)
 	res1, _ := host2.Read(13)
 	res2, _ := host2.Read(0)
@@ -317,7 +320,7 @@ func TestShouldNotCreateNewIntervalOnLockReacquire(t *testing.T) {
 	assert.Len(t, host2.GetWritenoticeRecords(byte(2), 0), 0)
 
 	host1.Write(1, byte(2))
-	assert.Equal(t, Vectorclock{[]uint{0, 0}}, host1.vc)
+	assert.Equal(t, tm.Timestamp{0, 0}, host1.Timestamp())
 	assert.Len(t, host1.GetWritenoticeRecords(byte(1), 0), 0)
 	assert.Len(t, host1.GetWritenoticeRecords(byte(2), 0), 0)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(1), 0), 0)
@@ -327,24 +330,24 @@ func TestShouldNotCreateNewIntervalOnLockReacquire(t *testing.T) {
 	channel2 := make(chan bool, 0)
 	go func() {
 		channel1 <- true
-		assert.Equal(t, Vectorclock{[]uint{0, 0}}, host1.vc)
+		assert.Equal(t, tm.Timestamp{0, 0}, host1.Timestamp())
 		host1.Barrier(1)
-		assert.Equal(t, Vectorclock{[]uint{1, 0}}, host1.vc)
+		assert.Equal(t, tm.Timestamp{1, 0}, host1.Timestamp())
 		channel1 <- true
 	}()
 	go func() {
 		channel2 <- true
 		host2.Barrier(1)
-		assert.Equal(t, Vectorclock{[]uint{1, 0}}, host2.vc)
+		assert.Equal(t, tm.Timestamp{1, 0}, host2.Timestamp())
 		channel2 <- true
 	}()
 	<-channel1
 	<-channel2
 	<-channel1
 	<-channel2
-	assert.Equal(t, Vectorclock{[]uint{1, 0}}, host1.GetIntervalRecords(byte(1))[0].Timestamp)
+	assert.Equal(t, tm.Timestamp{1, 0}, host1.GetIntervalRecords(byte(1))[0].Timestamp)
 	assert.Len(t, host1.GetIntervalRecords(byte(2)), 0)
-	assert.Equal(t, Vectorclock{[]uint{1, 0}}, host2.GetIntervalRecords(byte(1))[0].Timestamp)
+	assert.Equal(t, tm.Timestamp{1, 0}, host2.GetIntervalRecords(byte(1))[0].Timestamp)
 	assert.Len(t, host2.GetIntervalRecords(byte(2)), 0)
 	assert.Nil(t, host1.GetWritenoticeRecords(byte(1), 0)[0].Diff)
 
@@ -357,7 +360,7 @@ func TestShouldNotCreateNewIntervalOnLockReacquire(t *testing.T) {
 	assert.Len(t, host1.GetWritenoticeRecords(byte(2), 0), 0)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(1), 0), 1)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(2), 0), 0)
-	assert.Equal(t, Vectorclock{[]uint{1, 0}}, host2.vc)
+	assert.Equal(t, tm.Timestamp{1, 0}, host2.Timestamp())
 	channel1 = make(chan bool, 0)
 	channel2 = make(chan bool, 0)
 	go func() {
@@ -374,12 +377,13 @@ func TestShouldNotCreateNewIntervalOnLockReacquire(t *testing.T) {
 	<-channel2
 	<-channel1
 	<-channel2
-	assert.Equal(t, host2.vc, Vectorclock{[]uint{1, 1}})
+	assert.Equal(t, tm.Timestamp{1, 1}, host2.Timestamp())
 	assert.Len(t, host1.GetWritenoticeRecords(byte(1), 0), 1)
 	assert.Len(t, host1.GetWritenoticeRecords(byte(2), 0), 1)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(1), 0), 1)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(2), 0), 1)
 
+	//the diff of the last interval of host 2 has not been made yet, so this write goes into it
 	host2.Write(1, byte(4))
 	channel := make(chan bool)
 	go func() {
@@ -392,12 +396,14 @@ func TestShouldNotCreateNewIntervalOnLockReacquire(t *testing.T) {
 	}()
 	<-channel
 	<-channel
-	assert.Equal(t, host1.vc, Vectorclock{[]uint{1, 2}})
-	assert.Equal(t, host2.vc, Vectorclock{[]uint{1, 2}})
+	assert.Equal(t, tm.Timestamp{1, 1}, host1.Timestamp())
+	assert.Equal(t, tm.Timestamp{1, 1}, host2.Timestamp())
 	assert.Len(t, host1.GetWritenoticeRecords(byte(1), 0), 1)
-	assert.Len(t, host1.GetWritenoticeRecords(byte(2), 0), 2)
+	assert.Len(t, host1.GetWritenoticeRecords(byte(2), 0), 1)
 	assert.Len(t, host2.GetWritenoticeRecords(byte(1), 0), 1)
-	assert.Len(t, host2.GetWritenoticeRecords(byte(2), 0), 2)
+	assert.Len(t, host2.GetWritenoticeRecords(byte(2), 0), 1)
+	res, _ := host1.Read(1)
+	assert.Equal(t, byte(4), res)
 	host1.Shutdown()
 	host2.Shutdown()
 }
@@ -441,11 +447,13 @@ func TestBarrierReadWrites(t *testing.T) {
 	<-started
 	group.Wait()
 
-	assert.Equal(t, Vectorclock{Value: []uint{1, 1, 1}}, host1.vc)
-	assert.Equal(t, Vectorclock{Value: []uint{1, 1, 1}}, host2.vc)
-	assert.Equal(t, Vectorclock{Value: []uint{1, 1, 1}}, host3.vc)
+	assert.Equal(t, tm.Timestamp{1, 1, 1}, host1.Timestamp())
+	assert.Equal(t, tm.Timestamp{1, 1, 1}, host2.Timestamp())
+	assert.Equal(t, tm.Timestamp{1, 1, 1}, host3.Timestamp())
 
-	assert.Equal(t, &Vectorclock{Value: []uint{0, 1, 0}}, host1.GetWritenoticeRecords(byte(2), 1)[0].GetTimestamp())
+	//host 2 may or may not have seen the interval of host 1 before its own, depending on when it got lock 2
+	assert.Len(t, host1.GetWritenoticeRecords(byte(2), 1), 1)
+	assert.Equal(t, int32(1), host1.GetWritenoticeRecords(byte(2), 1)[0].Timestamp[1])
 	//all changes made in host1 and host2 should be seen by all.
 	res1, _ := host1.Read(12)
 	res2, _ := host1.Read(13)
@@ -487,19 +495,24 @@ func TestLockReqacquire(t *testing.T) {
 	host1.Write(1, byte(1))
 	host1.ReleaseLock(1)
 
-	assert.Equal(t, Vectorclock{[]uint{0, 0, 0}}, host1.vc)
+	assert.Equal(t, tm.Timestamp{0, 0, 0}, host1.Timestamp())
 	host1.AcquireLock(1)
 	host1.Write(1, byte(2))
 	host1.ReleaseLock(1)
-	assert.Equal(t, Vectorclock{[]uint{0, 0, 0}}, host1.vc)
+	assert.Equal(t, tm.Timestamp{0, 0, 0}, host1.Timestamp())
 	host2.AcquireLock(1)
-	host2.Write(1, byte(2))
+	res, _ := host2.Read(1)
+	assert.Equal(t, byte(2), res)
+	host2.Write(1, byte(3))
 	host2.ReleaseLock(1)
-	assert.Equal(t, Vectorclock{[]uint{1, 0, 0}}, host1.vc)
+	assert.Equal(t, tm.Timestamp{1, 0, 0}, host1.Timestamp())
 
 	host1.AcquireLock(1)
-	assert.Equal(t, Vectorclock{[]uint{1, 1, 0}}, host1.vc)
-	assert.Equal(t, Vectorclock{[]uint{1, 1, 0}}, host2.vc)
+	assert.Equal(t, tm.Timestamp{1, 1, 0}, host1.Timestamp())
+	assert.Equal(t, tm.Timestamp{1, 1, 0}, host2.Timestamp())
+	res, _ = host1.Read(1)
+	assert.Equal(t, byte(3), res)
+	host1.ReleaseLock(1)
 	host1.Shutdown()
 	host2.Shutdown()
 	host3.Shutdown()
@@ -601,3 +614,39 @@ func TestLockReacquireDifferentLocks(t *testing.T) {
 	host2.Shutdown()
 	host3.Shutdown()
 }
+
+func TestNewBarrierProcIds(t *testing.T) {
+	host1 := setupTreadMarksStruct(3)
+	host2 := setupTreadMarksStruct(3)
+	host3 := setupTreadMarksStruct(3)
+
+	host1.Startup()
+	host2.Join("localhost:2000")
+	host3.Join("localhost:2000")
+
+	//process ids start at 1, so this is a barrier of host 2 and host 3 only
+	id := host1.NewBarrier([]int{2, 3})
+	done := make(chan bool, 2)
+	go func() {
+		host2.Write(5, byte(42))
+		host2.Barrier(id)
+		done <- true
+	}()
+	time.Sleep(time.Millisecond * 200)
+	select {
+	case <-done:
+		t.Error("host 2 passed the barrier before host 3 arrived")
+	default:
+	}
+	host3.Barrier(id)
+	<-done
+	res, _ := host3.Read(5)
+	assert.Equal(t, byte(42), res)
+	assert.Equal(t, tm.Timestamp{0, 1, 0}, host3.Timestamp())
+	assert.Len(t, host3.GetIntervalRecords(byte(2)), 1)
+	assert.Panics(t, func() { host1.NewBarrier([]int{4}) })
+
+	host1.Shutdown()
+	host2.Shutdown()
+	host3.Shutdown()
+}